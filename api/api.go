@@ -2,12 +2,23 @@ package api
 
 import (
 	"context"
+	"net/http"
 
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/auth"
+	"github.com/ONSdigital/dp-census-search-prototypes/cors"
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
+	"github.com/ONSdigital/dp-census-search-prototypes/ratelimit"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	"github.com/ONSdigital/go-ns/server"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+const serviceName = "dp-search-api"
+
 var httpServer *server.Server
 
 // SearchAPI manages searches across indices
@@ -21,16 +32,28 @@ type SearchAPI struct {
 }
 
 // CreateAndInitialiseSearchAPI manages all the routes configured to API
-func CreateAndInitialiseSearchAPI(ctx context.Context, bindAddr string, esAPI Elasticsearcher, defaultMaxResults int, datasetIndex, postcodeIndex, boundaryFileIndex string, errorChan chan error) {
+func CreateAndInitialiseSearchAPI(ctx context.Context, bindAddr string, esAPI Elasticsearcher, defaultMaxResults int, datasetIndex, postcodeIndex, boundaryFileIndex string, hc *healthcheck.HealthCheck, corsPolicy *cors.Policy, limiter *ratelimit.Limiter, verifier auth.Verifier, errorChan chan error) {
 
 	router := mux.NewRouter()
-	routes(ctx,
-		router,
+	router.Use(tracing.Middleware(serviceName, routeTemplate))
+	HandleErrors(router, corsPolicy)
+	metrics.Instrument(router, routeTemplate)
+	router.Use(corsPolicy.Middleware)
+	router.HandleFunc("/health", hc.Handler).Methods("GET")
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// search routes are rate limited, health and metrics are not
+	search := router.NewRoute().Subrouter()
+	search.Use(limiter.Middleware(routeTemplate))
+
+	NewSearchAPI(ctx,
+		search,
 		esAPI,
 		defaultMaxResults,
 		datasetIndex,
 		postcodeIndex,
 		boundaryFileIndex,
+		verifier,
 	)
 
 	httpServer = server.New(bindAddr, router)
@@ -47,11 +70,13 @@ func CreateAndInitialiseSearchAPI(ctx context.Context, bindAddr string, esAPI El
 	}()
 }
 
-func routes(ctx context.Context,
+// NewSearchAPI registers the search routes on router, which is served by the caller
+func NewSearchAPI(ctx context.Context,
 	router *mux.Router,
 	elasticsearch Elasticsearcher,
 	defaultMaxResults int,
-	datasetIndex, postcodeIndex, boundaryFileIndex string) *SearchAPI {
+	datasetIndex, postcodeIndex, boundaryFileIndex string,
+	verifier auth.Verifier) *SearchAPI {
 
 	api := SearchAPI{
 		defaultMaxResults: defaultMaxResults,
@@ -62,14 +87,43 @@ func routes(ctx context.Context,
 		boundaryFileIndex: boundaryFileIndex,
 	}
 
-	api.router.HandleFunc("/search/parent", api.postParentSearch).Methods("POST", "OPTIONS")
+	api.router.HandleFunc("/search", api.getSmartSearch).Methods("GET", "OPTIONS")
+	// routes that write to elasticsearch require authentication, searches are public
+	authenticate := auth.Middleware(verifier)
+
+	api.router.Handle("/search/parent", authenticate(http.HandlerFunc(api.postParentSearch))).Methods("POST", "OPTIONS")
 	api.router.HandleFunc("/search/parent/{id}", api.getParentSearch).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/search/postcodes/{postcode}", api.getPostcodeSearch).Methods("GET", "OPTIONS")
 	api.router.HandleFunc("/search/placenames/{name}", api.getPlaceNameSearch).Methods("GET", "OPTIONS")
+	api.router.HandleFunc("/search/point", api.getPointSearch).Methods("GET", "OPTIONS")
+	api.router.HandleFunc("/search/radius", api.getRadiusSearch).Methods("GET", "OPTIONS")
 
 	return &api
 }
 
+// HandleErrors answers requests matching no route, or a route but not its methods, with
+// a json error. Router middleware does not run for these, so CORS headers are added here.
+func HandleErrors(router *mux.Router, corsPolicy *cors.Policy) {
+	router.NotFoundHandler = corsPolicy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errs.WriteError(w, errs.ErrResourceNotFound)
+	}))
+
+	router.MethodNotAllowedHandler = corsPolicy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errs.WriteError(w, errs.ErrMethodNotAllowed)
+	}))
+}
+
+// routeTemplate returns the path template of the matched route, e.g. /search/postcodes/{postcode}
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return "unknown"
+}
+
 // Close represents the graceful shutting down of the http server
 func Close(ctx context.Context) error {
 	if err := httpServer.Shutdown(ctx); err != nil {
@@ -0,0 +1,317 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/api"
+	"github.com/ONSdigital/dp-census-search-prototypes/auth"
+	"github.com/ONSdigital/dp-census-search-prototypes/cors"
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	"github.com/gorilla/mux"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newFixtureRouter returns the search routes backed by elasticsearch fixtures replayed from
+// testdata, or recorded against ELASTIC_SEARCH_URL when ES_RECORD is set. Re-record them
+// with `ES_RECORD=true go test ./api` against a cluster loaded by the scripts.
+func newFixtureRouter(t *testing.T, name string) (*mux.Router, *es.Recorder) {
+	cli, _ := es.NewClient(false, "")
+
+	recorder, err := es.NewRecorder(cli, filepath.Join("testdata", name+".json"), es.RecordModeFromEnv())
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+
+	url := os.Getenv("ELASTIC_SEARCH_URL")
+	if url == "" {
+		url = "http://localhost:9200"
+	}
+
+	esAPI := es.NewElasticSearchAPI(recorder, url)
+	esAPI.SetRetryPolicy(es.RetryPolicy{})
+
+	router := mux.NewRouter()
+	api.NewSearchAPI(context.Background(), router, esAPI, 1000, "test_geo", "test_postcode", "test_boundary_files", auth.Any{})
+
+	return router, recorder
+}
+
+func get(router http.Handler, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+
+	return w
+}
+
+func TestHandleErrors(t *testing.T) {
+	Convey("Given the search api with json error handlers", t, func() {
+		corsPolicy, err := cors.New(cors.Options{AllowedOrigins: []string{"https://www.ons.gov.uk"}})
+		So(err, ShouldBeNil)
+
+		router := mux.NewRouter()
+		api.HandleErrors(router, corsPolicy)
+		api.NewSearchAPI(context.Background(), router, nil, 1000, "test_geo", "test_postcode", "test_boundary_files", auth.Any{})
+
+		Convey("When a path matching no route is requested", func() {
+			r := httptest.NewRequest("GET", "/search/nowhere", nil)
+			r.Header.Set("Origin", "https://www.ons.gov.uk")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			Convey("Then not found is returned as json with cors headers", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+				So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "https://www.ons.gov.uk")
+				So(w.Body.String(), ShouldContainSubstring, `"code":"resource_not_found"`)
+			})
+		})
+
+		Convey("When a route is requested with a method it does not allow", func() {
+			r := httptest.NewRequest("DELETE", "/search/point", nil)
+			r.Header.Set("Origin", "https://www.ons.gov.uk")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			Convey("Then method not allowed is returned as json with cors headers", func() {
+				So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+				So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+				So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "https://www.ons.gov.uk")
+				So(w.Body.String(), ShouldContainSubstring, `"code":"method_not_allowed"`)
+			})
+		})
+	})
+}
+
+func TestGetPostcodeSearch(t *testing.T) {
+	router, recorder := newFixtureRouter(t, "postcode_search")
+	defer recorder.Save()
+
+	Convey("Given the search api", t, func() {
+		Convey("When searching for areas within a distance of a postcode", func() {
+			w := get(router, "/search/postcodes/CF24%204NY?distance=1,km")
+
+			Convey("Then the areas are returned with pagination links", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.SearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.TotalCount, ShouldEqual, 2)
+				So(results.Count, ShouldEqual, 2)
+				So(results.Limit, ShouldEqual, 50)
+				So(results.Items[0].Code, ShouldEqual, "W01001689")
+				So(results.Items[1].Code, ShouldEqual, "W01001690")
+				So(results.Links, ShouldNotBeNil)
+			})
+
+			Convey("Then the postcode is returned with its geography codes", func() {
+				var results models.SearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.Postcode, ShouldNotBeNil)
+				So(results.Postcode.Postcode, ShouldEqual, "CF24 4NY")
+				So(results.Postcode.Lat, ShouldEqual, 51.487381)
+				So(results.Postcode.LSOA, ShouldEqual, "W01001689")
+				So(results.Postcode.LAD, ShouldEqual, "W06000015")
+				So(results.Postcode.Country, ShouldEqual, "W92000004")
+				So(results.Postcode.Region, ShouldBeEmpty)
+				So(results.Postcode.TerminationDate, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When searching with a postcode that does not exist", func() {
+			w := get(router, "/search/postcodes/ZZ99%209ZZ?distance=1,km")
+
+			Convey("Then postcode not found is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusNotFound)
+				So(w.Body.String(), ShouldContainSubstring, "postcode not found")
+			})
+		})
+
+		Convey("When searching with an invalid distance", func() {
+			w := get(router, "/search/postcodes/CF244NY?distance=far")
+
+			Convey("Then bad request is returned without calling elasticsearch", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	})
+}
+
+func TestGetPlaceNameSearch(t *testing.T) {
+	router, recorder := newFixtureRouter(t, "place_name_search")
+	defer recorder.Save()
+
+	Convey("Given the search api", t, func() {
+		Convey("When searching by place name", func() {
+			w := get(router, "/search/placenames/bradford?limit=2")
+
+			Convey("Then the best matches are returned with their location", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.SearchResultsWithLocation
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.TotalCount, ShouldEqual, 5)
+				So(results.Count, ShouldEqual, 2)
+				So(results.Items[0].Name, ShouldEqual, "Bradford")
+				So(results.Items[0].Location.Type, ShouldEqual, "polygon")
+				So(results.Links, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When searching with an invalid limit", func() {
+			w := get(router, "/search/placenames/bradford?limit=lots")
+
+			Convey("Then bad request is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	})
+}
+
+func TestGetPointSearch(t *testing.T) {
+	router, recorder := newFixtureRouter(t, "point_search")
+	defer recorder.Save()
+
+	Convey("Given the search api", t, func() {
+		Convey("When searching for the areas containing a national grid easting and northing", func() {
+			w := get(router, "/search/point?easting=318100&northing=176600")
+
+			Convey("Then the areas containing the point are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.SearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.Count, ShouldEqual, 1)
+				So(results.Items[0].Code, ShouldEqual, "W01001689")
+			})
+		})
+
+		Convey("When searching for the areas containing a latitude and longitude", func() {
+			w := get(router, "/search/point?lat=51.4822&lon=-3.1812")
+
+			Convey("Then the areas containing the point are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When searching for the areas overlapping the kilometre square of a grid reference", func() {
+			w := get(router, "/search/point?gridref=ST%2018%2076")
+
+			Convey("Then the areas overlapping the square are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.SearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.Count, ShouldEqual, 1)
+			})
+		})
+
+		Convey("When searching with an invalid grid reference", func() {
+			w := get(router, "/search/point?gridref=ST%20183%2076")
+
+			Convey("Then bad request is returned naming the grid reference", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldContainSubstring, `"field":"gridref"`)
+			})
+		})
+
+		Convey("When searching with both a latitude and an easting", func() {
+			w := get(router, "/search/point?lat=51.4822&easting=318100")
+
+			Convey("Then bad request is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldContainSubstring, "invalid_location")
+			})
+		})
+
+		Convey("When searching with an easting off the national grid", func() {
+			w := get(router, "/search/point?easting=800000&northing=176600")
+
+			Convey("Then bad request is returned naming the easting", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldContainSubstring, `"field":"easting"`)
+			})
+		})
+
+		Convey("When searching with a northing that is not a number", func() {
+			w := get(router, "/search/point?easting=100&northing=NaN")
+
+			Convey("Then bad request is returned naming the northing", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldContainSubstring, `"field":"northing"`)
+			})
+		})
+
+		Convey("When searching without a location", func() {
+			w := get(router, "/search/point")
+
+			Convey("Then bad request is returned", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+				So(w.Body.String(), ShouldContainSubstring, "missing_location")
+			})
+		})
+	})
+}
+
+func TestGetRadiusSearch(t *testing.T) {
+	router, recorder := newFixtureRouter(t, "radius_search")
+	defer recorder.Save()
+
+	Convey("Given the search api", t, func() {
+		Convey("When searching for areas within a distance of a national grid easting and northing", func() {
+			w := get(router, "/search/radius?easting=318100&northing=176600&distance=1,km")
+
+			Convey("Then the areas are returned with pagination links", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.SearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.Count, ShouldEqual, 2)
+				So(results.Items[0].Code, ShouldEqual, "W01001689")
+				So(results.Links, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When searching for areas within a distance of a grid reference", func() {
+			w := get(router, "/search/radius?gridref=ST1830076500&distance=1,km")
+
+			Convey("Then the areas are returned", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When searching without a distance", func() {
+			w := get(router, "/search/radius?easting=318100&northing=176600")
+
+			Convey("Then bad request is returned without calling elasticsearch", func() {
+				So(w.Code, ShouldEqual, http.StatusBadRequest)
+			})
+		})
+	})
+}
+
+func TestGetSmartSearch(t *testing.T) {
+	router, recorder := newFixtureRouter(t, "smart_search")
+	defer recorder.Save()
+
+	Convey("Given the search api", t, func() {
+		Convey("When searching for a grid reference", func() {
+			w := get(router, "/search?q=ST%2018%2076")
+
+			Convey("Then it is searched for as a grid reference", func() {
+				So(w.Code, ShouldEqual, http.StatusOK)
+
+				var results models.SmartSearchResults
+				So(json.Unmarshal(w.Body.Bytes(), &results), ShouldBeNil)
+				So(results.Type, ShouldEqual, models.QueryTypeGridRef)
+				So(results.Count, ShouldEqual, 1)
+			})
+		})
+	})
+}
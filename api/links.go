@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+)
+
+// requestURL rebuilds the absolute url of the incoming request so links can be
+// generated relative to the host the client used to reach the api
+func requestURL(r *http.Request) *url.URL {
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		u.Scheme = proto
+	}
+
+	return &u
+}
+
+// geographyLinks returns the link to the geography resource represented by code
+func geographyLinks(requestURL *url.URL, code string) *models.ItemLinks {
+	if code == "" {
+		return nil
+	}
+
+	u := url.URL{
+		Scheme:   requestURL.Scheme,
+		Host:     requestURL.Host,
+		Path:     "/search",
+		RawQuery: url.Values{"q": []string{code}}.Encode(),
+	}
+
+	return &models.ItemLinks{
+		Geography: &models.LinkObject{HRef: u.String()},
+	}
+}
+
+func setSearchResultsLinks(r *http.Request, page *models.PageVariables, searchResults *models.SearchResults) {
+	u := requestURL(r)
+
+	searchResults.Links = page.Links(u, searchResults.TotalCount)
+	for i := range searchResults.Items {
+		searchResults.Items[i].Links = geographyLinks(u, searchResults.Items[i].Code)
+	}
+}
+
+func setSearchResultsWithLocationLinks(r *http.Request, page *models.PageVariables, searchResults *models.SearchResultsWithLocation) {
+	u := requestURL(r)
+
+	searchResults.Links = page.Links(u, searchResults.TotalCount)
+	for i := range searchResults.Items {
+		searchResults.Items[i].Links = geographyLinks(u, searchResults.Items[i].Code)
+	}
+}
@@ -6,45 +6,38 @@ import (
 	"strconv"
 
 	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
 	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
 	"github.com/ONSdigital/go-ns/request"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
-const (
-	intersects = "intersects"
-
-	boundaryFileNotFound = "boundary file does not exist by id"
-)
+const intersects = "intersects"
 
 func (api *SearchAPI) postParentSearch(w http.ResponseWriter, r *http.Request) {
 	defer request.DrainBody(r)
-	setAccessControl(w, http.MethodPost)
+	w.Header().Set("Content-Type", "application/json")
 
 	ctx := r.Context()
 
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
-
 	// Maybe remove logData?
-	logData := log.Data{
+	logData := tracing.AddLogData(ctx, log.Data{
 		"request_body": r.Body,
-	}
+	})
 
 	geoLocation, err := models.CreateGeoLocation(r.Body)
 	if err != nil {
 		log.Event(ctx, "postParentSearch endpoint: request body has the wrong structure", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
 	if err = models.ValidateShape(geoLocation); err != nil {
 		log.Event(ctx, "postParentSearch endpoint: invalid boundary file", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
@@ -57,14 +50,14 @@ func (api *SearchAPI) postParentSearch(w http.ResponseWriter, r *http.Request) {
 	// Add doc to boundary index
 	if _, err = api.elasticsearch.AddBoundaryFile(ctx, api.boundaryFileIndex, boundaryDoc); err != nil {
 		log.Event(ctx, "postParentSearch endpoint: failed to upload document to index", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
 	b, err := json.Marshal(boundaryDoc)
 	if err != nil {
 		log.Event(ctx, "postParentSearch endpoint: failed to marshal boundary document", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, errs.ErrInternalServer)
+		errs.WriteError(w, errs.ErrInternalServer)
 		return
 	}
 
@@ -72,19 +65,14 @@ func (api *SearchAPI) postParentSearch(w http.ResponseWriter, r *http.Request) {
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "postParentSearch: error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errs.WriteError(w, errs.ErrInternalServer)
 	}
 }
 
 func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
-	setAccessControl(w, http.MethodGet)
-
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
+	w.Header().Set("Content-Type", "application/json")
 
 	var err error
 
@@ -93,11 +81,11 @@ func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 	requestedLimit := r.FormValue("limit")
 	requestedOffset := r.FormValue("offset")
 
-	logData := log.Data{
+	logData := tracing.AddLogData(ctx, log.Data{
 		"id":               id,
 		"requested_limit":  requestedLimit,
 		"requested_offset": requestedOffset,
-	}
+	})
 
 	log.Event(ctx, "getParentSearch endpoint: incoming request", log.INFO, logData)
 
@@ -106,7 +94,7 @@ func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 		limit, err = strconv.Atoi(requestedLimit)
 		if err != nil {
 			log.Event(ctx, "getParentSearch endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
 			return
 		}
 	}
@@ -116,7 +104,7 @@ func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 		offset, err = strconv.Atoi(requestedOffset)
 		if err != nil {
 			log.Event(ctx, "getParentSearch endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
 			return
 		}
 	}
@@ -129,7 +117,7 @@ func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 
 	if err = page.Validate(); err != nil {
 		log.Event(ctx, "getParentSearch endpoint: validate pagination", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
@@ -142,13 +130,13 @@ func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 	boundaryFileResponse, _, err := api.elasticsearch.GetBoundaryFile(ctx, api.boundaryFileIndex, id)
 	if err != nil {
 		log.Event(ctx, "getParentSearch endpoint: failed to search for boundary file", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
 	if len(boundaryFileResponse.Hits.Hits) < 1 {
 		log.Event(ctx, "getParentSearch endpoint: failed to find boundary file", log.ERROR, log.Error(errs.ErrBoundaryFileNotFound), logData)
-		setErrorCode(w, errs.ErrBoundaryFileNotFound)
+		errs.WriteError(w, errs.ErrBoundaryFileNotFound)
 		return
 	}
 
@@ -162,14 +150,14 @@ func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 	response, _, err := api.elasticsearch.QueryGeoLocation(ctx, api.datasetIndex, geoLocation, page.Limit, page.Offset, intersects)
 	if err != nil {
 		log.Event(ctx, "getParentSearch endpoint: failed to query elastic search index", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
 	searchResults := &models.SearchResults{
-		Count:  response.Hits.Total,
-		Limit:  page.Limit,
-		Offset: page.Offset,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		TotalCount: int(response.Hits.Total),
 	}
 
 	for _, result := range response.Hits.HitList {
@@ -177,24 +165,22 @@ func (api *SearchAPI) getParentSearch(w http.ResponseWriter, r *http.Request) {
 		searchResults.Items = append(searchResults.Items, doc)
 	}
 
+	searchResults.Count = len(searchResults.Items)
+	metrics.ObserveResultSize("parent", searchResults.Count)
+
+	setSearchResultsLinks(r, page, searchResults)
+
 	b, err := json.Marshal(searchResults)
 	if err != nil {
 		log.Event(ctx, "getParentSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, errs.ErrInternalServer)
+		errs.WriteError(w, errs.ErrInternalServer)
 	}
 
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errs.WriteError(w, errs.ErrInternalServer)
 	}
 
 	log.Event(ctx, "getParentSearch endpoint: successfully searched index", log.INFO, logData)
 }
-
-func setAccessControl(w http.ResponseWriter, method string) {
-	w.Header().Set("Access-Control-Allow-Methods", method+",OPTIONS")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Max-Age", "86400")
-	w.Header().Set("Content-Type", "application/json")
-}
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
 
 	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
 	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/gorilla/mux"
 )
@@ -14,12 +17,7 @@ import (
 func (api *SearchAPI) getPlaceNameSearch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
-	setAccessControl(w, http.MethodGet)
-
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
+	w.Header().Set("Content-Type", "application/json")
 
 	var err error
 
@@ -28,18 +26,18 @@ func (api *SearchAPI) getPlaceNameSearch(w http.ResponseWriter, r *http.Request)
 	requestedLimit := r.FormValue("limit")
 	requestedOffset := r.FormValue("offset")
 
-	logData := log.Data{
+	logData := tracing.AddLogData(ctx, log.Data{
 		"place_name":       placename,
 		"requested_limit":  requestedLimit,
 		"requested_offset": requestedOffset,
-	}
+	})
 
 	limit := defaultLimit
 	if requestedLimit != "" {
 		limit, err = strconv.Atoi(requestedLimit)
 		if err != nil {
 			log.Event(ctx, "getPlaceNameSearch endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
 			return
 		}
 	}
@@ -49,7 +47,7 @@ func (api *SearchAPI) getPlaceNameSearch(w http.ResponseWriter, r *http.Request)
 		offset, err = strconv.Atoi(requestedOffset)
 		if err != nil {
 			log.Event(ctx, "getPlaceNameSearch endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
 			return
 		}
 	}
@@ -62,7 +60,7 @@ func (api *SearchAPI) getPlaceNameSearch(w http.ResponseWriter, r *http.Request)
 
 	if err = page.Validate(); err != nil {
 		log.Event(ctx, "getPlaceNameSearch endpoint: validate pagination", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
@@ -71,43 +69,58 @@ func (api *SearchAPI) getPlaceNameSearch(w http.ResponseWriter, r *http.Request)
 
 	log.Event(ctx, "getPlaceNameSearch endpoint: just before querying search index", log.INFO, logData)
 
-	// build dataset search query
-	query := buildSearchQuery(placename, limit, offset)
-
-	// query geographical areas index with text search
-	response, _, err := api.elasticsearch.GetBoundaryFiles(ctx, api.datasetIndex, query)
+	searchResults, err := api.searchByPlaceName(ctx, placename, page)
 	if err != nil {
-		log.Event(ctx, "getParentSearch endpoint: failed to query elastic search index", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		log.Event(ctx, "getPlaceNameSearch endpoint: failed to query elastic search index", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
 		return
 	}
 
-	searchResults := &models.SearchResultsWithLocation{
-		Count:  response.Hits.Total,
-		Limit:  page.Limit,
-		Offset: page.Offset,
-	}
-
-	for _, result := range response.Hits.HitList {
-		doc := result.Source
-		searchResults.Items = append(searchResults.Items, doc)
-	}
+	setSearchResultsWithLocationLinks(r, page, searchResults)
 
 	b, err := json.Marshal(searchResults)
 	if err != nil {
 		log.Event(ctx, "getParentSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, errs.ErrInternalServer)
+		errs.WriteError(w, errs.ErrInternalServer)
 	}
 
 	_, err = w.Write(b)
 	if err != nil {
 		log.Event(ctx, "error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errs.WriteError(w, errs.ErrInternalServer)
 	}
 
 	log.Event(ctx, "getParentSearch endpoint: successfully searched index", log.INFO, logData)
 }
 
+// searchByPlaceName runs a text search against the name field of the dataset index
+func (api *SearchAPI) searchByPlaceName(ctx context.Context, placename string, page *models.PageVariables) (*models.SearchResultsWithLocation, error) {
+	// build dataset search query
+	query := buildSearchQuery(placename, page.Limit, page.Offset)
+
+	// query geographical areas index with text search
+	response, _, err := api.elasticsearch.GetBoundaryFiles(ctx, api.datasetIndex, query)
+	if err != nil {
+		return nil, err
+	}
+
+	searchResults := &models.SearchResultsWithLocation{
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		TotalCount: int(response.Hits.Total),
+	}
+
+	for _, result := range response.Hits.HitList {
+		doc := result.Source
+		searchResults.Items = append(searchResults.Items, doc)
+	}
+
+	searchResults.Count = len(searchResults.Items)
+	metrics.ObserveResultSize(models.QueryTypePlaceName, searchResults.Count)
+
+	return searchResults, nil
+}
+
 func buildSearchQuery(placename string, limit, offset int) interface{} {
 
 	name := make(map[string]string)
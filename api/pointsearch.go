@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/helpers"
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
+	"github.com/ONSdigital/log.go/log"
+)
+
+// resultSizeRadius labels the result sizes of searches around a location
+const resultSizeRadius = "radius"
+
+// locationFromRequest reads the location a search is made from out of the query parameters
+func locationFromRequest(r *http.Request) models.Location {
+	return models.Location{
+		Lat:      r.FormValue("lat"),
+		Lon:      r.FormValue("lon"),
+		Easting:  r.FormValue("easting"),
+		Northing: r.FormValue("northing"),
+		GridRef:  r.FormValue("gridref"),
+	}
+}
+
+func (api *SearchAPI) getPointSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var err error
+
+	location := locationFromRequest(r)
+	requestedLimit := r.FormValue("limit")
+	requestedOffset := r.FormValue("offset")
+
+	logData := tracing.AddLogData(ctx, log.Data{
+		"location":         location,
+		"requested_limit":  requestedLimit,
+		"requested_offset": requestedOffset,
+	})
+
+	log.Event(ctx, "getPointSearch endpoint: incoming request", log.INFO, logData)
+
+	lat, lon, err := location.Coordinate()
+	if err != nil {
+		log.Event(ctx, "getPointSearch endpoint: invalid location", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	limit := defaultLimit
+	if requestedLimit != "" {
+		limit, err = strconv.Atoi(requestedLimit)
+		if err != nil {
+			log.Event(ctx, "getPointSearch endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
+			return
+		}
+	}
+
+	offset := defaultOffset
+	if requestedOffset != "" {
+		offset, err = strconv.Atoi(requestedOffset)
+		if err != nil {
+			log.Event(ctx, "getPointSearch endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
+			return
+		}
+	}
+
+	page := &models.PageVariables{
+		DefaultMaxResults: api.defaultMaxResults,
+		Limit:             limit,
+		Offset:            offset,
+	}
+
+	if err = page.Validate(); err != nil {
+		log.Event(ctx, "getPointSearch endpoint: validate pagination", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	logData["lat"] = lat
+	logData["lon"] = lon
+	logData["limit"] = page.Limit
+	logData["offset"] = page.Offset
+
+	log.Event(ctx, "getPointSearch endpoint: just before querying search index", log.INFO, logData)
+
+	searchResults, err := api.searchByLocation(ctx, location, lat, lon, page)
+	if err != nil {
+		log.Event(ctx, "getPointSearch endpoint: failed to search by point", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	setSearchResultsLinks(r, page, searchResults)
+
+	b, err := json.Marshal(searchResults)
+	if err != nil {
+		log.Event(ctx, "getPointSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "error writing response", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
+	}
+
+	log.Event(ctx, "getPointSearch endpoint: successfully searched index", log.INFO, logData)
+}
+
+func (api *SearchAPI) getRadiusSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var err error
+
+	location := locationFromRequest(r)
+	distance := r.FormValue("distance")
+	requestedLimit := r.FormValue("limit")
+	requestedOffset := r.FormValue("offset")
+	requestedRelation := r.FormValue("relation")
+
+	logData := tracing.AddLogData(ctx, log.Data{
+		"location":           location,
+		"distance":           distance,
+		"requested_limit":    requestedLimit,
+		"requested_offset":   requestedOffset,
+		"requested_relation": requestedRelation,
+	})
+
+	log.Event(ctx, "getRadiusSearch endpoint: incoming request", log.INFO, logData)
+
+	lat, lon, err := location.Coordinate()
+	if err != nil {
+		log.Event(ctx, "getRadiusSearch endpoint: invalid location", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	limit := defaultLimit
+	if requestedLimit != "" {
+		limit, err = strconv.Atoi(requestedLimit)
+		if err != nil {
+			log.Event(ctx, "getRadiusSearch endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
+			return
+		}
+	}
+
+	offset := defaultOffset
+	if requestedOffset != "" {
+		offset, err = strconv.Atoi(requestedOffset)
+		if err != nil {
+			log.Event(ctx, "getRadiusSearch endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
+			return
+		}
+	}
+
+	relation := defaultRelation
+	if requestedRelation != "" {
+		relation, err = models.ValidateRelation(requestedRelation)
+		if err != nil {
+			log.Event(ctx, "getRadiusSearch endpoint: request relation parameter error", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+	}
+
+	page := &models.PageVariables{
+		DefaultMaxResults: api.defaultMaxResults,
+		Limit:             limit,
+		Offset:            offset,
+	}
+
+	distObj, err := models.ValidateDistance(distance)
+	if err != nil {
+		log.Event(ctx, "getRadiusSearch endpoint: validate query param, distance", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	if err = page.Validate(); err != nil {
+		log.Event(ctx, "getRadiusSearch endpoint: validate pagination", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	logData["lat"] = lat
+	logData["lon"] = lon
+	logData["limit"] = page.Limit
+	logData["offset"] = page.Offset
+
+	log.Event(ctx, "getRadiusSearch endpoint: just before querying search index", log.INFO, logData)
+
+	searchResults, err := api.searchByRadius(ctx, helpers.Coordinate{Lat: lat, Lon: lon}, distObj, relation, page)
+	if err != nil {
+		log.Event(ctx, "getRadiusSearch endpoint: failed to search by radius", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	metrics.ObserveResultSize(resultSizeRadius, searchResults.Count)
+
+	setSearchResultsLinks(r, page, searchResults)
+
+	b, err := json.Marshal(searchResults)
+	if err != nil {
+		log.Event(ctx, "getRadiusSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "error writing response", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
+	}
+
+	log.Event(ctx, "getRadiusSearch endpoint: successfully searched index", log.INFO, logData)
+}
+
+// searchByRadius queries the dataset index with a circular polygon built from the
+// coordinate and distance
+func (api *SearchAPI) searchByRadius(ctx context.Context, coordinate helpers.Coordinate, distObj *models.DistObj, relation string, page *models.PageVariables) (*models.SearchResults, error) {
+	// calculate distance (in metres) based on distObj
+	dist := distObj.CalculateDistanceInMetres(ctx)
+
+	// build polygon from circle using long/lat of the coordinate and distance
+	polygonShape, err := helpers.CircleToPolygon(coordinate, dist, defaultSegments)
+	if err != nil {
+		return nil, err
+	}
+
+	var coordinates [][][]float64
+	geoLocation := &models.GeoLocation{
+		Type:        "polygon", // TODO make constant variable?
+		Coordinates: append(coordinates, polygonShape.Coordinates),
+	}
+
+	// query dataset index with polygon search
+	response, _, err := api.elasticsearch.QueryGeoLocation(ctx, api.datasetIndex, geoLocation, page.Limit, page.Offset, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	searchResults := &models.SearchResults{
+		TotalCount: int(response.Hits.Total),
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	}
+
+	for _, result := range response.Hits.HitList {
+		doc := result.Source
+		searchResults.Items = append(searchResults.Items, doc)
+	}
+
+	searchResults.Count = len(searchResults.Items)
+
+	return searchResults, nil
+}
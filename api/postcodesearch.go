@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -8,7 +9,9 @@ import (
 
 	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
 	"github.com/ONSdigital/dp-census-search-prototypes/helpers"
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
 	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
 	"github.com/ONSdigital/log.go/log"
 	"github.com/gorilla/mux"
 )
@@ -18,24 +21,12 @@ const (
 	defaultOffset   = 0
 	defaultSegments = 30
 	defaultRelation = "within"
-
-	postcodeNotFound = "postcode not found"
-
-	internalError         = "internal server error"
-	exceedsDefaultMaximum = "the maximum offset has been reached, the offset cannot be more than"
-	invalidDistanceParam  = "invalid distance value"
-	invalidRelationParam  = "incorrect relation value"
 )
 
 func (api *SearchAPI) getPostcodeSearch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
-	setAccessControl(w, http.MethodGet)
-
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
+	w.Header().Set("Content-Type", "application/json")
 
 	var err error
 
@@ -49,14 +40,14 @@ func (api *SearchAPI) getPostcodeSearch(w http.ResponseWriter, r *http.Request)
 	requestedOffset := r.FormValue("offset")
 	requestedRelation := r.FormValue("relation")
 
-	logData := log.Data{
+	logData := tracing.AddLogData(ctx, log.Data{
 		"postcode":           lcPostcode,
 		"postcode_raw":       postcode,
 		"distance":           distance,
 		"requested_limit":    requestedLimit,
 		"requested_offset":   requestedOffset,
 		"requested_relation": requestedRelation,
-	}
+	})
 
 	log.Event(ctx, "getPostcodeSearch endpoint: incoming request", log.INFO, logData)
 
@@ -65,7 +56,7 @@ func (api *SearchAPI) getPostcodeSearch(w http.ResponseWriter, r *http.Request)
 		limit, err = strconv.Atoi(requestedLimit)
 		if err != nil {
 			log.Event(ctx, "getPostcodeSearch endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
 			return
 		}
 	}
@@ -75,7 +66,7 @@ func (api *SearchAPI) getPostcodeSearch(w http.ResponseWriter, r *http.Request)
 		offset, err = strconv.Atoi(requestedOffset)
 		if err != nil {
 			log.Event(ctx, "getPostcodeSearch endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, errs.ErrParsingQueryParameters)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
 			return
 		}
 	}
@@ -85,7 +76,7 @@ func (api *SearchAPI) getPostcodeSearch(w http.ResponseWriter, r *http.Request)
 		relation, err = models.ValidateRelation(requestedRelation)
 		if err != nil {
 			log.Event(ctx, "getPostcodeSearch endpoint: request relation parameter error", log.ERROR, log.Error(err), logData)
-			setErrorCode(w, err)
+			errs.WriteError(w, err)
 			return
 		}
 	}
@@ -99,13 +90,13 @@ func (api *SearchAPI) getPostcodeSearch(w http.ResponseWriter, r *http.Request)
 	distObj, err := models.ValidateDistance(distance)
 	if err != nil {
 		log.Event(ctx, "getPostcodeSearch endpoint: validate query param, distance", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
 	if err = page.Validate(); err != nil {
 		log.Event(ctx, "getPostcodeSearch endpoint: validate pagination", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
+		errs.WriteError(w, err)
 		return
 	}
 
@@ -114,91 +105,65 @@ func (api *SearchAPI) getPostcodeSearch(w http.ResponseWriter, r *http.Request)
 
 	log.Event(ctx, "getPostcodeSearch endpoint: just before querying search index", log.INFO, logData)
 
-	// lookup postcode
-	postcodeResponse, _, err := api.elasticsearch.GetPostcodes(ctx, api.postcodeIndex, lcPostcode)
+	searchResults, err := api.searchByPostcode(ctx, lcPostcode, distObj, relation, page)
 	if err != nil {
-		log.Event(ctx, "getPostcodeSearch endpoint: failed to search for postcode", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
-		return
-	}
-
-	if len(postcodeResponse.Hits.Hits) < 1 {
-		log.Event(ctx, "getPostcodeSearch endpoint: failed to find postcode", log.ERROR, log.Error(errs.ErrPostcodeNotFound), logData)
-		setErrorCode(w, errs.ErrPostcodeNotFound)
+		log.Event(ctx, "getPostcodeSearch endpoint: failed to search by postcode", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
 		return
 	}
 
-	// calculate distance (in metres) based on distObj
-	dist := distObj.CalculateDistanceInMetres(ctx)
+	setSearchResultsLinks(r, page, searchResults)
 
-	pcCoordinate := helpers.Coordinate{
-		Lat: postcodeResponse.Hits.Hits[0].Source.Pin.Location.Lat,
-		Lon: postcodeResponse.Hits.Hits[0].Source.Pin.Location.Lon,
+	b, err := json.Marshal(searchResults)
+	if err != nil {
+		log.Event(ctx, "getPostcodeSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
+		return
 	}
 
-	// build polygon from circle using long/lat of postcod and distance
-	polygonShape, err := helpers.CircleToPolygon(pcCoordinate, dist, defaultSegments)
+	_, err = w.Write(b)
 	if err != nil {
-		setErrorCode(w, err)
+		log.Event(ctx, "error writing response", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
 	}
 
-	var coordinates [][][]float64
-	geoLocation := &models.GeoLocation{
-		Type:        "polygon", // TODO make constant variable?
-		Coordinates: append(coordinates, polygonShape.Coordinates),
-	}
+	log.Event(ctx, "getPostcodeSearch endpoint: successfully searched index", log.INFO, logData)
+}
 
-	// query dataset index with polygon search (intersect)
-	response, _, err := api.elasticsearch.QueryGeoLocation(ctx, api.datasetIndex, geoLocation, page.Limit, page.Offset, relation)
+// searchByPostcode finds the postcode and then queries the dataset index with
+// a circular polygon built from the postcode location and distance, returning the
+// postcode with its geography codes alongside the results
+func (api *SearchAPI) searchByPostcode(ctx context.Context, postcode string, distObj *models.DistObj, relation string, page *models.PageVariables) (*models.SearchResults, error) {
+	// lookup postcode
+	postcodeResponse, _, err := api.elasticsearch.GetPostcodes(ctx, api.postcodeIndex, postcode)
 	if err != nil {
-		log.Event(ctx, "getPostcodeSearch endpoint: failed to query elastic search index", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, err)
-		return
+		return nil, err
 	}
 
-	searchResults := &models.SearchResults{
-		TotalCount: response.Hits.Total,
-		Limit:      page.Limit,
-		Offset:     page.Offset,
+	if len(postcodeResponse.Hits.Hits) < 1 {
+		return nil, errs.ErrPostcodeNotFound
 	}
 
-	for _, result := range response.Hits.HitList {
-		doc := result.Source
-		searchResults.Items = append(searchResults.Items, doc)
-	}
+	source := postcodeResponse.Hits.Hits[0].Source
 
-	searchResults.Count = len(searchResults.Items)
+	pcCoordinate := helpers.Coordinate{
+		Lat: source.Pin.Location.Lat,
+		Lon: source.Pin.Location.Lon,
+	}
 
-	b, err := json.Marshal(searchResults)
+	searchResults, err := api.searchByRadius(ctx, pcCoordinate, distObj, relation, page)
 	if err != nil {
-		log.Event(ctx, "getPostcodeSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
-		setErrorCode(w, errs.ErrInternalServer)
-		return
+		return nil, err
 	}
 
-	_, err = w.Write(b)
-	if err != nil {
-		log.Event(ctx, "error writing response", log.ERROR, log.Error(err), logData)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	searchResults.Postcode = &models.Postcode{
+		Postcode:          source.RawPostcode,
+		Lat:               source.Pin.Location.Lat,
+		Lon:               source.Pin.Location.Lon,
+		PostcodeGeography: source.PostcodeGeography,
 	}
 
-	log.Event(ctx, "getPostcodeSearch endpoint: successfully searched index", log.INFO, logData)
-}
+	metrics.ObserveResultSize(models.QueryTypePostcode, searchResults.Count)
 
-func setErrorCode(w http.ResponseWriter, err error) {
-
-	switch {
-	case errs.NotFoundMap[err]:
-		http.Error(w, err.Error(), http.StatusNotFound)
-	case errs.BadRequestMap[err]:
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), exceedsDefaultMaximum):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), invalidDistanceParam):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	case strings.Contains(err.Error(), invalidRelationParam):
-		http.Error(w, err.Error(), http.StatusBadRequest)
-	default:
-		http.Error(w, internalError, http.StatusInternalServerError)
-	}
+	return searchResults, nil
 }
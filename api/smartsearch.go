@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const defaultDistance = "1,km"
+
+func (api *SearchAPI) getSmartSearch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var err error
+
+	q := strings.TrimSpace(r.FormValue("q"))
+
+	distance := r.FormValue("distance")
+	requestedLimit := r.FormValue("limit")
+	requestedOffset := r.FormValue("offset")
+	requestedRelation := r.FormValue("relation")
+
+	logData := tracing.AddLogData(ctx, log.Data{
+		"q":                  q,
+		"distance":           distance,
+		"requested_limit":    requestedLimit,
+		"requested_offset":   requestedOffset,
+		"requested_relation": requestedRelation,
+	})
+
+	log.Event(ctx, "getSmartSearch endpoint: incoming request", log.INFO, logData)
+
+	if q == "" {
+		log.Event(ctx, "getSmartSearch endpoint: missing search term", log.ERROR, log.Error(errs.ErrMissingSearchTerm), logData)
+		errs.WriteError(w, errs.ErrMissingSearchTerm)
+		return
+	}
+
+	limit := defaultLimit
+	if requestedLimit != "" {
+		limit, err = strconv.Atoi(requestedLimit)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: request limit parameter error", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
+			return
+		}
+	}
+
+	offset := defaultOffset
+	if requestedOffset != "" {
+		offset, err = strconv.Atoi(requestedOffset)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: request offset parameter error", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, errs.ErrParsingQueryParameters)
+			return
+		}
+	}
+
+	page := &models.PageVariables{
+		DefaultMaxResults: api.defaultMaxResults,
+		Limit:             limit,
+		Offset:            offset,
+	}
+
+	if err = page.Validate(); err != nil {
+		log.Event(ctx, "getSmartSearch endpoint: validate pagination", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, err)
+		return
+	}
+
+	logData["limit"] = page.Limit
+	logData["offset"] = page.Offset
+
+	queryType := models.ClassifyQuery(q)
+	logData["type"] = queryType
+
+	log.Event(ctx, "getSmartSearch endpoint: just before querying search index", log.INFO, logData)
+
+	searchResults := &models.SmartSearchResults{
+		Type:   queryType,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+
+	switch queryType {
+	case models.QueryTypePostcode:
+		if distance == "" {
+			distance = defaultDistance
+		}
+
+		distObj, err := models.ValidateDistance(distance)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: validate query param, distance", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		relation := defaultRelation
+		if requestedRelation != "" {
+			relation, err = models.ValidateRelation(requestedRelation)
+			if err != nil {
+				log.Event(ctx, "getSmartSearch endpoint: request relation parameter error", log.ERROR, log.Error(err), logData)
+				errs.WriteError(w, err)
+				return
+			}
+		}
+
+		postcode := strings.ToLower(strings.ReplaceAll(q, " ", ""))
+
+		results, err := api.searchByPostcode(ctx, postcode, distObj, relation, page)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: failed to search by postcode", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		setSearchResultsLinks(r, page, results)
+
+		searchResults.Count = results.Count
+		searchResults.Items = results.Items
+		searchResults.Links = results.Links
+		searchResults.Postcode = results.Postcode
+		searchResults.TotalCount = results.TotalCount
+	case models.QueryTypeCode:
+		results, err := api.searchByCode(ctx, strings.ToUpper(q), page)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: failed to search by code", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		setSearchResultsWithLocationLinks(r, page, results)
+
+		searchResults.Count = results.Count
+		searchResults.Items = results.Items
+		searchResults.Links = results.Links
+		searchResults.TotalCount = results.TotalCount
+	case models.QueryTypeGridRef:
+		location := models.Location{GridRef: q}
+
+		lat, lon, err := location.Coordinate()
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: invalid grid reference", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		results, err := api.searchByLocation(ctx, location, lat, lon, page)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: failed to search by grid reference", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		setSearchResultsLinks(r, page, results)
+
+		searchResults.Count = results.Count
+		searchResults.Items = results.Items
+		searchResults.Links = results.Links
+		searchResults.TotalCount = results.TotalCount
+	case models.QueryTypePoint:
+		lat, lon, err := models.ParsePoint(q)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: invalid point", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		results, err := api.searchByPoint(ctx, lat, lon, page)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: failed to search by point", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		setSearchResultsLinks(r, page, results)
+
+		searchResults.Count = results.Count
+		searchResults.Items = results.Items
+		searchResults.Links = results.Links
+		searchResults.TotalCount = results.TotalCount
+	default:
+		results, err := api.searchByPlaceName(ctx, q, page)
+		if err != nil {
+			log.Event(ctx, "getSmartSearch endpoint: failed to search by place name", log.ERROR, log.Error(err), logData)
+			errs.WriteError(w, err)
+			return
+		}
+
+		setSearchResultsWithLocationLinks(r, page, results)
+
+		searchResults.Count = results.Count
+		searchResults.Items = results.Items
+		searchResults.Links = results.Links
+		searchResults.TotalCount = results.TotalCount
+	}
+
+	b, err := json.Marshal(searchResults)
+	if err != nil {
+		log.Event(ctx, "getSmartSearch endpoint: failed to marshal search resource into bytes", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		log.Event(ctx, "error writing response", log.ERROR, log.Error(err), logData)
+		errs.WriteError(w, errs.ErrInternalServer)
+	}
+
+	log.Event(ctx, "getSmartSearch endpoint: successfully searched index", log.INFO, logData)
+}
+
+// searchByCode looks up geographical areas in the dataset index by their GSS code
+func (api *SearchAPI) searchByCode(ctx context.Context, code string, page *models.PageVariables) (*models.SearchResultsWithLocation, error) {
+	query := &models.Body{
+		From: page.Offset,
+		Size: page.Limit,
+		Query: models.Query{
+			Bool: models.Bool{
+				Filter: []models.Filter{
+					{
+						Term: map[string]string{"code": code},
+					},
+				},
+			},
+		},
+		Sort:      []models.Scores{{Score: models.Score{Order: "desc"}}},
+		TotalHits: true,
+	}
+
+	response, _, err := api.elasticsearch.GetBoundaryFiles(ctx, api.datasetIndex, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Hits.HitList) < 1 {
+		return nil, errs.ErrCodeNotFound
+	}
+
+	searchResults := &models.SearchResultsWithLocation{
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		TotalCount: int(response.Hits.Total),
+	}
+
+	for _, result := range response.Hits.HitList {
+		searchResults.Items = append(searchResults.Items, result.Source)
+	}
+
+	searchResults.Count = len(searchResults.Items)
+	metrics.ObserveResultSize(models.QueryTypeCode, searchResults.Count)
+
+	return searchResults, nil
+}
+
+// searchByLocation finds all geographical areas in the dataset index that contain the
+// location at lat, lon, or that overlap the grid square of a grid reference coarser than a
+// metre
+func (api *SearchAPI) searchByLocation(ctx context.Context, location models.Location, lat, lon float64, page *models.PageVariables) (*models.SearchResults, error) {
+	if square := location.GridSquare(); square != nil {
+		return api.searchByShape(ctx, square, models.QueryTypeGridRef, page)
+	}
+
+	return api.searchByPoint(ctx, lat, lon, page)
+}
+
+// searchByPoint finds all geographical areas in the dataset index that contain the point
+func (api *SearchAPI) searchByPoint(ctx context.Context, lat, lon float64, page *models.PageVariables) (*models.SearchResults, error) {
+	geoLocation := &models.GeoLocation{
+		Type:        "point",
+		Coordinates: []float64{lon, lat},
+	}
+
+	return api.searchByShape(ctx, geoLocation, models.QueryTypePoint, page)
+}
+
+// searchByShape finds all geographical areas in the dataset index that intersect the shape,
+// recording the number found against the type of search
+func (api *SearchAPI) searchByShape(ctx context.Context, geoLocation *models.GeoLocation, queryType string, page *models.PageVariables) (*models.SearchResults, error) {
+	response, _, err := api.elasticsearch.QueryGeoLocation(ctx, api.datasetIndex, geoLocation, page.Limit, page.Offset, intersects)
+	if err != nil {
+		return nil, err
+	}
+
+	searchResults := &models.SearchResults{
+		TotalCount: int(response.Hits.Total),
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	}
+
+	for _, result := range response.Hits.HitList {
+		searchResults.Items = append(searchResults.Items, result.Source)
+	}
+
+	searchResults.Count = len(searchResults.Items)
+	metrics.ObserveResultSize(queryType, searchResults.Count)
+
+	return searchResults, nil
+}
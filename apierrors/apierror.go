@@ -0,0 +1,149 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error represents an error returned to clients of the API as json. Code is stable
+// and safe for clients to match on, unlike the message.
+type Error struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Field   string                 `json:"field,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	Status  int                    `json:"-"`
+
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter time.Duration `json:"-"`
+}
+
+// Unavailable is implemented by errors returned while something the API depends on is
+// known to be down, giving how long clients should wait before trying again
+type Unavailable interface {
+	error
+	RetryAfter() time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// A list of error codes returned by the API
+const (
+	CodeBoundaryFileNotFound  = "boundary_file_not_found"
+	CodeCodeNotFound          = "code_not_found"
+	CodeInternalServerError   = "internal_server_error"
+	CodeInvalidCoordinates    = "invalid_coordinates"
+	CodeInvalidDistance       = "invalid_distance"
+	CodeInvalidLocation       = "invalid_location"
+	CodeInvalidPoint          = "invalid_point"
+	CodeInvalidQueryParameter = "invalid_query_parameter"
+	CodeInvalidRelation       = "invalid_relation"
+	CodeInvalidRequestBody    = "invalid_request_body"
+	CodeInvalidShape          = "invalid_shape"
+	CodeMaximumOffsetReached  = "maximum_offset_reached"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodeMissingField          = "missing_field"
+	CodeMissingLocation       = "missing_location"
+	CodeMissingSearchTerm     = "missing_search_term"
+	CodePostcodeNotFound      = "postcode_not_found"
+	CodeResourceNotFound      = "resource_not_found"
+	CodeServiceUnavailable    = "service_unavailable"
+	CodeTooManyRequests       = "too_many_requests"
+	CodeUnauthorised          = "unauthorised"
+)
+
+var codes = map[error]string{
+	ErrBoundaryFileNotFound:    CodeBoundaryFileNotFound,
+	ErrCodeNotFound:            CodeCodeNotFound,
+	ErrConflictingLocation:     CodeInvalidLocation,
+	ErrEmptyCoordinates:        CodeInvalidCoordinates,
+	ErrEmptyDistanceTerm:       CodeInvalidDistance,
+	ErrEmptyShape:              CodeInvalidShape,
+	ErrInvalidCoordinates:      CodeInvalidCoordinates,
+	ErrInvalidPoint:            CodeInvalidPoint,
+	ErrInvalidShape:            CodeInvalidShape,
+	ErrLessThanFourCoordinates: CodeInvalidCoordinates,
+	ErrLessThanTwoPolygons:     CodeInvalidShape,
+	ErrMissingLocation:         CodeMissingLocation,
+	ErrMissingSearchTerm:       CodeMissingSearchTerm,
+	ErrMissingShapeFile:        CodeMissingField,
+	ErrMethodNotAllowed:        CodeMethodNotAllowed,
+	ErrMissingType:             CodeMissingField,
+	ErrParsingQueryParameters:  CodeInvalidQueryParameter,
+	ErrPostcodeNotFound:        CodePostcodeNotFound,
+	ErrResourceNotFound:        CodeResourceNotFound,
+	ErrServiceUnavailable:      CodeServiceUnavailable,
+	ErrTooManyRequests:         CodeTooManyRequests,
+	ErrUnableToParseJSON:       CodeInvalidRequestBody,
+	ErrUnableToReadMessage:     CodeInvalidRequestBody,
+	ErrUnauthorised:            CodeUnauthorised,
+}
+
+// ToAPIError converts err into the error returned to clients. Unavailable errors are
+// returned as service unavailable. Errors not known to be caused by the request are
+// hidden behind a generic internal server error.
+func ToAPIError(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var unavailable Unavailable
+	if errors.As(err, &unavailable) {
+		return &Error{
+			Code:       CodeServiceUnavailable,
+			Message:    ErrServiceUnavailable.Error(),
+			Status:     http.StatusServiceUnavailable,
+			RetryAfter: unavailable.RetryAfter(),
+		}
+	}
+
+	var status int
+	switch {
+	case NotFoundMap[err]:
+		status = http.StatusNotFound
+	case BadRequestMap[err]:
+		status = http.StatusBadRequest
+	case err == ErrUnauthorised:
+		status = http.StatusUnauthorized
+	case err == ErrMethodNotAllowed:
+		status = http.StatusMethodNotAllowed
+	case err == ErrTooManyRequests:
+		status = http.StatusTooManyRequests
+	case err == ErrServiceUnavailable:
+		status = http.StatusServiceUnavailable
+	default:
+		return &Error{
+			Code:    CodeInternalServerError,
+			Message: ErrInternalServer.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &Error{
+		Code:    codes[err],
+		Message: err.Error(),
+		Status:  status,
+	}
+}
+
+// WriteError writes err to the response as json with the matching status code
+func WriteError(w http.ResponseWriter, err error) {
+	apiErr := ToAPIError(err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if apiErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.RetryAfter.Seconds()))))
+	}
+	w.WriteHeader(apiErr.Status)
+
+	// nothing more can be done for the client if the write fails
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
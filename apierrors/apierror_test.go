@@ -0,0 +1,78 @@
+package apierrors_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteError(t *testing.T) {
+	Convey("Given a known client error", t, func() {
+		w := httptest.NewRecorder()
+		errs.WriteError(w, errs.ErrPostcodeNotFound)
+
+		Convey("Then it is written as json with its code and status", func() {
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(w.Header().Get("Content-Type"), ShouldEqual, "application/json")
+			So(w.Body.String(), ShouldEqual, `{"code":"postcode_not_found","message":"postcode not found"}`+"\n")
+		})
+	})
+
+	Convey("Given an invalid distance", t, func() {
+		_, err := models.ValidateDistance("40,lightyears")
+
+		w := httptest.NewRecorder()
+		errs.WriteError(w, err)
+
+		Convey("Then the field and rejected value are included", func() {
+			var body errs.Error
+			So(json.Unmarshal(w.Body.Bytes(), &body), ShouldBeNil)
+
+			So(w.Code, ShouldEqual, http.StatusBadRequest)
+			So(body.Code, ShouldEqual, errs.CodeInvalidDistance)
+			So(body.Field, ShouldEqual, "distance")
+			So(body.Details, ShouldResemble, map[string]interface{}{"value": "40,lightyears"})
+		})
+	})
+
+	Convey("Given an offset beyond the maximum", t, func() {
+		page := &models.PageVariables{DefaultMaxResults: 1000, Limit: 50, Offset: 1000}
+		apiErr := errs.ToAPIError(page.Validate())
+
+		So(apiErr.Status, ShouldEqual, http.StatusBadRequest)
+		So(apiErr.Code, ShouldEqual, errs.CodeMaximumOffsetReached)
+		So(apiErr.Field, ShouldEqual, "offset")
+	})
+
+	Convey("Given an unexpected error", t, func() {
+		w := httptest.NewRecorder()
+		errs.WriteError(w, errors.New("dial tcp 10.0.0.1:9200: connection refused"))
+
+		Convey("Then the detail is hidden behind an internal server error", func() {
+			So(w.Code, ShouldEqual, http.StatusInternalServerError)
+			So(w.Body.String(), ShouldEqual, `{"code":"internal_server_error","message":"internal server error"}`+"\n")
+		})
+	})
+
+	Convey("Given the elasticsearch circuit breaker is open", t, func() {
+		breaker := es.NewCircuitBreaker(1, 30*time.Second)
+		breaker.Failure()
+
+		w := httptest.NewRecorder()
+		errs.WriteError(w, breaker.Allow())
+
+		Convey("Then service unavailable is returned with when to retry", func() {
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+			So(w.Header().Get("Retry-After"), ShouldEqual, "30")
+			So(w.Body.String(), ShouldEqual, `{"code":"service_unavailable","message":"service temporarily unavailable, try again later"}`+"\n")
+		})
+	})
+}
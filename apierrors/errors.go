@@ -5,38 +5,55 @@ import "errors"
 // A list of error messages for Search API
 var (
 	ErrBoundaryFileNotFound    = errors.New("invalid id, boundary file does not exist")
+	ErrConflictingLocation     = errors.New("conflicting location, set only one of lat and lon, easting and northing or gridref")
+	ErrCodeNotFound            = errors.New("geography code not found")
 	ErrEmptyCoordinates        = errors.New("missing coordinates in array")
 	ErrEmptyDistanceTerm       = errors.New("empty query term: distance")
 	ErrEmptyShape              = errors.New("empty shape")
 	ErrIndexNotFound           = errors.New("search index not found")
 	ErrInternalServer          = errors.New("internal server error")
 	ErrInvalidCoordinates      = errors.New("should contain two coordinates, representing [latitude, longitude]")
+	ErrInvalidPoint            = errors.New("invalid point, should contain a latitude and longitude separated by a comma e.g. 51.4816,-3.1791")
 	ErrInvalidShape            = errors.New("invalid list of coordinates, the first and last coordinates should be the same to complete boundary line")
 	ErrLessThanFourCoordinates = errors.New("invalid number of coordinates, need a minimum of 4 values")
 	ErrLessThanTwoPolygons     = errors.New("invalid number of polygons, needs a minimum of 2 values if the geometry type is set to multipolygon")
 	ErrMarshallingQuery        = errors.New("failed to marshal query to bytes for request body to send to elastic")
+	ErrMethodNotAllowed        = errors.New("method not allowed for this resource")
+	ErrMissingLocation         = errors.New("missing location, lat and lon, easting and northing or gridref query parameters must be set")
+	ErrMissingSearchTerm       = errors.New("missing search term, q query parameter must be set")
 	ErrMissingShapeFile        = errors.New("missing shapefile value in request")
 	ErrMissingType             = errors.New("missing type value in request")
 	ErrParsingQueryParameters  = errors.New("failed to parse query parameters, values must be an integer")
 	ErrPostcodeNotFound        = errors.New("postcode not found")
+	ErrResourceNotFound        = errors.New("resource not found")
+	ErrServiceUnavailable      = errors.New("service temporarily unavailable, try again later")
+	ErrTooManyRequests         = errors.New("too many requests, rate limit exceeded")
 	ErrUnableToParseJSON       = errors.New("failed to parse json body")
+	ErrUnauthorised            = errors.New("unauthorised, valid credentials are required")
 	ErrUnableToReadMessage     = errors.New("failed to read message body")
 	ErrUnexpectedStatusCode    = errors.New("unexpected status code from elastic api")
 	ErrUnmarshallingJSON       = errors.New("failed to unmarshal data")
 
 	NotFoundMap = map[error]bool{
 		ErrBoundaryFileNotFound: true,
+		ErrCodeNotFound:         true,
 		ErrPostcodeNotFound:     true,
+		ErrResourceNotFound:     true,
 	}
 
 	BadRequestMap = map[error]bool{
+		ErrConflictingLocation:     true,
 		ErrEmptyCoordinates:        true,
 		ErrEmptyDistanceTerm:       true,
 		ErrEmptyShape:              true,
 		ErrInvalidCoordinates:      true,
+		ErrInvalidPoint:            true,
 		ErrInvalidShape:            true,
 		ErrLessThanFourCoordinates: true,
 		ErrLessThanTwoPolygons:     true,
+		ErrMissingLocation:         true,
+		ErrMissingSearchTerm:       true,
+		ErrMissingShapeFile:        true,
 		ErrMissingType:             true,
 		ErrParsingQueryParameters:  true,
 		ErrUnableToParseJSON:       true,
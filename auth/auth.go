@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/log.go/log"
+	jwt "github.com/golang-jwt/jwt"
+)
+
+// APIKeyHeader is the header clients send a static API key in
+const APIKeyHeader = "X-Api-Key"
+
+type contextKey string
+
+const identityKey = contextKey("identity")
+
+// A list of errors returned when verifying credentials
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrMissingCredentials = errors.New("missing credentials")
+)
+
+// Verifier checks the credentials sent with a request and returns the identity of the caller
+type Verifier interface {
+	Verify(r *http.Request) (string, error)
+}
+
+// StaticKeys verifies requests against a fixed set of API keys sent in the X-Api-Key header
+type StaticKeys struct {
+	keys map[string]string
+}
+
+// NewStaticKeys creates a StaticKeys verifier from a map of client names to API keys
+func NewStaticKeys(keys map[string]string) *StaticKeys {
+	return &StaticKeys{keys: keys}
+}
+
+// Verify returns the name of the client the API key belongs to
+func (s *StaticKeys) Verify(r *http.Request) (string, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return "", ErrMissingCredentials
+	}
+
+	// compare against every key so the time taken does not reveal which key matched
+	var identity string
+	for name, k := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			identity = name
+		}
+	}
+
+	if identity == "" {
+		return "", ErrInvalidCredentials
+	}
+
+	return identity, nil
+}
+
+// JWT verifies HMAC signed JSON web tokens sent as a bearer token in the Authorization header
+type JWT struct {
+	secret []byte
+	issuer string
+}
+
+// NewJWT creates a JWT verifier for tokens signed with the shared secret. Tokens must have an
+// exp claim so that none are valid forever. When issuer is set the iss claim of the token
+// must match it.
+func NewJWT(secret []byte, issuer string) *JWT {
+	return &JWT{secret: secret, issuer: issuer}
+}
+
+// Verify returns the subject of a valid token
+func (j *JWT) Verify(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", ErrMissingCredentials
+	}
+
+	claims := &jwt.StandardClaims{}
+
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(header, "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidCredentials
+		}
+		return j.secret, nil
+	})
+	if err != nil {
+		return "", ErrInvalidCredentials
+	}
+
+	// exp is only checked when present, so tokens without one would never expire
+	if claims.ExpiresAt == 0 {
+		return "", ErrInvalidCredentials
+	}
+
+	if j.issuer != "" && !claims.VerifyIssuer(j.issuer, true) {
+		return "", ErrInvalidCredentials
+	}
+
+	if claims.Subject == "" {
+		return "", ErrInvalidCredentials
+	}
+
+	return claims.Subject, nil
+}
+
+// Any accepts requests passing any one of the verifiers, checked in order
+type Any []Verifier
+
+// Verify returns the identity from the first verifier that accepts the request
+func (a Any) Verify(r *http.Request) (string, error) {
+	err := ErrMissingCredentials
+
+	for _, verifier := range a {
+		identity, verifyErr := verifier.Verify(r)
+		if verifyErr == nil {
+			return identity, nil
+		}
+
+		// report invalid credentials over missing ones from verifiers the client did not use
+		if verifyErr != ErrMissingCredentials {
+			err = verifyErr
+		}
+	}
+
+	return "", err
+}
+
+// Middleware rejects requests that fail verification with a 401 and adds the identity
+// of the caller to the request context. Preflight requests are let through.
+func Middleware(verifier Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+
+			identity, err := verifier.Verify(r)
+			if err != nil {
+				log.Event(ctx, "request failed authentication", log.WARN, log.Error(err), log.Data{"method": r.Method, "path": r.URL.Path})
+				errs.WriteError(w, errs.ErrUnauthorised)
+				return
+			}
+
+			log.Event(ctx, "request authenticated", log.INFO, log.Data{"identity": identity, "method": r.Method, "path": r.URL.Path})
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, identityKey, identity)))
+		})
+	}
+}
+
+// Identity returns the identity of the authenticated caller held in ctx
+func Identity(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey).(string)
+	return identity
+}
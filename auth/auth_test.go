@@ -0,0 +1,143 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/auth"
+	jwt "github.com/golang-jwt/jwt"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var secret = []byte("a-shared-secret")
+
+func signToken(key interface{}, method jwt.SigningMethod, claims jwt.StandardClaims) string {
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+func TestStaticKeys(t *testing.T) {
+	Convey("Given a static key verifier", t, func() {
+		verifier := auth.NewStaticKeys(map[string]string{"loader": "abc123"})
+		r := httptest.NewRequest("POST", "/search/parent", nil)
+
+		Convey("A known key returns the client name", func() {
+			r.Header.Set(auth.APIKeyHeader, "abc123")
+			identity, err := verifier.Verify(r)
+			So(err, ShouldBeNil)
+			So(identity, ShouldEqual, "loader")
+		})
+
+		Convey("An unknown key is rejected", func() {
+			r.Header.Set(auth.APIKeyHeader, "abc124")
+			_, err := verifier.Verify(r)
+			So(err, ShouldEqual, auth.ErrInvalidCredentials)
+		})
+
+		Convey("A request without a key is rejected", func() {
+			_, err := verifier.Verify(r)
+			So(err, ShouldEqual, auth.ErrMissingCredentials)
+		})
+	})
+}
+
+func TestJWT(t *testing.T) {
+	Convey("Given a jwt verifier", t, func() {
+		verifier := auth.NewJWT(secret, "dp-search")
+		r := httptest.NewRequest("POST", "/search/parent", nil)
+		valid := jwt.StandardClaims{Subject: "admin", Issuer: "dp-search", ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+		Convey("A valid token returns the subject", func() {
+			r.Header.Set("Authorization", "Bearer "+signToken(secret, jwt.SigningMethodHS256, valid))
+			identity, err := verifier.Verify(r)
+			So(err, ShouldBeNil)
+			So(identity, ShouldEqual, "admin")
+		})
+
+		Convey("A token signed with another secret is rejected", func() {
+			r.Header.Set("Authorization", "Bearer "+signToken([]byte("other"), jwt.SigningMethodHS256, valid))
+			_, err := verifier.Verify(r)
+			So(err, ShouldEqual, auth.ErrInvalidCredentials)
+		})
+
+		Convey("An expired token is rejected", func() {
+			expired := valid
+			expired.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+			r.Header.Set("Authorization", "Bearer "+signToken(secret, jwt.SigningMethodHS256, expired))
+			_, err := verifier.Verify(r)
+			So(err, ShouldEqual, auth.ErrInvalidCredentials)
+		})
+
+		Convey("A token without an expiry is rejected", func() {
+			forever := valid
+			forever.ExpiresAt = 0
+			r.Header.Set("Authorization", "Bearer "+signToken(secret, jwt.SigningMethodHS256, forever))
+			_, err := verifier.Verify(r)
+			So(err, ShouldEqual, auth.ErrInvalidCredentials)
+		})
+
+		Convey("A token from another issuer is rejected", func() {
+			other := valid
+			other.Issuer = "someone-else"
+			r.Header.Set("Authorization", "Bearer "+signToken(secret, jwt.SigningMethodHS256, other))
+			_, err := verifier.Verify(r)
+			So(err, ShouldEqual, auth.ErrInvalidCredentials)
+		})
+
+		Convey("An unsigned token is rejected", func() {
+			r.Header.Set("Authorization", "Bearer "+signToken(jwt.UnsafeAllowNoneSignatureType, jwt.SigningMethodNone, valid))
+			_, err := verifier.Verify(r)
+			So(err, ShouldEqual, auth.ErrInvalidCredentials)
+		})
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	Convey("Given the auth middleware accepting api keys or tokens", t, func() {
+		var identity string
+		verifier := auth.Any{auth.NewStaticKeys(map[string]string{"loader": "abc123"}), auth.NewJWT(secret, "")}
+		handler := auth.Middleware(verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity = auth.Identity(r.Context())
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+		Convey("A request with a valid token is passed on with the caller identity", func() {
+			r := httptest.NewRequest("POST", "/search/parent", nil)
+			r.Header.Set("Authorization", "Bearer "+signToken(secret, jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "admin", ExpiresAt: time.Now().Add(time.Hour).Unix()}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(identity, ShouldEqual, "admin")
+		})
+
+		Convey("A request without credentials is rejected with a 401", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("POST", "/search/parent", nil))
+
+			So(w.Code, ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("A preflight request is passed on", func() {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/search/parent", nil))
+
+			So(w.Code, ShouldEqual, http.StatusCreated)
+		})
+	})
+
+	Convey("Given no verifiers are configured, every request is rejected", t, func() {
+		handler := auth.Middleware(auth.Any{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		r := httptest.NewRequest("POST", "/search/parent", nil)
+		r.Header.Set(auth.APIKeyHeader, "abc123")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		So(w.Code, ShouldEqual, http.StatusUnauthorized)
+	})
+}
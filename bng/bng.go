@@ -0,0 +1,246 @@
+// Package bng converts between British National Grid (EPSG:27700) eastings and northings
+// and WGS84 latitude and longitude, and reads Ordnance Survey grid references.
+//
+// The grid is a transverse mercator projection of the OSGB36 datum on the Airy 1830
+// ellipsoid. Moving between OSGB36 and WGS84 uses the 7 parameter Helmert transformation
+// published by Ordnance Survey, which is accurate to within about 5 metres across Great
+// Britain. That is ample for finding the areas around a point, the OSTN15 grid shift that
+// gets to centimetres is not used as it needs a large data file.
+package bng
+
+import (
+	"errors"
+	"math"
+)
+
+// Bounds of the grid in metres
+const (
+	MaxEasting  = 700000
+	MaxNorthing = 1300000
+)
+
+// ErrInvalidCoordinates is returned for an easting or northing that cannot be converted,
+// such as one that is not a finite number
+var ErrInvalidCoordinates = errors.New("easting and northing cannot be converted to a latitude and longitude")
+
+// maxIterations bounds the search for the latitude of a northing, which takes a handful of
+// iterations for any point on or near the grid
+const maxIterations = 100
+
+type ellipsoid struct {
+	a, b float64
+}
+
+var (
+	airy1830 = ellipsoid{a: 6377563.396, b: 6356256.909}
+	wgs84    = ellipsoid{a: 6378137, b: 6356752.314245}
+)
+
+// National grid projection
+const (
+	f0 = 0.9996012717 // scale factor on the central meridian
+	e0 = 400000       // easting of true origin
+	n0 = -100000      // northing of true origin
+)
+
+var (
+	lat0 = toRadians(49)
+	lon0 = toRadians(-2)
+)
+
+// helmert is a transformation between datums: a translation in metres, a scale in parts
+// per million and rotations in seconds of arc
+type helmert struct {
+	tx, ty, tz float64
+	s          float64
+	rx, ry, rz float64
+}
+
+// wgs84ToOSGB36 and its inverse, which negates every parameter
+var (
+	wgs84ToOSGB36 = helmert{tx: -446.448, ty: 125.157, tz: -542.060, s: 20.4894, rx: -0.1502, ry: -0.2470, rz: -0.8421}
+	osgb36ToWGS84 = helmert{tx: 446.448, ty: -125.157, tz: 542.060, s: -20.4894, rx: 0.1502, ry: 0.2470, rz: 0.8421}
+)
+
+// ToWGS84 converts an easting and northing to WGS84 latitude and longitude in degrees
+func ToWGS84(easting, northing float64) (lat, lon float64, err error) {
+	if lat, lon, err = ToOSGB36(easting, northing); err != nil {
+		return 0, 0, err
+	}
+
+	lat, lon = transform(lat, lon, airy1830, wgs84, osgb36ToWGS84)
+
+	return lat, lon, nil
+}
+
+// FromWGS84 converts a WGS84 latitude and longitude in degrees to an easting and northing
+func FromWGS84(lat, lon float64) (easting, northing float64) {
+	lat, lon = transform(lat, lon, wgs84, airy1830, wgs84ToOSGB36)
+	return FromOSGB36(lat, lon)
+}
+
+// ToOSGB36 converts an easting and northing to OSGB36 latitude and longitude in degrees,
+// without changing datum. ErrInvalidCoordinates is returned for values that are not finite
+// or so far from the grid that no latitude is found for the northing.
+func ToOSGB36(easting, northing float64) (lat, lon float64, err error) {
+	if !isFinite(easting) || !isFinite(northing) {
+		return 0, 0, ErrInvalidCoordinates
+	}
+
+	a, b := airy1830.a, airy1830.b
+	e2 := 1 - (b*b)/(a*a)
+
+	phi := lat0
+	m := 0.0
+	for i := 0; ; i++ {
+		if i == maxIterations {
+			return 0, 0, ErrInvalidCoordinates
+		}
+
+		phi += (northing - n0 - m) / (a * f0)
+		m = meridionalArc(phi)
+		if math.Abs(northing-n0-m) < 0.00001 {
+			break
+		}
+	}
+
+	sinPhi, cosPhi, tanPhi := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+	secPhi := 1 / cosPhi
+
+	nu := a * f0 / math.Sqrt(1-e2*sinPhi*sinPhi)
+	rho := a * f0 * (1 - e2) / math.Pow(1-e2*sinPhi*sinPhi, 1.5)
+	eta2 := nu/rho - 1
+
+	tan2 := tanPhi * tanPhi
+	tan4 := tan2 * tan2
+	tan6 := tan4 * tan2
+
+	vii := tanPhi / (2 * rho * nu)
+	viii := tanPhi / (24 * rho * math.Pow(nu, 3)) * (5 + 3*tan2 + eta2 - 9*tan2*eta2)
+	ix := tanPhi / (720 * rho * math.Pow(nu, 5)) * (61 + 90*tan2 + 45*tan4)
+	x := secPhi / nu
+	xi := secPhi / (6 * math.Pow(nu, 3)) * (nu/rho + 2*tan2)
+	xii := secPhi / (120 * math.Pow(nu, 5)) * (5 + 28*tan2 + 24*tan4)
+	xiia := secPhi / (5040 * math.Pow(nu, 7)) * (61 + 662*tan2 + 1320*tan4 + 720*tan6)
+
+	de := easting - e0
+	de2 := de * de
+
+	phi = phi - vii*de2 + viii*de2*de2 - ix*de2*de2*de2
+	lambda := lon0 + x*de - xi*de2*de + xii*de2*de2*de - xiia*de2*de2*de2*de
+
+	return toDegrees(phi), toDegrees(lambda), nil
+}
+
+// FromOSGB36 converts an OSGB36 latitude and longitude in degrees to an easting and
+// northing, without changing datum
+func FromOSGB36(lat, lon float64) (easting, northing float64) {
+	a, b := airy1830.a, airy1830.b
+	e2 := 1 - (b*b)/(a*a)
+
+	phi, lambda := toRadians(lat), toRadians(lon)
+
+	sinPhi, cosPhi, tanPhi := math.Sin(phi), math.Cos(phi), math.Tan(phi)
+	cos3 := cosPhi * cosPhi * cosPhi
+	cos5 := cos3 * cosPhi * cosPhi
+	tan2 := tanPhi * tanPhi
+	tan4 := tan2 * tan2
+
+	nu := a * f0 / math.Sqrt(1-e2*sinPhi*sinPhi)
+	rho := a * f0 * (1 - e2) / math.Pow(1-e2*sinPhi*sinPhi, 1.5)
+	eta2 := nu/rho - 1
+
+	i := meridionalArc(phi) + n0
+	ii := nu / 2 * sinPhi * cosPhi
+	iii := nu / 24 * sinPhi * cos3 * (5 - tan2 + 9*eta2)
+	iiia := nu / 720 * sinPhi * cos5 * (61 - 58*tan2 + tan4)
+	iv := nu * cosPhi
+	v := nu / 6 * cos3 * (nu/rho - tan2)
+	vi := nu / 120 * cos5 * (5 - 18*tan2 + tan4 + 14*eta2 - 58*tan2*eta2)
+
+	dl := lambda - lon0
+	dl2 := dl * dl
+
+	northing = i + ii*dl2 + iii*dl2*dl2 + iiia*dl2*dl2*dl2
+	easting = e0 + iv*dl + v*dl2*dl + vi*dl2*dl2*dl
+
+	return easting, northing
+}
+
+// meridionalArc is the distance in metres along the central meridian of the grid from the
+// latitude of true origin to phi, scaled by f0
+func meridionalArc(phi float64) float64 {
+	a, b := airy1830.a, airy1830.b
+	n := (a - b) / (a + b)
+	n2, n3 := n*n, n*n*n
+
+	dPhi, sPhi := phi-lat0, phi+lat0
+
+	return b * f0 * ((1+n+5.0/4*n2+5.0/4*n3)*dPhi -
+		(3*n+3*n2+21.0/8*n3)*math.Sin(dPhi)*math.Cos(sPhi) +
+		(15.0/8*n2+15.0/8*n3)*math.Sin(2*dPhi)*math.Cos(2*sPhi) -
+		35.0/24*n3*math.Sin(3*dPhi)*math.Cos(3*sPhi))
+}
+
+// transform moves a latitude and longitude from one datum to another, through cartesian
+// coordinates, taking the height above the ellipsoid to be 0
+func transform(lat, lon float64, from, to ellipsoid, h helmert) (float64, float64) {
+	x, y, z := toCartesian(lat, lon, from)
+
+	s := h.s / 1e6
+	rx := toRadians(h.rx / 3600)
+	ry := toRadians(h.ry / 3600)
+	rz := toRadians(h.rz / 3600)
+
+	x2 := h.tx + (1+s)*x - rz*y + ry*z
+	y2 := h.ty + rz*x + (1+s)*y - rx*z
+	z2 := h.tz - ry*x + rx*y + (1+s)*z
+
+	return fromCartesian(x2, y2, z2, to)
+}
+
+func toCartesian(lat, lon float64, e ellipsoid) (x, y, z float64) {
+	phi, lambda := toRadians(lat), toRadians(lon)
+	e2 := 1 - (e.b*e.b)/(e.a*e.a)
+
+	sinPhi := math.Sin(phi)
+	nu := e.a / math.Sqrt(1-e2*sinPhi*sinPhi)
+
+	x = nu * math.Cos(phi) * math.Cos(lambda)
+	y = nu * math.Cos(phi) * math.Sin(lambda)
+	z = (1 - e2) * nu * sinPhi
+
+	return x, y, z
+}
+
+func fromCartesian(x, y, z float64, e ellipsoid) (lat, lon float64) {
+	e2 := 1 - (e.b*e.b)/(e.a*e.a)
+	p := math.Sqrt(x*x + y*y)
+
+	phi := math.Atan2(z, p*(1-e2))
+	for i := 0; i < 10; i++ {
+		sinPhi := math.Sin(phi)
+		nu := e.a / math.Sqrt(1-e2*sinPhi*sinPhi)
+
+		next := math.Atan2(z+e2*nu*sinPhi, p)
+		if math.Abs(next-phi) < 1e-12 {
+			phi = next
+			break
+		}
+		phi = next
+	}
+
+	return toDegrees(phi), toDegrees(math.Atan2(y, x))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func toDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
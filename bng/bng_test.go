@@ -0,0 +1,84 @@
+package bng_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/bng"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func dms(degrees, minutes, seconds float64) float64 {
+	return degrees + minutes/60 + seconds/3600
+}
+
+func TestGrid(t *testing.T) {
+	Convey("Given the worked example from the Ordnance Survey guide to coordinate systems", t, func() {
+		easting, northing := 651409.903, 313177.270
+		lat, lon := dms(52, 39, 27.2531), dms(1, 43, 4.5177)
+
+		Convey("When the easting and northing are converted to OSGB36", func() {
+			gotLat, gotLon, err := bng.ToOSGB36(easting, northing)
+
+			Convey("Then the latitude and longitude match to a ten thousandth of a second", func() {
+				So(err, ShouldBeNil)
+				So(gotLat, ShouldAlmostEqual, lat, 0.0001/3600)
+				So(gotLon, ShouldAlmostEqual, lon, 0.0001/3600)
+			})
+		})
+
+		Convey("When the latitude and longitude are converted to the grid", func() {
+			gotEasting, gotNorthing := bng.FromOSGB36(lat, lon)
+
+			Convey("Then the easting and northing match to the millimetre", func() {
+				So(gotEasting, ShouldAlmostEqual, easting, 0.001)
+				So(gotNorthing, ShouldAlmostEqual, northing, 0.001)
+			})
+		})
+	})
+}
+
+func TestInvalidCoordinates(t *testing.T) {
+	Convey("Given an easting or northing that is not a finite number", t, func() {
+		for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+			_, _, err := bng.ToWGS84(100, value)
+			So(err, ShouldEqual, bng.ErrInvalidCoordinates)
+
+			_, _, err = bng.ToOSGB36(value, 100)
+			So(err, ShouldEqual, bng.ErrInvalidCoordinates)
+		}
+	})
+}
+
+func TestWGS84(t *testing.T) {
+	Convey("Given an easting and northing", t, func() {
+		easting, northing := 651409.903, 313177.270
+
+		Convey("When it is converted to WGS84", func() {
+			lat, lon, err := bng.ToWGS84(easting, northing)
+
+			Convey("Then it is moved onto the WGS84 datum", func() {
+				So(err, ShouldBeNil)
+				So(lat, ShouldAlmostEqual, dms(52, 39, 28.72), 0.01/3600)
+				So(lon, ShouldAlmostEqual, dms(1, 42, 57.79), 0.01/3600)
+			})
+
+			Convey("Then converting it back gives the same easting and northing to the centimetre", func() {
+				gotEasting, gotNorthing := bng.FromWGS84(lat, lon)
+				So(gotEasting, ShouldAlmostEqual, easting, 0.01)
+				So(gotNorthing, ShouldAlmostEqual, northing, 0.01)
+			})
+		})
+	})
+
+	Convey("Given the WGS84 location of Cardiff Castle", t, func() {
+		Convey("When it is converted to the grid", func() {
+			easting, northing := bng.FromWGS84(51.4822, -3.1812)
+
+			Convey("Then it is at its national grid position to the nearest 100 metres", func() {
+				So(easting, ShouldAlmostEqual, 318100, 100)
+				So(northing, ShouldAlmostEqual, 176600, 100)
+			})
+		})
+	})
+}
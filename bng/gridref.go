@@ -0,0 +1,111 @@
+package bng
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// ErrInvalidGridRef is returned for text that is not an Ordnance Survey grid reference
+var ErrInvalidGridRef = errors.New("invalid grid reference, should be two letters followed by an even number of up to 10 digits e.g. ST 18 76 or ST1830076500")
+
+// gridLetters are the letters naming the squares of the grid, I is not used
+const gridLetters = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+
+// GridRef is an Ordnance Survey grid reference such as ST 18 76, which names a square of the
+// grid. Easting and Northing are the south west corner of the square, whose sides are
+// Precision metres long: 100km for letters alone down to 1 metre for ten digits.
+type GridRef struct {
+	Easting   float64
+	Northing  float64
+	Precision float64
+}
+
+// ParseGridRef reads a grid reference of two letters followed by an even number of up to
+// ten digits, half for the easting and half for the northing, which may be separated from
+// the letters and each other by spaces
+func ParseGridRef(ref string) (GridRef, error) {
+	ref = strings.ToUpper(strings.TrimSpace(ref))
+	if len(ref) < 2 {
+		return GridRef{}, ErrInvalidGridRef
+	}
+
+	l1 := strings.IndexByte(gridLetters, ref[0])
+	l2 := strings.IndexByte(gridLetters, ref[1])
+	if l1 < 0 || l2 < 0 {
+		return GridRef{}, ErrInvalidGridRef
+	}
+
+	digits, err := gridDigits(ref[2:])
+	if err != nil {
+		return GridRef{}, err
+	}
+
+	// the first letter names a 500km square and the second a 100km square within it,
+	// each lettered from the north west corner across then down, 5 squares to a side
+	e100km := ((l1-2)%5)*5 + l2%5
+	n100km := (19 - (l1/5)*5) - l2/5
+
+	half := len(digits) / 2
+	precision := math.Pow10(5 - half)
+
+	g := GridRef{
+		Easting:   float64(e100km)*100000 + digitValue(digits[:half])*precision,
+		Northing:  float64(n100km)*100000 + digitValue(digits[half:])*precision,
+		Precision: precision,
+	}
+
+	if g.Easting < 0 || g.Easting >= MaxEasting || g.Northing < 0 || g.Northing >= MaxNorthing {
+		return GridRef{}, ErrInvalidGridRef
+	}
+
+	return g, nil
+}
+
+// gridDigits returns the digits of a grid reference, which may be separated into the
+// easting and northing by a space. There must be as many easting digits as northing.
+func gridDigits(s string) (string, error) {
+	parts := strings.Fields(s)
+	if len(parts) > 2 {
+		return "", ErrInvalidGridRef
+	}
+
+	if len(parts) == 2 && len(parts[0]) != len(parts[1]) {
+		return "", ErrInvalidGridRef
+	}
+
+	digits := strings.Join(parts, "")
+	if len(digits)%2 != 0 || len(digits) > 10 {
+		return "", ErrInvalidGridRef
+	}
+
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return "", ErrInvalidGridRef
+		}
+	}
+
+	return digits, nil
+}
+
+func digitValue(digits string) float64 {
+	value := 0.0
+	for _, c := range digits {
+		value = value*10 + float64(c-'0')
+	}
+
+	return value
+}
+
+// Centre returns the easting and northing of the middle of the grid square
+func (g GridRef) Centre() (easting, northing float64) {
+	return g.Easting + g.Precision/2, g.Northing + g.Precision/2
+}
+
+// Corners returns the easting and northing of the corners of the grid square, anticlockwise
+// from the south west, ending where they start
+func (g GridRef) Corners() [][2]float64 {
+	e, n, p := g.Easting, g.Northing, g.Precision
+
+	return [][2]float64{{e, n}, {e + p, n}, {e + p, n + p}, {e, n + p}, {e, n}}
+}
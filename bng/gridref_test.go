@@ -0,0 +1,50 @@
+package bng_test
+
+import (
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/bng"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseGridRef(t *testing.T) {
+	Convey("Given grid references with different numbers of digits", t, func() {
+		Convey("Then the precision is implied by the number of digits", func() {
+			for ref, want := range map[string]bng.GridRef{
+				"ST":             {Easting: 300000, Northing: 100000, Precision: 100000},
+				"ST 1 7":         {Easting: 310000, Northing: 170000, Precision: 10000},
+				"ST 18 76":       {Easting: 318000, Northing: 176000, Precision: 1000},
+				"st1876":         {Easting: 318000, Northing: 176000, Precision: 1000},
+				"ST1830076500":   {Easting: 318300, Northing: 176500, Precision: 1},
+				"TG 51409 13177": {Easting: 651409, Northing: 313177, Precision: 1},
+				"SV 0 0":         {Easting: 0, Northing: 0, Precision: 10000},
+				"HP 6 1":         {Easting: 460000, Northing: 1210000, Precision: 10000},
+			} {
+				got, err := bng.ParseGridRef(ref)
+				So(err, ShouldBeNil)
+				So(got, ShouldResemble, want)
+			}
+		})
+	})
+
+	Convey("Given text that is not a grid reference", t, func() {
+		for _, ref := range []string{"", "S", "ST 183 76", "ST 1876 5", "ST187", "IT 18 76", "AA 18 76", "ST18x6", "ST 18 76 01", "ST123456789012"} {
+			_, err := bng.ParseGridRef(ref)
+			So(err, ShouldEqual, bng.ErrInvalidGridRef)
+		}
+	})
+
+	Convey("Given a grid reference to the nearest kilometre", t, func() {
+		ref, _ := bng.ParseGridRef("ST 18 76")
+
+		Convey("Then its centre is the middle of the kilometre square", func() {
+			easting, northing := ref.Centre()
+			So(easting, ShouldEqual, 318500)
+			So(northing, ShouldEqual, 176500)
+		})
+
+		Convey("Then its corners go round the square from the south west", func() {
+			So(ref.Corners(), ShouldResemble, [][2]float64{{318000, 176000}, {319000, 176000}, {319000, 177000}, {318000, 177000}, {318000, 176000}})
+		})
+	})
+}
@@ -2,12 +2,10 @@ package main
 
 import (
 	"context"
-	"net/http"
 	"os"
 
 	"github.com/ONSdigital/dp-census-search-prototypes/config"
 	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
-	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
 )
 
@@ -22,20 +20,43 @@ func main() {
 		os.Exit(1)
 	}
 
-	cli := dphttp.NewClient()
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
 	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
 
-	// create elasticsearch index with settings/mapping
-	status, err := esAPI.CreateSearchIndex(ctx, cfg.BoundaryFileIndex, mappingsFile)
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to detect elasticsearch version", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	// boundary files are added through the api rather than loaded, so an existing index or
+	// alias is left alone
+	exists, err := esAPI.IndexExists(ctx, cfg.BoundaryFileIndex)
 	if err != nil {
-		if status != http.StatusBadRequest {
-			log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
-			os.Exit(1)
-		}
+		log.Event(ctx, "failed to check for index", log.ERROR, log.Error(err))
+		os.Exit(1)
+	}
 
+	if exists {
 		log.Event(ctx, "index already exists", log.INFO)
-	} else {
-		log.Event(ctx, "successfully created index", log.INFO)
+		return
 	}
+
+	// create elasticsearch index with settings/mapping behind the configured alias
+	indexName, status, err := esAPI.CreateIndexVersion(ctx, cfg.BoundaryFileIndex, mappingsFile)
+	if err != nil {
+		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
+		os.Exit(1)
+	}
+
+	if err = esAPI.SwapAlias(ctx, cfg.BoundaryFileIndex, indexName); err != nil {
+		log.Event(ctx, "failed to create alias", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	log.Event(ctx, "successfully created index", log.INFO, log.Data{"index": indexName})
 }
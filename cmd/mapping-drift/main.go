@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"sort"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/log.go/log"
+)
+
+func main() {
+	ctx := context.Background()
+
+	apply := flag.Bool("apply", false, "add missing fields and update dynamic settings where this can be done in place")
+	flag.Parse()
+
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to detect elasticsearch version", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	indexMappings := cfg.IndexMappings()
+
+	var indices []string
+	for index := range indexMappings {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	remaining := false
+
+	for _, index := range indices {
+		drift, err := esAPI.MappingDrift(ctx, index, indexMappings[index])
+		if err != nil {
+			log.Event(ctx, "failed to check index for mapping drift", log.ERROR, log.Error(err), log.Data{"index": index})
+			remaining = true
+			continue
+		}
+
+		if !drift.HasDrift() {
+			log.Event(ctx, "index matches its mapping file", log.INFO, log.Data{"index": index, "mappings_file": drift.MappingsFile})
+			continue
+		}
+
+		if drift.RequiresReindex {
+			log.Event(ctx, "mapping drift found, reload the index to apply it", log.WARN, drift.LogData())
+			remaining = true
+			continue
+		}
+
+		if !*apply {
+			log.Event(ctx, "mapping drift found, run with -apply to apply it in place", log.WARN, drift.LogData())
+			remaining = true
+			continue
+		}
+
+		if err = esAPI.ApplyMappingDrift(ctx, drift); err != nil {
+			log.Event(ctx, "failed to apply mapping drift", log.ERROR, log.Error(err), drift.LogData())
+			remaining = true
+			continue
+		}
+
+		log.Event(ctx, "applied mapping drift in place", log.INFO, drift.LogData())
+	}
+
+	if remaining {
+		os.Exit(1)
+	}
+}
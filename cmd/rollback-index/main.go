@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/log.go/log"
+)
+
+func main() {
+	ctx := context.Background()
+
+	alias := flag.String("alias", "", "alias to point back at the previous version of its index, e.g. test_postcode")
+	flag.Parse()
+
+	if *alias == "" {
+		log.Event(ctx, "missing alias, set with -alias", log.FATAL)
+		os.Exit(1)
+	}
+
+	logData := log.Data{"alias": *alias}
+
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+
+	previous, err := esAPI.RollbackIndex(ctx, *alias)
+	if err != nil {
+		logData["index"] = previous
+		if previous != "" {
+			log.Event(ctx, "rolled back alias but failed to delete the index version rolled back from, delete it before loading again", log.FATAL, log.Error(err), logData)
+		} else {
+			log.Event(ctx, "failed to roll back index", log.FATAL, log.Error(err), logData)
+		}
+		os.Exit(1)
+	}
+
+	logData["index"] = previous
+	log.Event(ctx, "successfully rolled back alias to previous index", log.INFO, logData)
+}
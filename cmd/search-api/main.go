@@ -2,17 +2,32 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/ONSdigital/dp-census-search-prototypes/api"
+	"github.com/ONSdigital/dp-census-search-prototypes/auth"
 	"github.com/ONSdigital/dp-census-search-prototypes/config"
+	"github.com/ONSdigital/dp-census-search-prototypes/cors"
 	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
-	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/ONSdigital/dp-census-search-prototypes/memory"
+	"github.com/ONSdigital/dp-census-search-prototypes/ratelimit"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
+	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	"github.com/ONSdigital/log.go/log"
 )
 
+var (
+	// BuildTime represents the time in which the service was built
+	BuildTime string
+	// GitCommit represents the commit (SHA-1) hash of the service that is running
+	GitCommit string
+	// Version represents the version of the service that is running
+	Version string
+)
+
 func main() {
 	log.Namespace = "dp-search-api"
 	ctx := context.Background()
@@ -37,18 +52,58 @@ func run(ctx context.Context) error {
 
 	log.Event(ctx, "config on startup", log.INFO, log.Data{"config": cfg})
 
-	cli := dphttp.NewClient()
-	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+	shutdownTracing, err := tracing.Init(ctx, cfg.TracingExporter, cfg.OTLPEndpoint, log.Namespace)
+	if err != nil {
+		log.Event(ctx, "failed to initialise tracing", log.ERROR, log.Error(err), log.Data{"exporter": cfg.TracingExporter})
+		return err
+	}
+
+	defer func() {
+		if err := shutdownTracing(ctx); err != nil {
+			log.Event(ctx, "failed to flush spans on shutdown", log.ERROR, log.Error(err))
+		}
+	}()
+
+	versionInfo, err := healthcheck.NewVersionInfo(BuildTime, GitCommit, Version)
+	if err != nil {
+		log.Event(ctx, "failed to create service version information", log.ERROR, log.Error(err))
+		return err
+	}
+
+	hc := healthcheck.New(versionInfo, cfg.HealthCheckCriticalTimeout, cfg.HealthCheckInterval)
+
+	backend, err := newBackend(ctx, &hc, cfg)
+	if err != nil {
+		return err
+	}
+
+	hc.Start(ctx)
+	defer hc.Stop()
+
+	corsPolicy, err := cors.New(cors.Options{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	})
+	if err != nil {
+		log.Event(ctx, "failed to create cors policy", log.ERROR, log.Error(err))
+		return err
+	}
+
+	verifier := newVerifier(ctx, cfg)
 
-	_, status, err := esAPI.CallElastic(ctx, cfg.ElasticSearchAPIURL, "GET", nil)
+	limiter, err := newRateLimiter(cfg, verifier)
 	if err != nil {
-		log.Event(ctx, "failed to start up, unable to connect to elastic search instance", log.ERROR, log.Error(err), log.Data{"http_status": status})
+		log.Event(ctx, "failed to create rate limiter", log.ERROR, log.Error(err))
 		return err
 	}
 
 	apiErrors := make(chan error, 1)
 
-	api.CreateAndInitialiseSearchAPI(ctx, cfg.BindAddr, esAPI, cfg.MaxSearchResultsOffset, cfg.DatasetIndex, cfg.PostcodeIndex, cfg.BoundaryFileIndex, apiErrors)
+	api.CreateAndInitialiseSearchAPI(ctx, cfg.BindAddr, backend, cfg.MaxSearchResultsOffset, cfg.DatasetIndex, cfg.PostcodeIndex, cfg.BoundaryFileIndex, &hc, corsPolicy, limiter, verifier, apiErrors)
 
 	// block until a fatal error occurs
 	select {
@@ -61,3 +116,149 @@ func run(ctx context.Context) error {
 
 	return nil
 }
+
+// newBackend creates the search backend named in the config and registers its health checks
+func newBackend(ctx context.Context, hc *healthcheck.HealthCheck, cfg *config.Config) (api.Elasticsearcher, error) {
+	switch cfg.Backend {
+	case "elasticsearch":
+		return newElasticsearchBackend(ctx, hc, cfg)
+	case "memory":
+		return newMemoryBackend(ctx, hc, cfg)
+	}
+
+	err := fmt.Errorf("unknown backend %q, expected elasticsearch or memory", cfg.Backend)
+	log.Event(ctx, "failed to create search backend", log.ERROR, log.Error(err))
+
+	return nil, err
+}
+
+func newElasticsearchBackend(ctx context.Context, hc *healthcheck.HealthCheck, cfg *config.Config) (*es.API, error) {
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch client", log.ERROR, log.Error(err))
+		return nil, err
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+	esAPI.SetRetryPolicy(es.RetryPolicy{
+		MaxRetries:     cfg.ElasticsearchMaxRetries,
+		InitialBackoff: cfg.ElasticsearchRetryBackoff,
+		MaxBackoff:     cfg.ElasticsearchRetryMaxBackoff,
+	})
+	esAPI.SetCircuitBreaker(es.NewCircuitBreaker(cfg.ElasticsearchBreakerFailures, cfg.ElasticsearchBreakerTimeout))
+
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to start up, unable to connect to elastic search instance", log.ERROR, log.Error(err))
+		return nil, err
+	}
+
+	checkMappingDrift(ctx, esAPI, cfg)
+
+	if err = registerCheckers(ctx, hc, esAPI, cfg); err != nil {
+		return nil, err
+	}
+
+	return esAPI, nil
+}
+
+// newMemoryBackend loads the configured snapshots into an in-memory store. Indices without
+// a snapshot start empty, so boundary files can still be added to them.
+func newMemoryBackend(ctx context.Context, hc *healthcheck.HealthCheck, cfg *config.Config) (*memory.Store, error) {
+	store := memory.New()
+
+	for index, path := range cfg.MemorySnapshots {
+		logData := log.Data{"index": index, "path": path}
+
+		count, err := store.LoadFile(index, path)
+		if err != nil {
+			log.Event(ctx, "failed to load snapshot into memory", log.ERROR, log.Error(err), logData)
+			return nil, err
+		}
+
+		logData["documents"] = count
+		log.Event(ctx, "loaded snapshot into memory", log.INFO, logData)
+	}
+
+	for _, index := range []string{cfg.DatasetIndex, cfg.PostcodeIndex, cfg.BoundaryFileIndex} {
+		if _, ok := cfg.MemorySnapshots[index]; !ok {
+			log.Event(ctx, "no snapshot configured for index, it will start empty", log.WARN, log.Data{"index": index})
+			store.Create(index)
+		}
+
+		if err := hc.AddCheck("Memory index "+index, store.IndexChecker(index)); err != nil {
+			log.Event(ctx, "error adding check for memory index", log.ERROR, log.Error(err), log.Data{"index": index})
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// registerCheckers adds the elasticsearch cluster and index checkers to the health check
+func registerCheckers(ctx context.Context, hc *healthcheck.HealthCheck, esAPI *es.API, cfg *config.Config) error {
+	if err := hc.AddCheck("Elasticsearch", esAPI.Checker); err != nil {
+		log.Event(ctx, "error adding check for elasticsearch", log.ERROR, log.Error(err))
+		return err
+	}
+
+	for _, index := range []string{cfg.DatasetIndex, cfg.PostcodeIndex, cfg.BoundaryFileIndex} {
+		if err := hc.AddCheck("Elasticsearch index "+index, esAPI.IndexChecker(index)); err != nil {
+			log.Event(ctx, "error adding check for elasticsearch index", log.ERROR, log.Error(err), log.Data{"index": index})
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkMappingDrift warns about any configured index that no longer matches its mapping
+// file, without stopping the api from starting
+func checkMappingDrift(ctx context.Context, esAPI *es.API, cfg *config.Config) {
+	for index, mappingsFile := range cfg.IndexMappings() {
+		drift, err := esAPI.MappingDrift(ctx, index, mappingsFile)
+		if err != nil {
+			log.Event(ctx, "failed to check index for mapping drift", log.WARN, log.Error(err), log.Data{"index": index})
+			continue
+		}
+
+		if drift.HasDrift() {
+			log.Event(ctx, "index does not match its mapping file, run the mapping-drift command for details", log.WARN, drift.LogData())
+		}
+	}
+}
+
+// newRateLimiter creates the rate limiter for the search routes from the default and per route
+// limits, giving clients that pass the verifier a bucket of their own
+func newRateLimiter(cfg *config.Config, verifier auth.Verifier) (*ratelimit.Limiter, error) {
+	defaultLimit := ratelimit.Limit{RequestsPerSecond: cfg.RateLimitRequestsPerSecond, Burst: cfg.RateLimitBurst}
+
+	routes := make(map[string]ratelimit.Limit)
+	for route, value := range cfg.RateLimitRoutes {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", route, err)
+		}
+		routes[route] = limit
+	}
+
+	return ratelimit.New(defaultLimit, routes, cfg.RateLimitTrustProxy, verifier)
+}
+
+// newVerifier accepts any of the configured API keys or signed tokens on the write routes
+func newVerifier(ctx context.Context, cfg *config.Config) auth.Verifier {
+	var verifiers auth.Any
+
+	if len(cfg.APIKeys) > 0 {
+		verifiers = append(verifiers, auth.NewStaticKeys(cfg.APIKeys))
+	}
+
+	if cfg.JWTSecret != "" {
+		verifiers = append(verifiers, auth.NewJWT([]byte(cfg.JWTSecret), cfg.JWTIssuer))
+	}
+
+	if len(verifiers) == 0 {
+		log.Event(ctx, "no api keys or jwt secret configured, all requests to write endpoints will be rejected", log.WARN)
+	}
+
+	return verifiers
+}
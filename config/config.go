@@ -1,18 +1,52 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 // Config is the filing resource handler config
 type Config struct {
-	BindAddr                  string `envconfig:"BIND_ADDR"                  json:"-"`
-	BoundaryFileIndex         string `envconfig:"BOUNDARY_FILE_INDEX"`
-	DatasetIndex              string `envconfig:"DATASET_INDEX"`
-	ElasticSearchAPIURL       string `envconfig:"ELASTIC_SEARCH_URL"         json:"-"`
-	MaxSearchResultsOffset    int    `envconfig:"MAX_SEARCH_RESULTS_OFFSET"`
-	PostcodeIndex             string `envconfig:"POSTCODE_INDEX"`
-	SignElasticsearchRequests bool   `envconfig:"SIGN_ELASTICSEARCH_REQUESTS"`
+	APIKeys                      map[string]string `envconfig:"API_KEYS"                        json:"-"`
+	AWSRegion                    string            `envconfig:"AWS_REGION"`
+	Backend                      string            `envconfig:"BACKEND"`
+	BindAddr                     string            `envconfig:"BIND_ADDR"                       json:"-"`
+	BoundaryFileIndex            string            `envconfig:"BOUNDARY_FILE_INDEX"`
+	BulkDeadLetterFile           string            `envconfig:"BULK_DEAD_LETTER_FILE"`
+	BulkFlushBytes               int               `envconfig:"BULK_FLUSH_BYTES"`
+	BulkFlushDocuments           int               `envconfig:"BULK_FLUSH_DOCUMENTS"`
+	BulkFlushInterval            time.Duration     `envconfig:"BULK_FLUSH_INTERVAL"`
+	BulkWorkers                  int               `envconfig:"BULK_WORKERS"`
+	CORSAllowCredentials         bool              `envconfig:"CORS_ALLOW_CREDENTIALS"`
+	CORSAllowedHeaders           []string          `envconfig:"CORS_ALLOWED_HEADERS"`
+	CORSAllowedMethods           []string          `envconfig:"CORS_ALLOWED_METHODS"`
+	CORSAllowedOrigins           []string          `envconfig:"CORS_ALLOWED_ORIGINS"`
+	CORSExposedHeaders           []string          `envconfig:"CORS_EXPOSED_HEADERS"`
+	CORSMaxAge                   time.Duration     `envconfig:"CORS_MAX_AGE"`
+	DatasetIndex                 string            `envconfig:"DATASET_INDEX"`
+	DatasetMappingsFile          string            `envconfig:"DATASET_MAPPINGS_FILE"`
+	ElasticSearchAPIURL          string            `envconfig:"ELASTIC_SEARCH_URL"              json:"-"`
+	ElasticsearchBreakerFailures int               `envconfig:"ELASTICSEARCH_BREAKER_FAILURES"`
+	ElasticsearchBreakerTimeout  time.Duration     `envconfig:"ELASTICSEARCH_BREAKER_TIMEOUT"`
+	ElasticsearchMaxRetries      int               `envconfig:"ELASTICSEARCH_MAX_RETRIES"`
+	ElasticsearchRetryBackoff    time.Duration     `envconfig:"ELASTICSEARCH_RETRY_BACKOFF"`
+	ElasticsearchRetryMaxBackoff time.Duration     `envconfig:"ELASTICSEARCH_RETRY_MAX_BACKOFF"`
+	HealthCheckCriticalTimeout   time.Duration     `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
+	HealthCheckInterval          time.Duration     `envconfig:"HEALTHCHECK_INTERVAL"`
+	IndexVersionsToKeep          int               `envconfig:"INDEX_VERSIONS_TO_KEEP"`
+	JWTIssuer                    string            `envconfig:"JWT_ISSUER"`
+	JWTSecret                    string            `envconfig:"JWT_SECRET"                      json:"-"`
+	MaxSearchResultsOffset       int               `envconfig:"MAX_SEARCH_RESULTS_OFFSET"`
+	MemorySnapshots              map[string]string `envconfig:"MEMORY_SNAPSHOTS"`
+	OTLPEndpoint                 string            `envconfig:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	PostcodeIndex                string            `envconfig:"POSTCODE_INDEX"`
+	RateLimitBurst               int               `envconfig:"RATE_LIMIT_BURST"`
+	RateLimitRequestsPerSecond   float64           `envconfig:"RATE_LIMIT_REQUESTS_PER_SECOND"`
+	RateLimitRoutes              map[string]string `envconfig:"RATE_LIMIT_ROUTES"`
+	RateLimitTrustProxy          bool              `envconfig:"RATE_LIMIT_TRUST_PROXY"`
+	SignElasticsearchRequests    bool              `envconfig:"SIGN_ELASTICSEARCH_REQUESTS"`
+	TracingExporter              string            `envconfig:"TRACING_EXPORTER"`
 }
 
 var cfg *Config
@@ -24,14 +58,58 @@ func Get() (*Config, error) {
 	}
 
 	cfg = &Config{
-		BindAddr:                  ":10000",
-		BoundaryFileIndex:         "test_boundary_files",
-		DatasetIndex:              "test_parent",
-		ElasticSearchAPIURL:       "http://localhost:9200",
-		MaxSearchResultsOffset:    1000,
-		PostcodeIndex:             "test_postcode",
+		AWSRegion:                    "eu-west-1",
+		Backend:                      "elasticsearch",
+		BindAddr:                     ":10000",
+		BoundaryFileIndex:            "test_boundary_files",
+		BulkDeadLetterFile:           "",
+		BulkFlushBytes:               5 * 1024 * 1024,
+		BulkFlushDocuments:           500,
+		BulkFlushInterval:            30 * time.Second,
+		BulkWorkers:                  4,
+		CORSAllowCredentials:         false,
+		CORSAllowedHeaders:           []string{"Accept", "Authorization", "Content-Type", "X-Api-Key", "X-Request-Id", "traceparent"},
+		CORSAllowedMethods:           []string{"GET", "POST", "OPTIONS"},
+		CORSAllowedOrigins:           []string{"*"},
+		CORSExposedHeaders:           []string{"Retry-After", "X-Request-Id"},
+		CORSMaxAge:                   24 * time.Hour,
+		DatasetIndex:                 "test_parent",
+		DatasetMappingsFile:          "parent-mappings.json",
+		ElasticSearchAPIURL:          "http://localhost:9200",
+		ElasticsearchBreakerFailures: 5,
+		ElasticsearchBreakerTimeout:  30 * time.Second,
+		ElasticsearchMaxRetries:      3,
+		ElasticsearchRetryBackoff:    100 * time.Millisecond,
+		ElasticsearchRetryMaxBackoff: 5 * time.Second,
+		HealthCheckCriticalTimeout:   90 * time.Second,
+		HealthCheckInterval:          30 * time.Second,
+		IndexVersionsToKeep:          2,
+		MaxSearchResultsOffset:       1000,
+		MemorySnapshots:              map[string]string{},
+		OTLPEndpoint:                 "localhost:55680",
+		PostcodeIndex:                "test_postcode",
+		RateLimitBurst:               20,
+		RateLimitRequestsPerSecond:   10,
+		RateLimitRoutes: map[string]string{
+			"/search":                      "1/5",
+			"/search/parent":               "0.5/2",
+			"/search/parent/{id}":          "1/5",
+			"/search/postcodes/{postcode}": "1/5",
+			"/search/radius":               "1/5",
+		},
+		RateLimitTrustProxy:       false,
 		SignElasticsearchRequests: false,
+		TracingExporter:           "none",
 	}
 
 	return cfg, envconfig.Process("", cfg)
 }
+
+// IndexMappings returns the embedded mapping file each configured index is created from
+func (cfg *Config) IndexMappings() map[string]string {
+	return map[string]string{
+		cfg.DatasetIndex:      cfg.DatasetMappingsFile,
+		cfg.PostcodeIndex:     "postcode-mappings.json",
+		cfg.BoundaryFileIndex: "boundary-file-mappings.json",
+	}
+}
@@ -0,0 +1,113 @@
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrWildcardWithCredentials is returned when credentials are allowed from any origin,
+// which browsers refuse and would otherwise require reflecting every origin back
+var ErrWildcardWithCredentials = errors.New("cors: credentials cannot be allowed when any origin is allowed")
+
+// Options holds the cross origin resource sharing policy of the API
+type Options struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// Policy applies the CORS options to requests
+type Policy struct {
+	anyOrigin        bool
+	origins          map[string]bool
+	methods          map[string]bool
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// New creates a Policy from the options, an origin of "*" allows any origin
+func New(options Options) (*Policy, error) {
+	p := &Policy{
+		origins:          make(map[string]bool),
+		methods:          make(map[string]bool),
+		allowMethods:     strings.Join(options.AllowedMethods, ","),
+		allowHeaders:     strings.Join(options.AllowedHeaders, ","),
+		exposeHeaders:    strings.Join(options.ExposedHeaders, ","),
+		allowCredentials: options.AllowCredentials,
+		maxAge:           strconv.Itoa(int(options.MaxAge.Seconds())),
+	}
+
+	for _, origin := range options.AllowedOrigins {
+		if origin == "*" {
+			p.anyOrigin = true
+		}
+		p.origins[strings.ToLower(origin)] = true
+	}
+
+	if p.anyOrigin && p.allowCredentials {
+		return nil, ErrWildcardWithCredentials
+	}
+
+	for _, method := range options.AllowedMethods {
+		p.methods[strings.ToUpper(method)] = true
+	}
+
+	return p, nil
+}
+
+// Middleware answers every OPTIONS request and adds CORS headers to responses for
+// allowed origins, including error responses written by later handlers
+func (p *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && (p.anyOrigin || p.origins[strings.ToLower(origin)])
+
+		if !p.anyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if allowed {
+			if p.anyOrigin {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if p.allowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method != http.MethodOptions {
+			if allowed && p.exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		if origin != "" && requestMethod != "" && (!allowed || !p.methods[strings.ToUpper(requestMethod)]) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Methods", p.allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", p.allowHeaders)
+			w.Header().Set("Access-Control-Max-Age", p.maxAge)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
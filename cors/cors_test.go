@@ -0,0 +1,102 @@
+package cors_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/cors"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var errorHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "postcode not found", http.StatusNotFound)
+})
+
+func doRequest(policy *cors.Policy, method, origin, requestMethod string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/search/postcodes/cf244ny", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	if requestMethod != "" {
+		r.Header.Set("Access-Control-Request-Method", requestMethod)
+	}
+
+	w := httptest.NewRecorder()
+	policy.Middleware(errorHandler).ServeHTTP(w, r)
+
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	Convey("Given a policy allowing a single origin with credentials", t, func() {
+		policy, err := cors.New(cors.Options{
+			AllowedOrigins:   []string{"https://www.ons.gov.uk"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowedHeaders:   []string{"Content-Type", "X-Api-Key"},
+			ExposedHeaders:   []string{"X-Request-Id"},
+			AllowCredentials: true,
+			MaxAge:           time.Hour,
+		})
+		So(err, ShouldBeNil)
+
+		Convey("A preflight request from the origin is answered without reaching the handler", func() {
+			w := doRequest(policy, "OPTIONS", "https://www.ons.gov.uk", "POST")
+
+			So(w.Code, ShouldEqual, http.StatusNoContent)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "https://www.ons.gov.uk")
+			So(w.Header().Get("Access-Control-Allow-Credentials"), ShouldEqual, "true")
+			So(w.Header().Get("Access-Control-Allow-Methods"), ShouldEqual, "GET,POST")
+			So(w.Header().Get("Access-Control-Allow-Headers"), ShouldEqual, "Content-Type,X-Api-Key")
+			So(w.Header().Get("Access-Control-Max-Age"), ShouldEqual, "3600")
+			So(w.Header().Get("Vary"), ShouldEqual, "Origin")
+		})
+
+		Convey("A preflight request for a method that is not allowed is forbidden", func() {
+			So(doRequest(policy, "OPTIONS", "https://www.ons.gov.uk", "DELETE").Code, ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("A preflight request from another origin is forbidden", func() {
+			w := doRequest(policy, "OPTIONS", "https://example.com", "GET")
+
+			So(w.Code, ShouldEqual, http.StatusForbidden)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldBeEmpty)
+		})
+
+		Convey("An error response to the origin carries the cors headers", func() {
+			w := doRequest(policy, "GET", "https://www.ons.gov.uk", "")
+
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "https://www.ons.gov.uk")
+			So(w.Header().Get("Access-Control-Expose-Headers"), ShouldEqual, "X-Request-Id")
+		})
+
+		Convey("A response to another origin has no cors headers", func() {
+			w := doRequest(policy, "GET", "https://example.com", "")
+
+			So(w.Code, ShouldEqual, http.StatusNotFound)
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldBeEmpty)
+		})
+	})
+
+	Convey("Given a policy allowing any origin", t, func() {
+		policy, err := cors.New(cors.Options{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}})
+		So(err, ShouldBeNil)
+
+		Convey("A response to any origin allows all origins", func() {
+			w := doRequest(policy, "GET", "https://example.com", "")
+			So(w.Header().Get("Access-Control-Allow-Origin"), ShouldEqual, "*")
+			So(w.Header().Get("Vary"), ShouldBeEmpty)
+		})
+
+		Convey("An options request without an origin is answered with no content", func() {
+			So(doRequest(policy, "OPTIONS", "", "").Code, ShouldEqual, http.StatusNoContent)
+		})
+	})
+
+	Convey("Given credentials are allowed from any origin, New returns an error", t, func() {
+		_, err := cors.New(cors.Options{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+		So(err, ShouldEqual, cors.ErrWildcardWithCredentials)
+	})
+}
@@ -0,0 +1,318 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+// A list of errors returned when managing the versions of an index behind an alias
+var (
+	ErrNoBuildIndex        = errors.New("no index version newer than the one behind the alias, create one first")
+	ErrNoPreviousIndex     = errors.New("no index version older than the one behind the alias to roll back to")
+	ErrTooFewDocuments     = errors.New("index version contains fewer documents than expected")
+	ErrUnknownIndexInAlias = errors.New("alias points at an index that is not one of its versions")
+)
+
+// DefaultIndexVersionsToKeep is the number of versions kept for rollback behind the one in use
+const DefaultIndexVersionsToKeep = 2
+
+const indexVersionLayout = "20060102150405"
+
+type catIndex struct {
+	Index string `json:"index"`
+}
+
+type aliasAction map[string]map[string]string
+
+type aliasActions struct {
+	Actions []aliasAction `json:"actions"`
+}
+
+// IndexVersionName returns the name of the version of the index behind alias created at t
+func IndexVersionName(alias string, t time.Time) string {
+	return alias + "_" + t.UTC().Format(indexVersionLayout)
+}
+
+// CreateIndexVersion creates a new, empty version of the index behind alias. Clients
+// searching the alias are unaffected until the version is published.
+func (api *API) CreateIndexVersion(ctx context.Context, alias, mappingsFile string) (string, int, error) {
+	indexName := IndexVersionName(alias, time.Now())
+
+	status, err := api.CreateSearchIndex(ctx, indexName, mappingsFile)
+	if err != nil {
+		return "", status, err
+	}
+
+	log.Event(ctx, "created index version", log.INFO, log.Data{"alias": alias, "index": indexName})
+
+	return indexName, status, nil
+}
+
+// IndexVersions returns the versions of the index behind alias, oldest first
+func (api *API) IndexVersions(ctx context.Context, alias string) ([]string, error) {
+	body, _, err := api.CallElastic(ctx, api.url+"/_cat/indices/"+alias+"_*?format=json&h=index", "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var indices []catIndex
+	if err = json.Unmarshal(body, &indices); err != nil {
+		return nil, err
+	}
+
+	version := regexp.MustCompile("^" + regexp.QuoteMeta(alias) + `_\d{14}$`)
+
+	var versions []string
+	for _, index := range indices {
+		if version.MatchString(index.Index) {
+			versions = append(versions, index.Index)
+		}
+	}
+
+	sort.Strings(versions)
+
+	return versions, nil
+}
+
+// AliasedIndices returns the indices alias points at, none if the alias does not exist
+func (api *API) AliasedIndices(ctx context.Context, alias string) ([]string, error) {
+	body, status, err := api.CallElastic(ctx, api.url+"/_alias/"+alias, "GET", nil)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var aliases map[string]json.RawMessage
+	if err = json.Unmarshal(body, &aliases); err != nil {
+		return nil, err
+	}
+
+	var indices []string
+	for index := range aliases {
+		indices = append(indices, index)
+	}
+
+	sort.Strings(indices)
+
+	return indices, nil
+}
+
+// IndexExists reports whether an index or alias with the given name exists
+func (api *API) IndexExists(ctx context.Context, indexName string) (bool, error) {
+	_, status, err := api.CallElastic(ctx, api.url+"/"+indexName, "HEAD", nil)
+	if err != nil {
+		if status == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// BuildIndex returns the newest version of the index behind alias when it has not been
+// published yet, so that several loaders can fill the same version before it is
+func (api *API) BuildIndex(ctx context.Context, alias string) (string, error) {
+	versions, err := api.IndexVersions(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+
+	current, err := api.AliasedIndices(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+
+	if len(versions) == 0 {
+		return "", ErrNoBuildIndex
+	}
+
+	newest := versions[len(versions)-1]
+	for _, index := range current {
+		if index >= newest {
+			return "", ErrNoBuildIndex
+		}
+	}
+
+	return newest, nil
+}
+
+// CountDocuments refreshes an index so every document loaded is visible and counts them
+func (api *API) CountDocuments(ctx context.Context, indexName string) (int, error) {
+	if _, _, err := api.CallElastic(ctx, api.url+"/"+indexName+"/_refresh", "POST", nil); err != nil {
+		return 0, err
+	}
+
+	body, _, err := api.CallElastic(ctx, api.url+"/"+indexName+"/_count", "GET", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	indexCount := &IndexCount{}
+	if err = json.Unmarshal(body, indexCount); err != nil {
+		return 0, err
+	}
+
+	return indexCount.Count, nil
+}
+
+// SwapAlias atomically points alias at indexName alone. An index created before
+// aliases were used and named after the alias is deleted in the same step.
+func (api *API) SwapAlias(ctx context.Context, alias, indexName string) error {
+	current, err := api.AliasedIndices(ctx, alias)
+	if err != nil {
+		return err
+	}
+
+	var actions []aliasAction
+	for _, index := range current {
+		if index != indexName {
+			actions = append(actions, aliasAction{"remove": {"index": index, "alias": alias}})
+		}
+	}
+
+	if len(current) == 0 {
+		exists, err := api.IndexExists(ctx, alias)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			actions = append(actions, aliasAction{"remove_index": {"index": alias}})
+		}
+	}
+
+	actions = append(actions, aliasAction{"add": {"index": indexName, "alias": alias}})
+
+	body, err := json.Marshal(aliasActions{Actions: actions})
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = api.CallElastic(ctx, api.url+"/_aliases", "POST", body); err != nil {
+		return err
+	}
+
+	log.Event(ctx, "swapped alias", log.INFO, log.Data{"alias": alias, "index": indexName, "previous": current})
+
+	return nil
+}
+
+// PublishIndex checks a version of the index behind alias holds at least minDocuments,
+// points the alias at it and deletes all but keep of the versions it replaces. A version
+// that fails the check is left in place for inspection.
+func (api *API) PublishIndex(ctx context.Context, alias, indexName string, minDocuments, keep int) error {
+	logData := log.Data{"alias": alias, "index": indexName, "min_documents": minDocuments}
+
+	count, err := api.CountDocuments(ctx, indexName)
+	if err != nil {
+		log.Event(ctx, "failed to count documents in index version", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	logData["documents"] = count
+
+	if count < minDocuments || count == 0 {
+		log.Event(ctx, "index version failed validation, alias unchanged", log.ERROR, log.Error(ErrTooFewDocuments), logData)
+		return ErrTooFewDocuments
+	}
+
+	if err = api.SwapAlias(ctx, alias, indexName); err != nil {
+		log.Event(ctx, "failed to swap alias", log.ERROR, log.Error(err), logData)
+		return err
+	}
+
+	if _, err = api.PruneIndexVersions(ctx, alias, keep); err != nil {
+		log.Event(ctx, "failed to delete old index versions", log.WARN, log.Error(err), logData)
+	}
+
+	log.Event(ctx, "published index version", log.INFO, logData)
+
+	return nil
+}
+
+// PruneIndexVersions deletes the versions older than the one behind alias, keeping the
+// newest keep of them for rollback. Versions newer than the alias are left alone.
+func (api *API) PruneIndexVersions(ctx context.Context, alias string, keep int) ([]string, error) {
+	versions, current, err := api.currentIndexVersion(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for i := 0; i < current-keep; i++ {
+		if _, err = api.DeleteSearchIndex(ctx, versions[i]); err != nil {
+			return deleted, err
+		}
+
+		deleted = append(deleted, versions[i])
+	}
+
+	if len(deleted) > 0 {
+		log.Event(ctx, "deleted old index versions", log.INFO, log.Data{"alias": alias, "deleted": deleted})
+	}
+
+	return deleted, nil
+}
+
+// RollbackIndex points alias back at the version published before the current one and
+// deletes the version rolled back from, so it cannot be loaded into or published again
+func (api *API) RollbackIndex(ctx context.Context, alias string) (string, error) {
+	versions, current, err := api.currentIndexVersion(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+
+	if current < 1 {
+		return "", ErrNoPreviousIndex
+	}
+
+	previous := versions[current-1]
+
+	if err = api.SwapAlias(ctx, alias, previous); err != nil {
+		return "", err
+	}
+
+	if _, err = api.DeleteSearchIndex(ctx, versions[current]); err != nil {
+		return previous, err
+	}
+
+	log.Event(ctx, "deleted index version rolled back from", log.INFO, log.Data{"alias": alias, "index": versions[current]})
+
+	return previous, nil
+}
+
+// currentIndexVersion returns the versions of the index behind alias and the position of
+// the one the alias points at
+func (api *API) currentIndexVersion(ctx context.Context, alias string) ([]string, int, error) {
+	versions, err := api.IndexVersions(ctx, alias)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	current, err := api.AliasedIndices(ctx, alias)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(current) != 1 {
+		return nil, 0, ErrUnknownIndexInAlias
+	}
+
+	for i, version := range versions {
+		if version == current[0] {
+			return versions, i, nil
+		}
+	}
+
+	return nil, 0, ErrUnknownIndexInAlias
+}
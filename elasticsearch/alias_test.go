@@ -0,0 +1,184 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeCluster holds just enough state to stand in for the index and alias apis
+type fakeCluster struct {
+	mu      sync.Mutex
+	indices map[string]int
+	aliases map[string]string
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.Method == "GET" && parts[0] == "_cat":
+		prefix := strings.TrimSuffix(parts[2], "*")
+		var indices []map[string]string
+		for index := range c.indices {
+			if strings.HasPrefix(index, prefix) {
+				indices = append(indices, map[string]string{"index": index})
+			}
+		}
+		json.NewEncoder(w).Encode(indices)
+	case r.Method == "GET" && parts[0] == "_alias":
+		index, ok := c.aliases[parts[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"` + index + `":{"aliases":{"` + parts[1] + `":{}}}}`))
+	case r.Method == "POST" && parts[0] == "_aliases":
+		var body struct {
+			Actions []map[string]map[string]string `json:"actions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, action := range body.Actions {
+			if index, ok := action["remove_index"]; ok {
+				delete(c.indices, index["index"])
+			}
+			if add, ok := action["add"]; ok {
+				c.aliases[add["alias"]] = add["index"]
+			}
+		}
+	case r.Method == "HEAD":
+		_, isIndex := c.indices[parts[0]]
+		_, isAlias := c.aliases[parts[0]]
+		if !isIndex && !isAlias {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == "PUT":
+		c.indices[parts[0]] = 0
+	case r.Method == "DELETE":
+		delete(c.indices, parts[0])
+	case len(parts) == 2 && parts[1] == "_refresh":
+	case len(parts) == 2 && parts[1] == "_count":
+		w.Write([]byte(`{"count":` + strconv.Itoa(c.indices[parts[0]]) + `}`))
+	}
+}
+
+func (c *fakeCluster) versions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var versions []string
+	for index := range c.indices {
+		versions = append(versions, index)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
+
+// createVersion creates the version of the index behind alias named after day and holding documents
+func createVersion(ctx context.Context, esAPI *es.API, cluster *fakeCluster, alias string, day, documents int) string {
+	index := es.IndexVersionName(alias, time.Date(2020, time.March, day, 9, 0, 0, 0, time.UTC))
+
+	_, err := esAPI.CreateSearchIndex(ctx, index, "postcode-mappings.json")
+	So(err, ShouldBeNil)
+
+	cluster.mu.Lock()
+	cluster.indices[index] = documents
+	cluster.mu.Unlock()
+
+	return index
+}
+
+func TestIndexVersions(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given an index created before aliases were used", t, func() {
+		cluster := &fakeCluster{indices: map[string]int{"test_postcode": 10}, aliases: map[string]string{}}
+		server := httptest.NewServer(cluster)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+
+		first := createVersion(ctx, esAPI, cluster, "test_postcode", 1, 10)
+
+		So(first, ShouldEqual, "test_postcode_20200301090000")
+
+		Convey("When a version is being built it is found by the loaders", func() {
+			index, err := esAPI.BuildIndex(ctx, "test_postcode")
+			So(err, ShouldBeNil)
+			So(index, ShouldEqual, first)
+		})
+
+		Convey("When a version with fewer documents than expected is published", func() {
+			err := esAPI.PublishIndex(ctx, "test_postcode", first, 11, 1)
+
+			Convey("Then the alias is not created and the old index is kept", func() {
+				So(err, ShouldEqual, es.ErrTooFewDocuments)
+				So(cluster.aliases, ShouldBeEmpty)
+				So(cluster.versions(), ShouldContain, "test_postcode")
+			})
+		})
+
+		Convey("When the version is published", func() {
+			So(esAPI.PublishIndex(ctx, "test_postcode", first, 10, 1), ShouldBeNil)
+
+			Convey("Then the alias replaces the old index", func() {
+				So(cluster.aliases["test_postcode"], ShouldEqual, first)
+				So(cluster.versions(), ShouldResemble, []string{first})
+
+				_, err := esAPI.BuildIndex(ctx, "test_postcode")
+				So(err, ShouldEqual, es.ErrNoBuildIndex)
+			})
+
+			Convey("And two more versions are published keeping one previous version", func() {
+				second := createVersion(ctx, esAPI, cluster, "test_postcode", 2, 12)
+				So(esAPI.PublishIndex(ctx, "test_postcode", second, 12, 1), ShouldBeNil)
+
+				third := createVersion(ctx, esAPI, cluster, "test_postcode", 3, 14)
+				So(esAPI.PublishIndex(ctx, "test_postcode", third, 14, 1), ShouldBeNil)
+
+				Convey("Then the oldest version is deleted", func() {
+					So(cluster.aliases["test_postcode"], ShouldEqual, third)
+					So(cluster.versions(), ShouldResemble, []string{second, third})
+				})
+
+				Convey("Then rolling back points the alias at the previous version", func() {
+					previous, err := esAPI.RollbackIndex(ctx, "test_postcode")
+					So(err, ShouldBeNil)
+					So(previous, ShouldEqual, second)
+					So(cluster.aliases["test_postcode"], ShouldEqual, second)
+
+					_, err = esAPI.RollbackIndex(ctx, "test_postcode")
+					So(err, ShouldEqual, es.ErrNoPreviousIndex)
+				})
+
+				Convey("Then the version rolled back from is deleted and not used for the next load", func() {
+					_, err := esAPI.RollbackIndex(ctx, "test_postcode")
+					So(err, ShouldBeNil)
+					So(cluster.versions(), ShouldResemble, []string{second})
+
+					_, err = esAPI.BuildIndex(ctx, "test_postcode")
+					So(err, ShouldEqual, es.ErrNoBuildIndex)
+
+					fourth := createVersion(ctx, esAPI, cluster, "test_postcode", 4, 14)
+					index, err := esAPI.BuildIndex(ctx, "test_postcode")
+					So(err, ShouldBeNil)
+					So(index, ShouldEqual, fourth)
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,124 @@
+package elasticsearch
+
+import (
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling elasticsearch while the circuit breaker is open.
+// The errors a breaker returns match it with errors.Is and give how long until the breaker
+// lets a trial call through.
+var ErrCircuitOpen error = circuitOpenError(0)
+
+type circuitOpenError time.Duration
+
+func (e circuitOpenError) Error() string {
+	return "circuit breaker open, elasticsearch unavailable"
+}
+
+func (e circuitOpenError) Is(target error) bool {
+	_, ok := target.(circuitOpenError)
+	return ok
+}
+
+// RetryAfter returns how long until the breaker lets a trial call through
+func (e circuitOpenError) RetryAfter() time.Duration {
+	return time.Duration(e)
+}
+
+// DefaultFailureThreshold and DefaultOpenTimeout configure the circuit breaker of an API
+// unless another breaker is set
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+type breakerState int
+
+const (
+	closed breakerState = iota
+	open
+	halfOpen
+)
+
+// CircuitBreaker fails calls fast once elasticsearch has been unavailable for a number
+// of consecutive calls. After the open timeout a single trial call is let through; the
+// breaker closes again if it succeeds or stays open for another timeout if not.
+type CircuitBreaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// NewCircuitBreaker creates a CircuitBreaker which opens after failureThreshold consecutive
+// failures. A threshold of zero or less disables the breaker.
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold <= 0 {
+		return nil
+	}
+
+	return &CircuitBreaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+	}
+}
+
+// Allow returns ErrCircuitOpen if a call should not be made
+func (cb *CircuitBreaker) Allow() error {
+	if cb == nil {
+		return nil
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case open:
+		if remaining := cb.openTimeout - time.Since(cb.openedAt); remaining > 0 {
+			return circuitOpenError(remaining)
+		}
+		cb.state = halfOpen
+		cb.openedAt = time.Now()
+	case halfOpen:
+		// a trial call is in flight, allow another only if it never reported back
+		if remaining := cb.openTimeout - time.Since(cb.openedAt); remaining > 0 {
+			return circuitOpenError(remaining)
+		}
+		cb.openedAt = time.Now()
+	}
+
+	return nil
+}
+
+// Success records a call that reached elasticsearch and closes the breaker
+func (cb *CircuitBreaker) Success() {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.state = closed
+	cb.failures = 0
+}
+
+// Failure records a call that failed because elasticsearch was unavailable
+func (cb *CircuitBreaker) Failure() {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	if cb.state == halfOpen || cb.failures >= cb.failureThreshold {
+		cb.state = open
+		cb.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,228 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+// ErrBulkResponseMismatch is returned when elasticsearch does not report on every document in a bulk request
+var ErrBulkResponseMismatch = errors.New("bulk response does not contain an item for every document sent")
+
+// BulkItem is a document that elasticsearch did not index, with the reason it was rejected
+type BulkItem struct {
+	Position  int             `json:"position"`
+	Status    int             `json:"status"`
+	ErrorType string          `json:"error_type,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+	Document  json.RawMessage `json:"document"`
+}
+
+// BulkResult reports the outcome of every document in a bulk request. Failed items were
+// rejected, e.g. for an invalid geo_shape or mapping conflict, and will never succeed as
+// they are. Retryable items were still being refused, e.g. with a 429, when the retries
+// ran out. The position of an item is its index in the documents passed to BulkRequest,
+// or the order it was added to a BulkIndexer.
+type BulkResult struct {
+	Indexed   int
+	Failed    []BulkItem
+	Retryable []BulkItem
+}
+
+// DeadLetter records documents that could not be indexed
+type DeadLetter interface {
+	Write(indexName string, items []BulkItem) error
+}
+
+type bulkResponse struct {
+	Errors bool                             `json:"errors"`
+	Items  []map[string]bulkResponseOutcome `json:"items"`
+}
+
+type bulkResponseOutcome struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error,omitempty"`
+}
+
+// BulkRequest indexes the documents in a single bulk request and reports which were indexed.
+// Items refused with a 429, 502, 503 or 504 are sent again, on their own, following the retry
+// policy of the API. Items that are not indexed are logged and written to the dead letter, if set.
+func (api *API) BulkRequest(ctx context.Context, indexName string, documents []interface{}) (*BulkResult, error) {
+	sources := make([]json.RawMessage, len(documents))
+
+	for i, doc := range documents {
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		sources[i] = b
+	}
+
+	return api.bulk(ctx, indexName, nil, sources, 0)
+}
+
+// bulk indexes documents that are already encoded, numbering their positions from offset.
+// Documents are given the id at the same position in ids, or an id generated by
+// elasticsearch if there is none.
+func (api *API) bulk(ctx context.Context, indexName string, ids []string, sources []json.RawMessage, offset int) (*BulkResult, error) {
+	path := api.url + "/_bulk"
+
+	pending := make([]int, len(sources))
+	for i := range sources {
+		pending[i] = i
+	}
+
+	result := &BulkResult{}
+
+	for retry := 0; ; retry++ {
+		var bulk []byte
+		for _, position := range pending {
+			var id string
+			if position < len(ids) {
+				id = ids[position]
+			}
+
+			bulk = append(bulk, api.bulkAction(indexName, id)...)
+			bulk = append(bulk, sources[position]...)
+			bulk = append(bulk, '\n')
+		}
+
+		jsonBody, _, err := api.CallElastic(ctx, path, "POST", bulk)
+		if err != nil {
+			return nil, err
+		}
+
+		var response bulkResponse
+		if err = json.Unmarshal(jsonBody, &response); err != nil {
+			return nil, err
+		}
+
+		if len(response.Items) != len(pending) {
+			return nil, ErrBulkResponseMismatch
+		}
+
+		var retryable []BulkItem
+
+		for i, item := range response.Items {
+			for _, outcome := range item {
+				position := pending[i]
+
+				if outcome.Status >= http.StatusOK && outcome.Status < http.StatusMultipleChoices {
+					result.Indexed++
+					continue
+				}
+
+				rejected := BulkItem{Position: offset + position, Status: outcome.Status, Document: sources[position]}
+				if outcome.Error != nil {
+					rejected.ErrorType = outcome.Error.Type
+					rejected.Reason = outcome.Error.Reason
+				}
+
+				if retryableStatus[outcome.Status] {
+					retryable = append(retryable, rejected)
+				} else {
+					result.Failed = append(result.Failed, rejected)
+				}
+			}
+		}
+
+		if len(retryable) == 0 {
+			break
+		}
+
+		if retry >= api.retryPolicy.MaxRetries || !sleep(ctx, api.retryPolicy.backoff(retry)) {
+			result.Retryable = retryable
+			break
+		}
+
+		log.Event(ctx, "retrying bulk items", log.WARN, log.Data{"index": indexName, "items": len(retryable), "retry": retry + 1})
+
+		pending = pending[:0]
+		for _, item := range retryable {
+			pending = append(pending, item.Position-offset)
+		}
+	}
+
+	api.recordBulkFailures(ctx, indexName, len(sources), result)
+
+	return result, nil
+}
+
+func (api *API) recordBulkFailures(ctx context.Context, indexName string, total int, result *BulkResult) {
+	rejected := append(append([]BulkItem{}, result.Failed...), result.Retryable...)
+	if len(rejected) == 0 {
+		return
+	}
+
+	logData := log.Data{
+		"index":      indexName,
+		"documents":  total,
+		"indexed":    result.Indexed,
+		"failed":     len(result.Failed),
+		"retryable":  len(result.Retryable),
+		"position":   rejected[0].Position,
+		"error_type": rejected[0].ErrorType,
+		"reason":     rejected[0].Reason,
+	}
+
+	log.Event(ctx, "bulk request did not index every document", log.ERROR, logData)
+
+	if api.deadLetter == nil {
+		return
+	}
+
+	if err := api.deadLetter.Write(indexName, rejected); err != nil {
+		log.Event(ctx, "failed to write rejected documents to dead letter", log.ERROR, log.Error(err), logData)
+	}
+}
+
+// DeadLetterFile writes documents that could not be indexed to a newline delimited json
+// file, one line per document, so they can be corrected and loaded again
+type DeadLetterFile struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+type deadLetterRecord struct {
+	Index string `json:"index"`
+	BulkItem
+}
+
+// NewDeadLetterFile opens the file at path for appending, creating it if needed
+func NewDeadLetterFile(path string) (*DeadLetterFile, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeadLetterFile{file: file}, nil
+}
+
+// Write appends a line for each item
+func (d *DeadLetterFile) Write(indexName string, items []BulkItem) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	encoder := json.NewEncoder(d.file)
+	for _, item := range items {
+		if err := encoder.Encode(deadLetterRecord{Index: indexName, BulkItem: item}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the file
+func (d *DeadLetterFile) Close() error {
+	return d.file.Close()
+}
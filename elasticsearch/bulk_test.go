@@ -0,0 +1,128 @@
+package elasticsearch_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testDoc struct {
+	Code string `json:"code"`
+}
+
+// newBulkElasticsearch returns a server that indexes documents whose code is listed in
+// accept, rejects those listed in reject and refuses everything else with a 429 until
+// the request is retried
+func newBulkElasticsearch(requests *[][]string, accept, reject map[string]bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+
+		var codes []string
+		var items []string
+
+		for i := 1; i < len(lines); i += 2 {
+			var doc testDoc
+			json.Unmarshal([]byte(lines[i]), &doc)
+			codes = append(codes, doc.Code)
+
+			switch {
+			case accept[doc.Code] || len(*requests) > 0:
+				items = append(items, `{"index":{"status":201}}`)
+			case reject[doc.Code]:
+				items = append(items, `{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [location] of type [geo_shape]"}}}`)
+			default:
+				items = append(items, `{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"rejected execution"}}}`)
+			}
+		}
+
+		*requests = append(*requests, codes)
+		w.Write([]byte(`{"took":3,"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+}
+
+func TestBulkRequest(t *testing.T) {
+	ctx := context.Background()
+	docs := []interface{}{testDoc{"E01"}, testDoc{"E02"}, testDoc{"E03"}, testDoc{"E04"}}
+
+	Convey("Given elasticsearch rejects one document and refuses another", t, func() {
+		var requests [][]string
+		server := newBulkElasticsearch(&requests, map[string]bool{"E01": true, "E04": true}, map[string]bool{"E02": true})
+		defer server.Close()
+
+		deadLetterPath := filepath.Join(os.TempDir(), "bulk_test_dead_letter.ndjson")
+		os.Remove(deadLetterPath)
+		defer os.Remove(deadLetterPath)
+
+		deadLetter, err := es.NewDeadLetterFile(deadLetterPath)
+		So(err, ShouldBeNil)
+
+		esAPI := newTestAPI(server.URL, nil)
+		esAPI.SetDeadLetter(deadLetter)
+
+		result, err := esAPI.BulkRequest(ctx, "test_geo", docs)
+		So(err, ShouldBeNil)
+		So(deadLetter.Close(), ShouldBeNil)
+
+		Convey("Then only the refused document is sent again", func() {
+			So(requests, ShouldResemble, [][]string{{"E01", "E02", "E03", "E04"}, {"E03"}})
+		})
+
+		Convey("Then the result reports the rejected document and its position", func() {
+			So(result.Indexed, ShouldEqual, 3)
+			So(result.Retryable, ShouldBeEmpty)
+			So(result.Failed, ShouldHaveLength, 1)
+			So(result.Failed[0].Position, ShouldEqual, 1)
+			So(result.Failed[0].Status, ShouldEqual, http.StatusBadRequest)
+			So(result.Failed[0].ErrorType, ShouldEqual, "mapper_parsing_exception")
+			So(string(result.Failed[0].Document), ShouldEqual, `{"code":"E02"}`)
+		})
+
+		Convey("Then the rejected document is written to the dead letter file", func() {
+			f, err := os.Open(deadLetterPath)
+			So(err, ShouldBeNil)
+			defer f.Close()
+
+			var lines []string
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+
+			So(lines, ShouldHaveLength, 1)
+			So(lines[0], ShouldStartWith, `{"index":"test_geo","position":1,"status":400,"error_type":"mapper_parsing_exception"`)
+			So(lines[0], ShouldEndWith, `"document":{"code":"E02"}}`)
+		})
+	})
+
+	Convey("Given elasticsearch keeps refusing a document", t, func() {
+		var requests [][]string
+		server := newBulkElasticsearch(&requests, map[string]bool{"E01": true, "E02": true, "E04": true}, nil)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+		esAPI.SetRetryPolicy(es.RetryPolicy{})
+
+		result, err := esAPI.BulkRequest(ctx, "test_geo", docs)
+		So(err, ShouldBeNil)
+
+		Convey("Then it is reported as retryable once the retries run out", func() {
+			So(requests, ShouldHaveLength, 1)
+			So(result.Indexed, ShouldEqual, 3)
+			So(result.Failed, ShouldBeEmpty)
+			So(result.Retryable, ShouldHaveLength, 1)
+			So(result.Retryable[0].Position, ShouldEqual, 2)
+			So(result.Retryable[0].Status, ShouldEqual, http.StatusTooManyRequests)
+		})
+	})
+}
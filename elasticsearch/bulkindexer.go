@@ -0,0 +1,279 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Defaults for any BulkIndexerConfig fields that are not set
+const (
+	DefaultBulkWorkers        = 4
+	DefaultBulkFlushBytes     = 5 * 1024 * 1024
+	DefaultBulkFlushDocuments = 500
+	DefaultBulkFlushInterval  = 30 * time.Second
+)
+
+// BulkIndexerConfig controls how a BulkIndexer groups documents into bulk requests. A
+// request is sent once it reaches FlushBytes or FlushDocuments, or FlushInterval after
+// its first document was added. A document larger than FlushBytes is sent on its own.
+type BulkIndexerConfig struct {
+	Index          string
+	Workers        int
+	FlushBytes     int
+	FlushDocuments int
+	FlushInterval  time.Duration
+
+	// OnProgress is called with the running totals after every bulk request. It is
+	// never called concurrently, but is called from the worker goroutines and must
+	// not call back into the indexer.
+	OnProgress func(BulkIndexerStats)
+}
+
+// BulkIndexerStats holds the running totals of a BulkIndexer
+type BulkIndexerStats struct {
+	Added     int
+	Sent      int
+	Indexed   int
+	Failed    int
+	Retryable int
+	Requests  int
+	Bytes     int
+}
+
+// BulkIndexer indexes documents using concurrent bulk requests. Add blocks while every
+// worker is busy so documents are not read faster than elasticsearch can take them.
+type BulkIndexer struct {
+	api    *API
+	config BulkIndexerConfig
+
+	mu    sync.Mutex
+	batch *bulkBatch
+	added int
+
+	batches chan *bulkBatch
+	workers sync.WaitGroup
+	stop    chan struct{}
+	stopped chan struct{}
+
+	statsMu sync.Mutex
+	stats   BulkIndexerStats
+	err     error
+}
+
+type bulkBatch struct {
+	offset  int
+	ids     []string
+	sources []json.RawMessage
+	bytes   int
+	started time.Time
+}
+
+// NewBulkIndexer starts the workers of a BulkIndexer, which must be closed once every document has been added
+func (api *API) NewBulkIndexer(ctx context.Context, config BulkIndexerConfig) *BulkIndexer {
+	if config.Workers <= 0 {
+		config.Workers = DefaultBulkWorkers
+	}
+	if config.FlushBytes <= 0 {
+		config.FlushBytes = DefaultBulkFlushBytes
+	}
+	if config.FlushDocuments <= 0 {
+		config.FlushDocuments = DefaultBulkFlushDocuments
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultBulkFlushInterval
+	}
+
+	bi := &BulkIndexer{
+		api:     api,
+		config:  config,
+		batches: make(chan *bulkBatch),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		bi.workers.Add(1)
+		go bi.work(ctx)
+	}
+
+	go bi.flushOnInterval(ctx)
+
+	return bi
+}
+
+// Add queues a document to be indexed with an id generated by elasticsearch. It returns the
+// error from any failed bulk request so that callers stop adding documents, in which case
+// documents already added may not have been sent.
+func (bi *BulkIndexer) Add(ctx context.Context, document interface{}) error {
+	return bi.AddWithID(ctx, "", document)
+}
+
+// AddWithID queues a document to be indexed with the given id, replacing any document
+// already indexed with it, so a document sent more than once is only stored once
+func (bi *BulkIndexer) AddWithID(ctx context.Context, id string, document interface{}) error {
+	source, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	if err = bi.Err(); err != nil {
+		return err
+	}
+
+	size := len(bi.api.bulkAction(bi.config.Index, id)) + len(source) + 1
+
+	var full []*bulkBatch
+
+	bi.mu.Lock()
+	if bi.batch != nil && bi.batch.bytes+size > bi.config.FlushBytes {
+		full = append(full, bi.take())
+	}
+
+	if bi.batch == nil {
+		bi.batch = &bulkBatch{offset: bi.added, started: time.Now()}
+	}
+
+	bi.batch.ids = append(bi.batch.ids, id)
+	bi.batch.sources = append(bi.batch.sources, source)
+	bi.batch.bytes += size
+	bi.added++
+
+	if len(bi.batch.sources) >= bi.config.FlushDocuments || bi.batch.bytes >= bi.config.FlushBytes {
+		full = append(full, bi.take())
+	}
+	bi.mu.Unlock()
+
+	bi.statsMu.Lock()
+	bi.stats.Added++
+	bi.statsMu.Unlock()
+
+	for _, batch := range full {
+		if err = bi.send(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close sends any remaining documents and waits for every bulk request to finish
+func (bi *BulkIndexer) Close(ctx context.Context) (BulkIndexerStats, error) {
+	close(bi.stop)
+	<-bi.stopped
+
+	bi.mu.Lock()
+	batch := bi.take()
+	bi.mu.Unlock()
+
+	var err error
+	if batch != nil {
+		err = bi.send(ctx, batch)
+	}
+
+	close(bi.batches)
+	bi.workers.Wait()
+
+	if indexErr := bi.Err(); indexErr != nil {
+		err = indexErr
+	}
+
+	return bi.Stats(), err
+}
+
+// Stats returns the running totals
+func (bi *BulkIndexer) Stats() BulkIndexerStats {
+	bi.statsMu.Lock()
+	defer bi.statsMu.Unlock()
+
+	return bi.stats
+}
+
+// Err returns the error from the first bulk request that failed
+func (bi *BulkIndexer) Err() error {
+	bi.statsMu.Lock()
+	defer bi.statsMu.Unlock()
+
+	return bi.err
+}
+
+// take removes the batch being filled, callers must hold mu
+func (bi *BulkIndexer) take() *bulkBatch {
+	batch := bi.batch
+	bi.batch = nil
+
+	return batch
+}
+
+func (bi *BulkIndexer) send(ctx context.Context, batch *bulkBatch) error {
+	select {
+	case bi.batches <- batch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (bi *BulkIndexer) work(ctx context.Context) {
+	defer bi.workers.Done()
+
+	for batch := range bi.batches {
+		// once a request has failed the remaining batches are dropped
+		if bi.Err() != nil {
+			continue
+		}
+
+		result, err := bi.api.bulk(ctx, bi.config.Index, batch.ids, batch.sources, batch.offset)
+
+		bi.statsMu.Lock()
+		bi.stats.Requests++
+		bi.stats.Sent += len(batch.sources)
+		bi.stats.Bytes += batch.bytes
+
+		if err != nil {
+			if bi.err == nil {
+				bi.err = err
+			}
+		} else {
+			bi.stats.Indexed += result.Indexed
+			bi.stats.Failed += len(result.Failed)
+			bi.stats.Retryable += len(result.Retryable)
+		}
+
+		if bi.config.OnProgress != nil {
+			bi.config.OnProgress(bi.stats)
+		}
+		bi.statsMu.Unlock()
+	}
+}
+
+// flushOnInterval sends a partly filled batch once it has waited for the flush interval
+func (bi *BulkIndexer) flushOnInterval(ctx context.Context) {
+	defer close(bi.stopped)
+
+	ticker := time.NewTicker(bi.config.FlushInterval / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-bi.stop:
+			return
+		case <-ticker.C:
+			bi.mu.Lock()
+			var batch *bulkBatch
+			if bi.batch != nil && time.Since(bi.batch.started) >= bi.config.FlushInterval {
+				batch = bi.take()
+			}
+			bi.mu.Unlock()
+
+			if batch != nil {
+				select {
+				case bi.batches <- batch:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newCountingElasticsearch returns a server that indexes every document, recording the
+// number of documents and bytes in each request and the most requests handled at once
+func newCountingElasticsearch(delay time.Duration, sizes *[]int, bytes *[]int, maxInFlight *int32) *httptest.Server {
+	var mu sync.Mutex
+	var inFlight int32
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		docs := strings.Count(string(body), "\n") / 2
+
+		mu.Lock()
+		*sizes = append(*sizes, docs)
+		*bytes = append(*bytes, len(body))
+		if current > *maxInFlight {
+			*maxInFlight = current
+		}
+		mu.Unlock()
+
+		time.Sleep(delay)
+
+		items := strings.TrimSuffix(strings.Repeat(`{"index":{"status":201}},`, docs), ",")
+		w.Write([]byte(`{"took":1,"errors":false,"items":[` + items + `]}`))
+	}))
+}
+
+func TestBulkIndexer(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a bulk indexer flushing every 10 documents with 2 workers", t, func() {
+		var sizes, bytes []int
+		var maxInFlight int32
+		server := newCountingElasticsearch(20*time.Millisecond, &sizes, &bytes, &maxInFlight)
+		defer server.Close()
+
+		var progress []es.BulkIndexerStats
+		indexer := newTestAPI(server.URL, nil).NewBulkIndexer(ctx, es.BulkIndexerConfig{
+			Index:          "test_geo",
+			Workers:        2,
+			FlushDocuments: 10,
+			OnProgress:     func(stats es.BulkIndexerStats) { progress = append(progress, stats) },
+		})
+
+		for i := 0; i < 95; i++ {
+			So(indexer.Add(ctx, testDoc{Code: "E01"}), ShouldBeNil)
+		}
+
+		stats, err := indexer.Close(ctx)
+		So(err, ShouldBeNil)
+
+		Convey("Then every document is indexed in batches of at most 10", func() {
+			So(stats.Added, ShouldEqual, 95)
+			So(stats.Sent, ShouldEqual, 95)
+			So(stats.Indexed, ShouldEqual, 95)
+			So(stats.Requests, ShouldEqual, 10)
+			So(sizes, ShouldHaveLength, 10)
+			for _, size := range sizes {
+				So(size, ShouldBeLessThanOrEqualTo, 10)
+			}
+		})
+
+		Convey("Then no more requests than workers are sent at once", func() {
+			So(maxInFlight, ShouldBeLessThanOrEqualTo, 2)
+		})
+
+		Convey("Then progress is reported after every request", func() {
+			So(progress, ShouldHaveLength, 10)
+			So(progress[9], ShouldResemble, stats)
+		})
+	})
+
+	Convey("Given a bulk indexer flushing by size", t, func() {
+		var sizes, bytes []int
+		var maxInFlight int32
+		server := newCountingElasticsearch(0, &sizes, &bytes, &maxInFlight)
+		defer server.Close()
+
+		indexer := newTestAPI(server.URL, nil).NewBulkIndexer(ctx, es.BulkIndexerConfig{
+			Index:      "test_geo",
+			FlushBytes: 1000,
+		})
+
+		for i := 0; i < 50; i++ {
+			So(indexer.Add(ctx, testDoc{Code: strings.Repeat("E", 100)}), ShouldBeNil)
+		}
+		So(indexer.Add(ctx, testDoc{Code: strings.Repeat("W", 2000)}), ShouldBeNil)
+
+		stats, err := indexer.Close(ctx)
+		So(err, ShouldBeNil)
+
+		Convey("Then requests stay under the size limit unless a single document is larger", func() {
+			So(stats.Indexed, ShouldEqual, 51)
+			for i, size := range bytes {
+				if size > 1000 {
+					So(sizes[i], ShouldEqual, 1)
+				}
+			}
+			So(sum(bytes), ShouldEqual, stats.Bytes)
+		})
+	})
+
+	Convey("Given a bulk indexer with a short flush interval", t, func() {
+		var sizes, bytes []int
+		var maxInFlight int32
+		server := newCountingElasticsearch(0, &sizes, &bytes, &maxInFlight)
+		defer server.Close()
+
+		indexer := newTestAPI(server.URL, nil).NewBulkIndexer(ctx, es.BulkIndexerConfig{
+			Index:         "test_geo",
+			FlushInterval: 20 * time.Millisecond,
+		})
+
+		So(indexer.Add(ctx, testDoc{Code: "E01"}), ShouldBeNil)
+
+		Convey("Then a partly filled batch is sent without waiting for more documents", func() {
+			time.Sleep(100 * time.Millisecond)
+			So(indexer.Stats().Indexed, ShouldEqual, 1)
+
+			_, err := indexer.Close(ctx)
+			So(err, ShouldBeNil)
+		})
+	})
+
+	Convey("Given a bulk indexer adding documents with ids", t, func() {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+			w.Write([]byte(`{"took":1,"errors":false,"items":[{"index":{"status":200}},{"index":{"status":201}}]}`))
+		}))
+		defer server.Close()
+
+		indexer := newTestAPI(server.URL, nil).NewBulkIndexer(ctx, es.BulkIndexerConfig{Index: "test_geo"})
+
+		So(indexer.AddWithID(ctx, "42", testDoc{Code: "E01"}), ShouldBeNil)
+		So(indexer.Add(ctx, testDoc{Code: "E02"}), ShouldBeNil)
+
+		stats, err := indexer.Close(ctx)
+		So(err, ShouldBeNil)
+
+		Convey("Then the id is sent in the action of its document only", func() {
+			So(stats.Indexed, ShouldEqual, 2)
+
+			lines := strings.Split(strings.TrimSpace(body), "\n")
+			So(lines, ShouldHaveLength, 4)
+			So(lines[0], ShouldContainSubstring, `"_id": "42"`)
+			So(lines[2], ShouldNotContainSubstring, `"_id"`)
+		})
+	})
+
+	Convey("Given elasticsearch rejects bulk requests", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}))
+		defer server.Close()
+
+		indexer := newTestAPI(server.URL, nil).NewBulkIndexer(ctx, es.BulkIndexerConfig{Index: "test_geo", Workers: 1, FlushDocuments: 1})
+
+		Convey("Then adding documents stops with the error", func() {
+			var err error
+			for i := 0; i < 10 && err == nil; i++ {
+				err = indexer.Add(ctx, testDoc{Code: "E01"})
+			}
+			So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+
+			_, err = indexer.Close(ctx)
+			So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+		})
+	})
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
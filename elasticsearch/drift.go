@@ -0,0 +1,335 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+// ErrReindexRequired is returned when asked to apply drift that cannot be applied to a live index
+var ErrReindexRequired = errors.New("mapping drift cannot be applied in place, the index must be reloaded")
+
+// dynamicSettings can be changed on a live index, any other setting needs a new index
+var dynamicSettings = map[string]bool{
+	"index.auto_expand_replicas": true,
+	"index.max_result_window":    true,
+	"index.number_of_replicas":   true,
+	"index.refresh_interval":     true,
+}
+
+// FieldDrift describes a field or setting whose live definition differs from the mapping file
+type FieldDrift struct {
+	Name     string      `json:"name"`
+	Expected interface{} `json:"expected,omitempty"`
+	Live     interface{} `json:"live,omitempty"`
+}
+
+// MappingDrift holds the differences between a live index and the mapping file it was created from
+type MappingDrift struct {
+	Index           string       `json:"index"`
+	MappingsFile    string       `json:"mappings_file"`
+	Added           []FieldDrift `json:"added,omitempty"`
+	Changed         []FieldDrift `json:"changed,omitempty"`
+	Settings        []FieldDrift `json:"settings,omitempty"`
+	Unmapped        []FieldDrift `json:"unmapped,omitempty"`
+	RequiresReindex bool         `json:"requires_reindex"`
+
+	mappingType string
+	properties  json.RawMessage
+}
+
+// HasDrift reports whether the live index differs from the mapping file. Unmapped fields,
+// which elasticsearch maps dynamically as documents are loaded, are not drift.
+func (d *MappingDrift) HasDrift() bool {
+	return len(d.Added) > 0 || len(d.Changed) > 0 || len(d.Settings) > 0
+}
+
+// LogData returns the drift in a form suitable for logging
+func (d *MappingDrift) LogData() log.Data {
+	return log.Data{
+		"index":            d.Index,
+		"mappings_file":    d.MappingsFile,
+		"added":            d.Added,
+		"changed":          d.Changed,
+		"settings":         d.Settings,
+		"unmapped":         d.Unmapped,
+		"requires_reindex": d.RequiresReindex,
+	}
+}
+
+type indexDefinition struct {
+	Settings map[string]interface{}     `json:"settings"`
+	Mappings map[string]json.RawMessage `json:"mappings"`
+}
+
+// MappingDrift compares the live mappings and settings of an index, or the index behind an
+// alias, with the embedded mapping file. Fields in the file but not the index can be added
+// in place; fields whose type has changed and changes to static settings need a reindex.
+// Fields in the index but not the file are listed as unmapped.
+func (api *API) MappingDrift(ctx context.Context, indexName, mappingsFile string) (*MappingDrift, error) {
+	asset, err := Asset(mappingsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var expected indexDefinition
+	if err = json.Unmarshal(asset, &expected); err != nil {
+		return nil, err
+	}
+
+	drift := &MappingDrift{Index: indexName, MappingsFile: mappingsFile}
+
+	drift.mappingType, drift.properties = mappingProperties(expected.Mappings)
+
+	liveProperties, err := api.liveProperties(ctx, indexName)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedFields, err := flattenFields(drift.properties)
+	if err != nil {
+		return nil, err
+	}
+
+	liveFields, err := flattenFields(liveProperties)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range sortedKeys(expectedFields) {
+		live, ok := liveFields[name]
+		switch {
+		case !ok:
+			drift.Added = append(drift.Added, FieldDrift{Name: name, Expected: expectedFields[name]})
+		case !reflect.DeepEqual(live, expectedFields[name]):
+			drift.Changed = append(drift.Changed, FieldDrift{Name: name, Expected: expectedFields[name], Live: live})
+
+			if live["type"] != expectedFields[name]["type"] {
+				drift.RequiresReindex = true
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(liveFields) {
+		if _, ok := expectedFields[name]; !ok {
+			drift.Unmapped = append(drift.Unmapped, FieldDrift{Name: name, Live: liveFields[name]})
+		}
+	}
+
+	liveSettings, err := api.liveSettings(ctx, indexName)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedSettings := make(map[string]interface{})
+	flattenSettings("index", expected.Settings, expectedSettings)
+
+	for _, name := range sortedKeys(expectedSettings) {
+		if !reflect.DeepEqual(liveSettings[name], expectedSettings[name]) {
+			drift.Settings = append(drift.Settings, FieldDrift{Name: name, Expected: expectedSettings[name], Live: liveSettings[name]})
+
+			if !dynamicSettings[name] {
+				drift.RequiresReindex = true
+			}
+		}
+	}
+
+	return drift, nil
+}
+
+// ApplyMappingDrift adds the fields missing from a live index, updates the parameters of
+// changed fields and updates its dynamic settings. Drift that needs a reindex is not applied,
+// and elasticsearch rejects parameters that cannot be changed on a live field.
+func (api *API) ApplyMappingDrift(ctx context.Context, drift *MappingDrift) error {
+	if drift.RequiresReindex {
+		return ErrReindexRequired
+	}
+
+	if len(drift.Added) > 0 || len(drift.Changed) > 0 {
+		path := api.url + "/" + drift.Index + "/_mapping"
+		if !api.typeless() && drift.mappingType != "" {
+			path += "/" + drift.mappingType
+		}
+
+		body, err := json.Marshal(map[string]json.RawMessage{"properties": drift.properties})
+		if err != nil {
+			return err
+		}
+
+		if _, _, err = api.CallElastic(ctx, path, "PUT", body); err != nil {
+			return err
+		}
+	}
+
+	if len(drift.Settings) > 0 {
+		settings := make(map[string]interface{})
+		for _, setting := range drift.Settings {
+			settings[setting.Name] = setting.Expected
+		}
+
+		body, err := json.Marshal(settings)
+		if err != nil {
+			return err
+		}
+
+		if _, _, err = api.CallElastic(ctx, api.url+"/"+drift.Index+"/_settings", "PUT", body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (api *API) liveProperties(ctx context.Context, indexName string) (json.RawMessage, error) {
+	body, _, err := api.CallElastic(ctx, api.url+"/"+indexName+"/_mapping", "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var indices map[string]struct {
+		Mappings map[string]json.RawMessage `json:"mappings"`
+	}
+	if err = json.Unmarshal(body, &indices); err != nil {
+		return nil, err
+	}
+
+	// an alias is answered with the index behind it
+	for _, index := range indices {
+		_, properties := mappingProperties(index.Mappings)
+		return properties, nil
+	}
+
+	return nil, nil
+}
+
+func (api *API) liveSettings(ctx context.Context, indexName string) (map[string]interface{}, error) {
+	body, _, err := api.CallElastic(ctx, api.url+"/"+indexName+"/_settings?flat_settings=true", "GET", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var indices map[string]struct {
+		Settings map[string]interface{} `json:"settings"`
+	}
+	if err = json.Unmarshal(body, &indices); err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]interface{})
+	for _, index := range indices {
+		for name, value := range index.Settings {
+			settings[name] = settingValue(value)
+		}
+	}
+
+	return settings, nil
+}
+
+// mappingProperties returns the properties of a mapping and the mapping type they are
+// nested under, which is empty for elasticsearch 7 and later
+func mappingProperties(mappings map[string]json.RawMessage) (string, json.RawMessage) {
+	if properties, ok := mappings["properties"]; ok {
+		return "", properties
+	}
+
+	for mappingType, mapping := range mappings {
+		var typed struct {
+			Properties json.RawMessage `json:"properties"`
+		}
+		if err := json.Unmarshal(mapping, &typed); err == nil {
+			return mappingType, typed.Properties
+		}
+	}
+
+	return "", nil
+}
+
+// flattenFields returns the definition of every field keyed by its full name, with object
+// and multi-fields named after their parent, e.g. pin.location and postcode.raw
+func flattenFields(properties json.RawMessage) (map[string]map[string]interface{}, error) {
+	fields := make(map[string]map[string]interface{})
+	if len(properties) == 0 {
+		return fields, nil
+	}
+
+	var definitions map[string]map[string]interface{}
+	if err := json.Unmarshal(properties, &definitions); err != nil {
+		return nil, err
+	}
+
+	flattenDefinitions("", definitions, fields)
+
+	return fields, nil
+}
+
+func flattenDefinitions(prefix string, definitions map[string]map[string]interface{}, fields map[string]map[string]interface{}) {
+	for name, definition := range definitions {
+		field := make(map[string]interface{})
+
+		for key, value := range definition {
+			nested, ok := value.(map[string]interface{})
+			if !ok || (key != "properties" && key != "fields") {
+				field[key] = value
+				continue
+			}
+
+			children := make(map[string]map[string]interface{})
+			for child, childDefinition := range nested {
+				if childMap, ok := childDefinition.(map[string]interface{}); ok {
+					children[child] = childMap
+				}
+			}
+
+			flattenDefinitions(prefix+name+".", children, fields)
+		}
+
+		fields[prefix+name] = field
+	}
+}
+
+// flattenSettings names settings the way elasticsearch does with flat_settings
+func flattenSettings(prefix string, settings map[string]interface{}, flat map[string]interface{}) {
+	for name, value := range settings {
+		key := name
+		if !strings.HasPrefix(key, prefix+".") && key != prefix {
+			key = prefix + "." + name
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenSettings(key, nested, flat)
+			continue
+		}
+
+		flat[key] = settingValue(value)
+	}
+}
+
+// settingValue converts a setting to the strings elasticsearch returns settings as
+func settingValue(value interface{}) interface{} {
+	if values, ok := value.([]interface{}); ok {
+		strs := make([]string, len(values))
+		for i, v := range values {
+			strs[i] = fmt.Sprint(v)
+		}
+		return strs
+	}
+
+	return fmt.Sprint(value)
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, key := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, key.String())
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
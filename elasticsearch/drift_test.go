@@ -0,0 +1,152 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const liveSettings = `{"test_postcode_20200301090000":{"settings":{
+	"index.analysis.analyzer.raw_analyzer.filter":["lowercase","collapse_whitespace_filter","trim"],
+	"index.analysis.analyzer.raw_analyzer.tokenizer":"whitespace",
+	"index.analysis.analyzer.raw_analyzer.type":"custom",
+	"index.analysis.filter.autocomplete_filter.max_gram":"35",
+	"index.analysis.filter.autocomplete_filter.min_gram":"1",
+	"index.analysis.filter.autocomplete_filter.type":"edge_ngram",
+	"index.analysis.filter.collapse_whitespace_filter.pattern":"\\s+",
+	"index.analysis.filter.collapse_whitespace_filter.replacement":" ",
+	"index.analysis.filter.collapse_whitespace_filter.type":"pattern_replace",
+	"index.creation_date":"1583053200000",
+	"index.number_of_replicas":"REPLICAS",
+	"index.number_of_shards":"5",
+	"index.uuid":"u5mW4Z9nS1e6sY7Dvqz2xA"
+}}}`
+
+const postcodeProperties = `{
+	"pin":{"properties":{"location":{"type":"geo_point"}}},
+	"postcode":{"type":"keyword","fields":{"raw":{"type":"text","analyzer":"raw_analyzer","index_options":"docs","norms":false}}},
+	"postcode_raw":{"type":"keyword","index":false},
+	"oa":{"type":"keyword"},"lsoa":{"type":"keyword"},"msoa":{"type":"keyword"},"lad":{"type":"keyword"},"ward":{"type":"keyword"},
+	"parliamentary_constituency":{"type":"keyword"},"region":{"type":"keyword"},"country":{"type":"keyword"},
+	"imd_rank":{"type":"keyword"},"rural_urban":{"type":"keyword"},"termination_date":{"type":"keyword"}
+}`
+
+// newMappedElasticsearch returns a server answering for an index with the given
+// properties and number of replicas, recording any updates made to it
+func newMappedElasticsearch(properties, replicas string, updates map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT":
+			body, _ := ioutil.ReadAll(r.Body)
+			updates[r.URL.Path] = string(body)
+		case strings.HasSuffix(r.URL.Path, "/_mapping"):
+			w.Write([]byte(`{"test_postcode_20200301090000":{"mappings":{"doc":{"properties":` + properties + `}}}}`))
+		case strings.HasSuffix(r.URL.Path, "/_settings"):
+			w.Write([]byte(strings.Replace(liveSettings, "REPLICAS", replicas, 1)))
+		}
+	}))
+}
+
+func TestMappingDrift(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given an index matching its mapping file", t, func() {
+		server := newMappedElasticsearch(postcodeProperties, "1", nil)
+		defer server.Close()
+
+		drift, err := newTestAPI(server.URL, nil).MappingDrift(ctx, "test_postcode", "postcode-mappings.json")
+		So(err, ShouldBeNil)
+		So(drift.HasDrift(), ShouldBeFalse)
+	})
+
+	Convey("Given an index missing a field and with a different number of replicas", t, func() {
+		updates := make(map[string]string)
+		properties := strings.Replace(postcodeProperties, `,"fields":{"raw":{"type":"text","analyzer":"raw_analyzer","index_options":"docs","norms":false}}`, "", 1)
+		server := newMappedElasticsearch(properties, "0", updates)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+
+		drift, err := esAPI.MappingDrift(ctx, "test_postcode", "postcode-mappings.json")
+		So(err, ShouldBeNil)
+
+		Convey("Then the drift can be applied in place", func() {
+			So(drift.Added, ShouldHaveLength, 1)
+			So(drift.Added[0].Name, ShouldEqual, "postcode.raw")
+			So(drift.Changed, ShouldBeEmpty)
+			So(drift.Unmapped, ShouldBeEmpty)
+			So(drift.Settings, ShouldResemble, []es.FieldDrift{{Name: "index.number_of_replicas", Expected: "1", Live: "0"}})
+			So(drift.RequiresReindex, ShouldBeFalse)
+		})
+
+		Convey("Then applying it updates the mapping and settings", func() {
+			So(esAPI.ApplyMappingDrift(ctx, drift), ShouldBeNil)
+			So(updates["/test_postcode/_mapping/doc"], ShouldContainSubstring, `"raw_analyzer"`)
+			So(updates["/test_postcode/_settings"], ShouldEqual, `{"index.number_of_replicas":"1"}`)
+		})
+	})
+
+	Convey("Given an index with a changed and an extra field", t, func() {
+		properties := strings.Replace(postcodeProperties, `"location":{"type":"geo_point"}`, `"location":{"type":"geo_shape"}`, 1)
+		properties = strings.Replace(properties, `"postcode_raw"`, `"district":{"type":"text"},"postcode_raw"`, 1)
+		server := newMappedElasticsearch(properties, "1", nil)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+
+		drift, err := esAPI.MappingDrift(ctx, "test_postcode", "postcode-mappings.json")
+		So(err, ShouldBeNil)
+
+		Convey("Then the drift needs the index to be reloaded", func() {
+			So(drift.Changed, ShouldHaveLength, 1)
+			So(drift.Changed[0].Name, ShouldEqual, "pin.location")
+			So(drift.Unmapped, ShouldHaveLength, 1)
+			So(drift.Unmapped[0].Name, ShouldEqual, "district")
+			So(drift.RequiresReindex, ShouldBeTrue)
+			So(esAPI.ApplyMappingDrift(ctx, drift), ShouldEqual, es.ErrReindexRequired)
+		})
+	})
+
+	Convey("Given an index with a dynamically mapped field", t, func() {
+		properties := strings.Replace(postcodeProperties, `"postcode_raw"`, `"district":{"type":"text","fields":{"keyword":{"type":"keyword","ignore_above":256}}},"postcode_raw"`, 1)
+		server := newMappedElasticsearch(properties, "1", nil)
+		defer server.Close()
+
+		drift, err := newTestAPI(server.URL, nil).MappingDrift(ctx, "test_postcode", "postcode-mappings.json")
+		So(err, ShouldBeNil)
+
+		Convey("Then the field is listed as unmapped without being drift", func() {
+			So(drift.Unmapped, ShouldHaveLength, 2)
+			So(drift.Unmapped[0].Name, ShouldEqual, "district")
+			So(drift.Unmapped[1].Name, ShouldEqual, "district.keyword")
+			So(drift.HasDrift(), ShouldBeFalse)
+			So(drift.RequiresReindex, ShouldBeFalse)
+		})
+	})
+
+	Convey("Given an index with a field whose parameters but not type have changed", t, func() {
+		updates := make(map[string]string)
+		properties := strings.Replace(postcodeProperties, `"postcode_raw":{"type":"keyword","index":false}`, `"postcode_raw":{"type":"keyword"}`, 1)
+		server := newMappedElasticsearch(properties, "1", updates)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+
+		drift, err := esAPI.MappingDrift(ctx, "test_postcode", "postcode-mappings.json")
+		So(err, ShouldBeNil)
+
+		Convey("Then the drift can be applied in place", func() {
+			So(drift.Changed, ShouldHaveLength, 1)
+			So(drift.Changed[0].Name, ShouldEqual, "postcode_raw")
+			So(drift.RequiresReindex, ShouldBeFalse)
+			So(esAPI.ApplyMappingDrift(ctx, drift), ShouldBeNil)
+			So(updates["/test_postcode/_mapping/doc"], ShouldContainSubstring, `"postcode_raw"`)
+		})
+	})
+}
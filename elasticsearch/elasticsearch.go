@@ -8,11 +8,17 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"time"
 
 	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
 	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
 	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/semconv"
 )
 
 // ErrorUnexpectedStatusCode represents the error message to be returned when
@@ -21,19 +27,41 @@ var ErrorUnexpectedStatusCode = errors.New("unexpected status code from api")
 
 // API aggregates a client and URL and other common data for accessing the API
 type API struct {
-	clienter dphttp.Clienter
-	url      string
+	clienter     dphttp.Clienter
+	url          string
+	retryPolicy  RetryPolicy
+	breaker      *CircuitBreaker
+	deadLetter   DeadLetter
+	majorVersion int
 }
 
-// NewElasticSearchAPI creates an ElasticSearchAPI object
+// NewElasticSearchAPI creates an ElasticSearchAPI object, using the default retry
+// policy and circuit breaker
 func NewElasticSearchAPI(clienter dphttp.Clienter, elasticSearchAPIURL string) *API {
 
 	return &API{
-		clienter: clienter,
-		url:      elasticSearchAPIURL,
+		clienter:    clienter,
+		url:         elasticSearchAPIURL,
+		retryPolicy: DefaultRetryPolicy,
+		breaker:     NewCircuitBreaker(DefaultFailureThreshold, DefaultOpenTimeout),
 	}
 }
 
+// SetRetryPolicy sets how calls to elasticsearch are retried
+func (api *API) SetRetryPolicy(retryPolicy RetryPolicy) {
+	api.retryPolicy = retryPolicy
+}
+
+// SetDeadLetter sets where documents that bulk requests fail to index are recorded
+func (api *API) SetDeadLetter(deadLetter DeadLetter) {
+	api.deadLetter = deadLetter
+}
+
+// SetCircuitBreaker sets the circuit breaker guarding calls to elasticsearch, nil disables it
+func (api *API) SetCircuitBreaker(breaker *CircuitBreaker) {
+	api.breaker = breaker
+}
+
 // CreateSearchIndex creates a new index in elastic search
 func (api *API) CreateSearchIndex(ctx context.Context, indexName string, mappingsFile string) (int, error) {
 	path := api.url + "/" + indexName
@@ -43,6 +71,10 @@ func (api *API) CreateSearchIndex(ctx context.Context, indexName string, mapping
 		return 0, err
 	}
 
+	if indexMappings, err = api.adaptMappings(indexMappings); err != nil {
+		return 0, err
+	}
+
 	_, status, err := api.CallElastic(ctx, path, "PUT", indexMappings)
 	if err != nil {
 		return status, err
@@ -86,32 +118,6 @@ func (api *API) AddGeoLocation(ctx context.Context, indexName string, geoDoc *mo
 	return status, nil
 }
 
-// BulkRequest ...
-func (api *API) BulkRequest(ctx context.Context, indexName string, documents []interface{}) (int, error) {
-	path := api.url + "/_bulk"
-
-	var bulk []byte
-
-	for _, doc := range documents {
-
-		b, err := json.Marshal(doc)
-		if err != nil {
-			return 0, err
-		}
-
-		bulk = append(bulk, []byte("{ \"index\": {\"_index\": \""+indexName+"\", \"_type\": \"_doc\"} }\n")...) // It may need an ID?
-		bulk = append(bulk, b...)
-		bulk = append(bulk, []byte("\n")...)
-	}
-
-	_, status, err := api.CallElastic(ctx, path, "POST", bulk)
-	if err != nil {
-		return status, err
-	}
-
-	return status, nil
-}
-
 // SingleRequest ...
 func (api *API) SingleRequest(ctx context.Context, indexName string, document interface{}) (int, error) {
 	path := api.url + "/" + indexName + "/_doc"
@@ -261,13 +267,16 @@ func (api *API) QueryGeoLocation(ctx context.Context, indexName string, geoLocat
 		return nil, 0, errors.New("missing data")
 	}
 
-	if geoLocation.Type != "polygon" && geoLocation.Type != "multipolygon" {
+	if geoLocation.Type != "polygon" && geoLocation.Type != "multipolygon" && geoLocation.Type != "point" {
 		return nil, 0, errors.New("missing data")
 	}
 
 	path := api.url + "/" + indexName + "/_search"
 
-	query := buildGeoLocationQuery(*geoLocation, relation)
+	query := buildGeoLocationQuery(*geoLocation, limit, offset, relation)
+
+	// elasticsearch 7 stops counting hits at 10000 unless asked to count them all
+	query.TotalHits = api.typeless()
 
 	log.Event(ctx, "get documents based on geo polygon search", log.INFO, log.Data{"query": query, "path": path})
 
@@ -291,8 +300,74 @@ func (api *API) QueryGeoLocation(ctx context.Context, indexName string, geoLocat
 	return response, status, nil
 }
 
-// CallElastic builds a request to elastic search based on the method, path and payload
+// CallElastic builds a request to elastic search based on the method, path and payload.
+// Calls rejected with a 429 are retried with backoff, as are reads and other idempotent
+// calls rejected with a 502, 503 or 504 or that fail to connect. Writes made with POST are
+// not repeated in those cases as the first attempt may have been applied. Calls fail fast
+// with ErrCircuitOpen while elasticsearch is unavailable.
 func (api *API) CallElastic(ctx context.Context, path, method string, payload interface{}) ([]byte, int, error) {
+	start := time.Now()
+
+	ctx, span := tracing.Tracer().Start(ctx, "elasticsearch "+metrics.Operation(method, path), trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	jsonBody, status, err := api.callElasticWithRetries(ctx, path, method, payload)
+	metrics.ObserveElasticsearchCall(method, path, start, err)
+
+	if status > 0 {
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+	}
+	if err != nil {
+		span.RecordError(ctx, err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return jsonBody, status, err
+}
+
+func (api *API) callElasticWithRetries(ctx context.Context, path, method string, payload interface{}) ([]byte, int, error) {
+	logData := log.Data{"url": path, "method": method}
+
+	if err := api.breaker.Allow(); err != nil {
+		log.Event(ctx, "not calling elastic", log.ERROR, log.Error(err), logData)
+		return nil, 0, err
+	}
+
+	var (
+		jsonBody []byte
+		status   int
+		err      error
+	)
+
+	for retry := 0; ; retry++ {
+		jsonBody, status, err = api.callElastic(ctx, path, method, payload)
+		if retry >= api.retryPolicy.MaxRetries || !shouldRetry(ctx, method, path, status, err) {
+			break
+		}
+
+		delay := api.retryPolicy.backoff(retry)
+
+		logData["retry"] = retry + 1
+		logData["delay"] = delay.String()
+		logData["status_code"] = status
+		log.Event(ctx, "retrying call to elastic", log.WARN, log.Error(err), logData)
+
+		if !sleep(ctx, delay) {
+			break
+		}
+	}
+
+	switch {
+	case unavailable(status, err):
+		api.breaker.Failure()
+	case status > 0:
+		api.breaker.Success()
+	}
+
+	return jsonBody, status, err
+}
+
+func (api *API) callElastic(ctx context.Context, path, method string, payload interface{}) ([]byte, int, error) {
 	logData := log.Data{"url": path, "method": method}
 
 	URL, err := url.Parse(path)
@@ -318,6 +393,8 @@ func (api *API) CallElastic(ctx context.Context, path, method string, payload in
 		return nil, 0, err
 	}
 
+	tracing.InjectHeaders(ctx, req)
+
 	resp, err := api.clienter.Do(ctx, req)
 	if err != nil {
 		log.Event(ctx, "failed to call elastic", log.ERROR, log.Error(err), logData)
@@ -343,8 +420,10 @@ func (api *API) CallElastic(ctx context.Context, path, method string, payload in
 	return jsonBody, resp.StatusCode, nil
 }
 
-func buildGeoLocationQuery(geoLocation models.GeoLocation, relation string) models.GeoLocationRequest {
+func buildGeoLocationQuery(geoLocation models.GeoLocation, limit, offset int, relation string) models.GeoLocationRequest {
 	return models.GeoLocationRequest{
+		From: offset,
+		Size: limit,
 		Query: models.GeoLocationQuery{
 			Bool: models.BooleanObject{
 				Must: models.MustObject{
@@ -0,0 +1,87 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+)
+
+// List of health check messages
+const (
+	MsgHealthy         = "elasticsearch is healthy"
+	MsgClusterWarning  = "elasticsearch cluster health is yellow"
+	MsgClusterCritical = "elasticsearch cluster health is red"
+	MsgUnavailable     = "unable to connect to elasticsearch"
+)
+
+// ClusterHealth represents the response from the elasticsearch cluster health endpoint
+type ClusterHealth struct {
+	ClusterName string `json:"cluster_name"`
+	Status      string `json:"status"`
+}
+
+// IndexCount represents the response from the elasticsearch count endpoint
+type IndexCount struct {
+	Count int `json:"count"`
+}
+
+// Checker checks the health of the elasticsearch cluster and updates the provided CheckState accordingly
+func (api *API) Checker(ctx context.Context, state *health.CheckState) error {
+	body, status, err := api.CallElastic(ctx, api.url+"/_cluster/health", "GET", nil)
+	if err != nil {
+		state.Update(health.StatusCritical, MsgUnavailable, status)
+		return err
+	}
+
+	clusterHealth := &ClusterHealth{}
+	if err = json.Unmarshal(body, clusterHealth); err != nil {
+		state.Update(health.StatusCritical, err.Error(), status)
+		return err
+	}
+
+	switch clusterHealth.Status {
+	case "green":
+		state.Update(health.StatusOK, MsgHealthy, status)
+	case "yellow":
+		state.Update(health.StatusWarning, MsgClusterWarning, status)
+	default:
+		state.Update(health.StatusCritical, MsgClusterCritical, status)
+	}
+
+	return nil
+}
+
+// IndexChecker returns a checker for the existence and document count of an index. A missing
+// index is critical, whereas an empty index is a warning as the index is likely being (re)loaded.
+func (api *API) IndexChecker(indexName string) health.Checker {
+	return func(ctx context.Context, state *health.CheckState) error {
+		body, status, err := api.CallElastic(ctx, api.url+"/"+indexName+"/_count", "GET", nil)
+		if err != nil {
+			if status == http.StatusNotFound {
+				state.Update(health.StatusCritical, fmt.Sprintf("index %s does not exist", indexName), status)
+				return err
+			}
+
+			state.Update(health.StatusCritical, MsgUnavailable, status)
+			return err
+		}
+
+		indexCount := &IndexCount{}
+		if err = json.Unmarshal(body, indexCount); err != nil {
+			state.Update(health.StatusCritical, err.Error(), status)
+			return err
+		}
+
+		if indexCount.Count < 1 {
+			state.Update(health.StatusWarning, fmt.Sprintf("index %s contains no documents", indexName), status)
+			return nil
+		}
+
+		state.Update(health.StatusOK, fmt.Sprintf("index %s contains %d documents", indexName, indexCount.Count), status)
+
+		return nil
+	}
+}
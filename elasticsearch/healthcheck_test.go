@@ -0,0 +1,122 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newHealthElasticsearch returns a server responding to every request with status and body
+func newHealthElasticsearch(status int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestChecker(t *testing.T) {
+	ctx := context.Background()
+
+	clusterStates := []struct {
+		health  string
+		status  string
+		message string
+	}{
+		{"green", health.StatusOK, es.MsgHealthy},
+		{"yellow", health.StatusWarning, es.MsgClusterWarning},
+		{"red", health.StatusCritical, es.MsgClusterCritical},
+	}
+
+	for _, cluster := range clusterStates {
+		cluster := cluster
+
+		Convey("Given the cluster health is "+cluster.health, t, func() {
+			server := newHealthElasticsearch(http.StatusOK, `{"cluster_name":"search","status":"`+cluster.health+`"}`)
+			defer server.Close()
+
+			state := health.NewCheckState("elasticsearch")
+
+			Convey("Then the check is "+cluster.status, func() {
+				So(newTestAPI(server.URL, nil).Checker(ctx, state), ShouldBeNil)
+				So(state.Status(), ShouldEqual, cluster.status)
+				So(state.Message(), ShouldEqual, cluster.message)
+				So(state.StatusCode(), ShouldEqual, http.StatusOK)
+			})
+		})
+	}
+
+	Convey("Given the cluster cannot be reached", t, func() {
+		server := newHealthElasticsearch(http.StatusOK, "")
+		server.Close()
+
+		state := health.NewCheckState("elasticsearch")
+
+		Convey("Then the check is critical", func() {
+			So(newTestAPI(server.URL, nil).Checker(ctx, state), ShouldNotBeNil)
+			So(state.Status(), ShouldEqual, health.StatusCritical)
+			So(state.Message(), ShouldEqual, es.MsgUnavailable)
+		})
+	})
+}
+
+func TestIndexChecker(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given an index holding documents", t, func() {
+		server := newHealthElasticsearch(http.StatusOK, `{"count":42}`)
+		defer server.Close()
+
+		state := health.NewCheckState("test_geo")
+
+		Convey("Then the check is ok", func() {
+			So(newTestAPI(server.URL, nil).IndexChecker("test_geo")(ctx, state), ShouldBeNil)
+			So(state.Status(), ShouldEqual, health.StatusOK)
+			So(state.Message(), ShouldEqual, "index test_geo contains 42 documents")
+		})
+	})
+
+	Convey("Given an empty index", t, func() {
+		server := newHealthElasticsearch(http.StatusOK, `{"count":0}`)
+		defer server.Close()
+
+		state := health.NewCheckState("test_geo")
+
+		Convey("Then the check is a warning", func() {
+			So(newTestAPI(server.URL, nil).IndexChecker("test_geo")(ctx, state), ShouldBeNil)
+			So(state.Status(), ShouldEqual, health.StatusWarning)
+			So(state.Message(), ShouldEqual, "index test_geo contains no documents")
+		})
+	})
+
+	Convey("Given an index that does not exist", t, func() {
+		server := newHealthElasticsearch(http.StatusNotFound, `{"error":{"type":"index_not_found_exception"},"status":404}`)
+		defer server.Close()
+
+		state := health.NewCheckState("test_geo")
+
+		Convey("Then the check is critical", func() {
+			So(newTestAPI(server.URL, nil).IndexChecker("test_geo")(ctx, state), ShouldNotBeNil)
+			So(state.Status(), ShouldEqual, health.StatusCritical)
+			So(state.Message(), ShouldEqual, "index test_geo does not exist")
+			So(state.StatusCode(), ShouldEqual, http.StatusNotFound)
+		})
+	})
+
+	Convey("Given the cluster cannot be reached", t, func() {
+		server := newHealthElasticsearch(http.StatusOK, "")
+		server.Close()
+
+		state := health.NewCheckState("test_geo")
+
+		Convey("Then the check is critical", func() {
+			So(newTestAPI(server.URL, nil).IndexChecker("test_geo")(ctx, state), ShouldNotBeNil)
+			So(state.Status(), ShouldEqual, health.StatusCritical)
+			So(state.Message(), ShouldEqual, es.MsgUnavailable)
+		})
+	})
+}
@@ -0,0 +1,305 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+)
+
+// RecordEnv is the environment variable that switches recorders to record mode, e.g.
+// `ES_RECORD=true go test ./...` re-records fixtures against ELASTIC_SEARCH_URL
+const RecordEnv = "ES_RECORD"
+
+// fixturePrecision is the number of decimal places numbers in request bodies are rounded
+// to when matching, so shapes calculated with floating point still match on replay
+const fixturePrecision = 1e7
+
+// ErrNoFixture is returned in replay mode for a request that was not recorded
+var ErrNoFixture = errors.New("no recorded fixture matches request")
+
+// RecordMode selects whether a Recorder calls elasticsearch or replays its fixtures
+type RecordMode int
+
+// The modes a Recorder can run in
+const (
+	Replay RecordMode = iota
+	Record
+)
+
+// RecordModeFromEnv returns Record when the ES_RECORD environment variable is set
+func RecordModeFromEnv() RecordMode {
+	if os.Getenv(RecordEnv) != "" {
+		return Record
+	}
+
+	return Replay
+}
+
+// Fixture is a recorded request to elasticsearch and its response
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest holds the parts of a request that are matched on replay. Json bodies are
+// held normalised in Body, any other body, such as a bulk request, in Text.
+type FixtureRequest struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// FixtureResponse is the response replayed for a matching request
+type FixtureResponse struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// Recorder wraps a Clienter so that calls to elasticsearch can be recorded to a fixture
+// file and replayed from it offline. In record mode requests are sent using the wrapped
+// client and saved along with their responses; in replay mode the response recorded for
+// a request with the same method, path and normalised body is returned instead. Requests
+// made more than once are replayed in the order they were recorded, with the last
+// response repeated.
+type Recorder struct {
+	dphttp.Clienter
+	file     string
+	mode     RecordMode
+	mu       sync.Mutex
+	fixtures []Fixture
+	replayed []bool
+}
+
+// NewRecorder creates a Recorder for a fixture file, loading the file in replay mode
+func NewRecorder(clienter dphttp.Clienter, file string, mode RecordMode) (*Recorder, error) {
+	r := &Recorder{
+		Clienter: clienter,
+		file:     file,
+		mode:     mode,
+	}
+
+	if mode == Record {
+		return r, nil
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(b, &r.fixtures); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	// fixtures may have been edited by hand, so are normalised the same way as requests,
+	// and responses are compacted back to the form elasticsearch sends them in
+	for i, fixture := range r.fixtures {
+		if normalised, ok := normaliseJSON(fixture.Request.Body); ok {
+			r.fixtures[i].Request.Body = normalised
+		}
+
+		var body bytes.Buffer
+		if err = json.Compact(&body, fixture.Response.Body); err == nil {
+			r.fixtures[i].Response.Body = body.Bytes()
+		}
+	}
+
+	r.replayed = make([]bool, len(r.fixtures))
+
+	return r, nil
+}
+
+// Do records or replays a request depending on the mode of the recorder
+func (r *Recorder) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var body []byte
+
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		body = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	request := FixtureRequest{Method: req.Method, Path: fixturePath(req)}
+	request.Body, request.Text = normaliseBody(body)
+
+	if r.mode == Record {
+		return r.record(ctx, req, request)
+	}
+
+	return r.replay(req, request)
+}
+
+func (r *Recorder) record(ctx context.Context, req *http.Request, request FixtureRequest) (*http.Response, error) {
+	resp, err := r.Clienter.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := FixtureResponse{Status: resp.StatusCode}
+	if json.Valid(b) {
+		response.Body = b
+	} else {
+		response.Text = string(b)
+	}
+
+	r.mu.Lock()
+	r.fixtures = append(r.fixtures, Fixture{Request: request, Response: response})
+	r.mu.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, request FixtureRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := -1
+	for i, fixture := range r.fixtures {
+		if !fixture.Request.matches(request) {
+			continue
+		}
+
+		match = i
+		if !r.replayed[i] {
+			break
+		}
+	}
+
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s %s%s", ErrNoFixture, request.Method, request.Path, request.Body, request.Text)
+	}
+
+	r.replayed[match] = true
+	response := r.fixtures[match].Response
+
+	body := []byte(response.Text)
+	if len(response.Body) > 0 {
+		body = response.Body
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		StatusCode:    response.Status,
+		Header:        http.Header{"Content-Type": []string{"application/json; charset=UTF-8"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes the fixtures recorded so far to the fixture file. It does nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != Record {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := json.MarshalIndent(r.fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(r.file), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.file, append(b, '\n'), 0644)
+}
+
+func (f FixtureRequest) matches(o FixtureRequest) bool {
+	return f.Method == o.Method && f.Path == o.Path && f.Text == o.Text && bytes.Equal(f.Body, o.Body)
+}
+
+// fixturePath returns the path and sorted query of a request, leaving out the host so
+// fixtures recorded against one cluster replay for any url
+func fixturePath(req *http.Request) string {
+	path := req.URL.Path
+	if query := req.URL.Query(); len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return path
+}
+
+// normaliseBody returns a json body with its keys sorted and numbers rounded, or any
+// other body, such as the newline delimited json of a bulk request, as text with each
+// json line normalised
+func normaliseBody(body []byte) (json.RawMessage, string) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, ""
+	}
+
+	if normalised, ok := normaliseJSON(body); ok {
+		return normalised, ""
+	}
+
+	lines := strings.Split(strings.TrimRight(string(body), "\n"), "\n")
+	for i, line := range lines {
+		if normalised, ok := normaliseJSON([]byte(line)); ok {
+			lines[i] = string(normalised)
+		}
+	}
+
+	return nil, strings.Join(lines, "\n") + "\n"
+}
+
+func normaliseJSON(b []byte) (json.RawMessage, bool) {
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, false
+	}
+
+	normalised, err := json.Marshal(roundNumbers(value))
+	if err != nil {
+		return nil, false
+	}
+
+	return normalised, true
+}
+
+func roundNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return math.Round(v*fixturePrecision) / fixturePrecision
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = roundNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = roundNumbers(v[key])
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,115 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newFixtureAPI returns an API replaying the named fixture file from testdata, or
+// recording it against ELASTIC_SEARCH_URL when ES_RECORD is set. The recorder must be
+// saved once the test has finished to write a recording.
+func newFixtureAPI(t *testing.T, name string) (*es.API, *es.Recorder) {
+	cli, _ := es.NewClient(false, "")
+
+	recorder, err := es.NewRecorder(cli, filepath.Join("testdata", name+".json"), es.RecordModeFromEnv())
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+
+	url := os.Getenv("ELASTIC_SEARCH_URL")
+	if url == "" {
+		url = "http://localhost:9200"
+	}
+
+	esAPI := es.NewElasticSearchAPI(recorder, url)
+	esAPI.SetRetryPolicy(testRetryPolicy)
+
+	return esAPI, recorder
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "fixtures.json")
+
+	Convey("Given a recorder in record mode", t, func() {
+		var calls int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if r.URL.Path == "/missing/_search" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"type":"index_not_found_exception"},"status":404}`))
+				return
+			}
+
+			total := "0"
+			if body, _ := ioutil.ReadAll(r.Body); strings.Contains(string(body), "np108xg") {
+				total = "1"
+			}
+			w.Write([]byte(`{"hits":{"total":` + total + `,"hits":[]}}`))
+		}))
+		defer server.Close()
+
+		cli, _ := es.NewClient(false, "")
+		recorder, err := es.NewRecorder(cli, file, es.Record)
+		So(err, ShouldBeNil)
+
+		esAPI := es.NewElasticSearchAPI(recorder, server.URL)
+
+		_, _, err = esAPI.CallElastic(ctx, server.URL+"/test/_search?size=1&from=0", "GET", []byte(`{"query":{"term":{"postcode":"np108xg"}},"size":1}`))
+		So(err, ShouldBeNil)
+		_, _, err = esAPI.CallElastic(ctx, server.URL+"/test/_search?size=1&from=0", "GET", []byte(`{"query":{"term":{"postcode":"np109xx"}},"size":1}`))
+		So(err, ShouldBeNil)
+		_, status, _ := esAPI.CallElastic(ctx, server.URL+"/missing/_search", "GET", nil)
+		So(status, ShouldEqual, http.StatusNotFound)
+
+		So(recorder.Save(), ShouldBeNil)
+		So(calls, ShouldEqual, 3)
+
+		Convey("When the fixtures are replayed without elasticsearch", func() {
+			server.Close()
+
+			recorder, err := es.NewRecorder(cli, file, es.Replay)
+			So(err, ShouldBeNil)
+
+			esAPI := es.NewElasticSearchAPI(recorder, "http://elasticsearch:9200")
+
+			Convey("Then a request with the same body in a different order gets its recorded response", func() {
+				body, status, err := esAPI.CallElastic(ctx, "http://elasticsearch:9200/test/_search?from=0&size=1", "GET", []byte(`{"size":1,"query":{"term":{"postcode":"np108xg"}}}`))
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(string(body), ShouldContainSubstring, `"total":1`)
+
+				body, _, err = esAPI.CallElastic(ctx, "http://elasticsearch:9200/test/_search?from=0&size=1", "GET", []byte(`{"size":1,"query":{"term":{"postcode":"np109xx"}}}`))
+				So(err, ShouldBeNil)
+				So(string(body), ShouldContainSubstring, `"total":0`)
+			})
+
+			Convey("Then a recorded error status is replayed", func() {
+				_, status, err := esAPI.CallElastic(ctx, "http://elasticsearch:9200/missing/_search", "GET", nil)
+				So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+				So(status, ShouldEqual, http.StatusNotFound)
+			})
+
+			Convey("Then a request that was not recorded fails", func() {
+				_, _, err := esAPI.CallElastic(ctx, "http://elasticsearch:9200/test/_search", "GET", []byte(`{"query":{"term":{"postcode":"e11aa"}}}`))
+				So(errors.Is(err, es.ErrNoFixture), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+)
+
+// RetryPolicy controls how failed calls to elasticsearch are retried. The delay before
+// each retry is chosen at random up to an exponentially increasing cap, so that many
+// clients retrying at once do not hit elasticsearch together.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy is used by an API unless another policy is set
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
+
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// backoff returns the delay before the given retry, starting at zero
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	ceiling := p.MaxBackoff
+	if retry < 32 {
+		if d := p.InitialBackoff << uint(retry); d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// readOnlyAPIs are called with POST but only read, so can be repeated safely
+var readOnlyAPIs = map[string]bool{
+	"_count":   true,
+	"_msearch": true,
+	"_refresh": true,
+	"_search":  true,
+}
+
+// shouldRetry reports whether a call failed in a way that may succeed if repeated. A call
+// that may have reached elasticsearch is only repeated if doing so cannot write twice; a
+// 429 means elasticsearch refused the call without acting on it so is always retried.
+func shouldRetry(ctx context.Context, method, uri string, status int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+
+	if !idempotent(method, uri) {
+		return false
+	}
+
+	if retryableStatus[status] {
+		return true
+	}
+
+	return status == 0 && isConnectionError(err)
+}
+
+// idempotent reports whether making a call more than once has the same effect as making
+// it once. POST creates documents with generated ids and runs bulk requests, so is only
+// idempotent for the apis that read.
+func idempotent(method, uri string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	case "POST":
+		u, err := url.Parse(uri)
+		return err == nil && readOnlyAPIs[path.Base(u.Path)]
+	}
+
+	return false
+}
+
+// unavailable reports whether a call failed because elasticsearch could not serve it
+func unavailable(status int, err error) bool {
+	if status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout {
+		return true
+	}
+
+	return status == 0 && isConnectionError(err)
+}
+
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Op == "parse" {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// sleep waits for the delay, returning false if the context is done first or its
+// deadline would pass before the delay is over
+func sleep(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
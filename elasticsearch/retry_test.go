@@ -0,0 +1,194 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var testRetryPolicy = es.RetryPolicy{
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+// newFlakyElasticsearch returns a server responding with the given status codes in
+// turn, then 200 for every request after them
+func newFlakyElasticsearch(calls *int32, statuses ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(calls, 1))
+		if call <= len(statuses) {
+			w.WriteHeader(statuses[call-1])
+			return
+		}
+
+		w.Write([]byte(`{"status":"green"}`))
+	}))
+}
+
+func newTestAPI(url string, breaker *es.CircuitBreaker) *es.API {
+	cli, _ := es.NewClient(false, "")
+
+	esAPI := es.NewElasticSearchAPI(cli, url)
+	esAPI.SetRetryPolicy(testRetryPolicy)
+	esAPI.SetCircuitBreaker(breaker)
+
+	return esAPI
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given elasticsearch responds with transient errors", t, func() {
+		var calls int32
+		server := newFlakyElasticsearch(&calls, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+		defer server.Close()
+
+		Convey("Then the call is retried until it succeeds", func() {
+			body, status, err := newTestAPI(server.URL, nil).CallElastic(ctx, server.URL+"/_cluster/health", "GET", nil)
+			So(err, ShouldBeNil)
+			So(status, ShouldEqual, http.StatusOK)
+			So(string(body), ShouldEqual, `{"status":"green"}`)
+			So(calls, ShouldEqual, 3)
+		})
+	})
+
+	Convey("Given elasticsearch keeps responding with transient errors", t, func() {
+		var calls int32
+		server := newFlakyElasticsearch(&calls, 503, 503, 503, 503, 503)
+		defer server.Close()
+
+		Convey("Then the call gives up after the maximum number of retries", func() {
+			_, status, err := newTestAPI(server.URL, nil).CallElastic(ctx, server.URL+"/_cluster/health", "GET", nil)
+			So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+			So(status, ShouldEqual, http.StatusServiceUnavailable)
+			So(calls, ShouldEqual, 4)
+		})
+	})
+
+	Convey("Given elasticsearch rejects the request", t, func() {
+		var calls int32
+		server := newFlakyElasticsearch(&calls, http.StatusBadRequest)
+		defer server.Close()
+
+		Convey("Then the call is not retried", func() {
+			_, status, err := newTestAPI(server.URL, nil).CallElastic(ctx, server.URL+"/test/_search", "POST", []byte(`{}`))
+			So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+			So(status, ShouldEqual, http.StatusBadRequest)
+			So(calls, ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given elasticsearch responds with a bad gateway to a search", t, func() {
+		var calls int32
+		server := newFlakyElasticsearch(&calls, http.StatusBadGateway)
+		defer server.Close()
+
+		Convey("Then the search is retried", func() {
+			_, status, err := newTestAPI(server.URL, nil).CallElastic(ctx, server.URL+"/test/_search", "POST", []byte(`{}`))
+			So(err, ShouldBeNil)
+			So(status, ShouldEqual, http.StatusOK)
+			So(calls, ShouldEqual, 2)
+		})
+	})
+
+	Convey("Given elasticsearch responds with a gateway timeout to a write", t, func() {
+		var calls int32
+		server := newFlakyElasticsearch(&calls, http.StatusGatewayTimeout)
+		defer server.Close()
+
+		Convey("Then the write is not retried in case it was applied", func() {
+			_, status, err := newTestAPI(server.URL, nil).CallElastic(ctx, server.URL+"/test/_doc", "POST", []byte(`{}`))
+			So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+			So(status, ShouldEqual, http.StatusGatewayTimeout)
+			So(calls, ShouldEqual, 1)
+		})
+	})
+
+	Convey("Given elasticsearch responds with too many requests to a write", t, func() {
+		var calls int32
+		server := newFlakyElasticsearch(&calls, http.StatusTooManyRequests)
+		defer server.Close()
+
+		Convey("Then the write is retried", func() {
+			_, status, err := newTestAPI(server.URL, nil).CallElastic(ctx, server.URL+"/_bulk", "POST", []byte(`{}`))
+			So(err, ShouldBeNil)
+			So(status, ShouldEqual, http.StatusOK)
+			So(calls, ShouldEqual, 2)
+		})
+	})
+
+	Convey("Given the context deadline is shorter than the backoff", t, func() {
+		var calls int32
+		server := newFlakyElasticsearch(&calls, 503, 503, 503, 503)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+		esAPI.SetRetryPolicy(es.RetryPolicy{MaxRetries: 3, InitialBackoff: time.Minute, MaxBackoff: time.Minute})
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		Convey("Then the call returns without waiting", func() {
+			start := time.Now()
+			_, status, _ := esAPI.CallElastic(timeoutCtx, server.URL+"/_cluster/health", "GET", nil)
+			So(status, ShouldEqual, http.StatusServiceUnavailable)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+		})
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given elasticsearch cannot be reached", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		url := server.URL
+		server.Close()
+
+		esAPI := newTestAPI(url, es.NewCircuitBreaker(2, time.Hour))
+		esAPI.SetRetryPolicy(es.RetryPolicy{})
+
+		Convey("Then calls fail fast once the failure threshold is reached", func() {
+			_, _, err := esAPI.CallElastic(ctx, url+"/_cluster/health", "GET", nil)
+			So(err, ShouldNotBeNil)
+			So(errors.Is(err, es.ErrCircuitOpen), ShouldBeFalse)
+
+			_, _, err = esAPI.CallElastic(ctx, url+"/_cluster/health", "GET", nil)
+			So(errors.Is(err, es.ErrCircuitOpen), ShouldBeFalse)
+
+			_, _, err = esAPI.CallElastic(ctx, url+"/_cluster/health", "GET", nil)
+			So(errors.Is(err, es.ErrCircuitOpen), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given an open circuit breaker", t, func() {
+		breaker := es.NewCircuitBreaker(1, 10*time.Millisecond)
+		breaker.Failure()
+		So(errors.Is(breaker.Allow(), es.ErrCircuitOpen), ShouldBeTrue)
+
+		Convey("Then a single trial call is allowed after the timeout", func() {
+			time.Sleep(20 * time.Millisecond)
+			So(breaker.Allow(), ShouldBeNil)
+			So(errors.Is(breaker.Allow(), es.ErrCircuitOpen), ShouldBeTrue)
+
+			Convey("And the breaker closes when it succeeds", func() {
+				breaker.Success()
+				So(breaker.Allow(), ShouldBeNil)
+				So(breaker.Allow(), ShouldBeNil)
+			})
+
+			Convey("And the breaker opens again when it fails", func() {
+				breaker.Failure()
+				So(errors.Is(breaker.Allow(), es.ErrCircuitOpen), ShouldBeTrue)
+			})
+		})
+	})
+}
@@ -0,0 +1,151 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// The searches below replay fixtures recorded against a cluster loaded by the scripts in
+// this repository. Re-record them with `ES_RECORD=true go test ./elasticsearch`.
+
+// cardiff is a polygon over central Cardiff
+var cardiff = &models.GeoLocation{
+	Type:        "polygon",
+	Coordinates: [][][]float64{{{-3.19, 51.47}, {-3.15, 51.47}, {-3.15, 51.49}, {-3.19, 51.49}, {-3.19, 51.47}}},
+}
+
+func TestGetPostcodes(t *testing.T) {
+	ctx := context.Background()
+
+	esAPI, recorder := newFixtureAPI(t, "get_postcodes")
+	defer recorder.Save()
+
+	Convey("Given a postcode index", t, func() {
+		Convey("When a postcode in the index is requested", func() {
+			response, status, err := esAPI.GetPostcodes(ctx, "test_postcode", "cf244ny")
+
+			Convey("Then its location is returned", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(response.Hits.Hits, ShouldHaveLength, 1)
+				So(response.Hits.Hits[0].Source.Postcode, ShouldEqual, "cf244ny")
+				So(response.Hits.Hits[0].Source.RawPostcode, ShouldEqual, "CF24 4NY")
+				So(response.Hits.Hits[0].Source.Pin.Location.Lat, ShouldAlmostEqual, 51.4874, 0.001)
+				So(response.Hits.Hits[0].Source.Pin.Location.Lon, ShouldAlmostEqual, -3.1589, 0.001)
+			})
+		})
+
+		Convey("When a postcode that is not in the index is requested", func() {
+			response, status, err := esAPI.GetPostcodes(ctx, "test_postcode", "zz999zz")
+
+			Convey("Then no hits are returned", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(response.Hits.Hits, ShouldBeEmpty)
+			})
+		})
+
+		Convey("When the index does not exist", func() {
+			response, status, err := esAPI.GetPostcodes(ctx, "missing_index", "cf244ny")
+
+			Convey("Then the not found status is returned with an error", func() {
+				So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+				So(status, ShouldEqual, http.StatusNotFound)
+				So(response, ShouldBeNil)
+			})
+		})
+	})
+}
+
+func TestQueryGeoLocation(t *testing.T) {
+	ctx := context.Background()
+
+	esAPI, recorder := newFixtureAPI(t, "query_geo_location")
+	defer recorder.Save()
+
+	Convey("Given an index of geographical areas", t, func() {
+		Convey("When searching for areas within a polygon", func() {
+			response, status, err := esAPI.QueryGeoLocation(ctx, "test_geo", cardiff, 2, 0, "within")
+
+			Convey("Then the first page of areas is returned with the total", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(int(response.Hits.Total), ShouldEqual, 3)
+				So(response.Hits.HitList, ShouldHaveLength, 2)
+				So(response.Hits.HitList[0].Source.Code, ShouldEqual, "W01001689")
+				So(response.Hits.HitList[0].Source.Hierarchy, ShouldEqual, "lsoa")
+				So(response.Hits.HitList[0].Source.LSOA11NM, ShouldEqual, "Cardiff 032A")
+			})
+		})
+
+		Convey("When searching for areas intersecting a point", func() {
+			point := &models.GeoLocation{Type: "point", Coordinates: []float64{-3.1589, 51.4874}}
+			response, status, err := esAPI.QueryGeoLocation(ctx, "test_geo", point, 10, 0, "intersects")
+
+			Convey("Then the area containing the point is returned", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(int(response.Hits.Total), ShouldEqual, 1)
+				So(response.Hits.HitList[0].Source.Code, ShouldEqual, "W01001690")
+			})
+		})
+
+		Convey("When searching with an unsupported shape", func() {
+			line := &models.GeoLocation{Type: "linestring", Coordinates: [][]float64{{-3.19, 51.47}, {-3.15, 51.49}}}
+			_, _, err := esAPI.QueryGeoLocation(ctx, "test_geo", line, 10, 0, "intersects")
+
+			Convey("Then an error is returned without calling elasticsearch", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestGetBoundaryFiles(t *testing.T) {
+	ctx := context.Background()
+
+	esAPI, recorder := newFixtureAPI(t, "get_boundary_files")
+	defer recorder.Save()
+
+	query := models.Body{
+		From: 0,
+		Size: 2,
+		Query: models.Query{
+			Bool: models.Bool{Should: []models.Match{{Match: map[string]string{"name": "bradford"}}}},
+		},
+		Sort:      []models.Scores{{Score: models.Score{Order: "desc"}}},
+		TotalHits: true,
+	}
+
+	Convey("Given an index of geographical areas", t, func() {
+		Convey("When searching by name", func() {
+			response, status, err := esAPI.GetBoundaryFiles(ctx, "test_geo", query)
+
+			Convey("Then the best matches are returned first with their location", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(int(response.Hits.Total), ShouldEqual, 5)
+				So(response.Hits.HitList, ShouldHaveLength, 2)
+				So(response.Hits.HitList[0].Source.Name, ShouldEqual, "Bradford")
+				So(response.Hits.HitList[0].Source.Hierarchy, ShouldEqual, "tcity")
+				So(response.Hits.HitList[0].Source.Location.Type, ShouldEqual, "polygon")
+				So(response.Hits.HitList[0].Score, ShouldBeGreaterThan, response.Hits.HitList[1].Score)
+			})
+		})
+
+		Convey("When the index does not exist", func() {
+			_, status, err := esAPI.GetBoundaryFiles(ctx, "missing_index", query)
+
+			Convey("Then index not found is returned", func() {
+				So(err, ShouldEqual, errs.ErrIndexNotFound)
+				So(status, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
@@ -0,0 +1,90 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+// awsService is the name of the AWS Elasticsearch Service used when signing requests
+const awsService = "es"
+
+// SigningClient wraps a Clienter, signing every request with AWS signature version 4 before it is sent
+type SigningClient struct {
+	dphttp.Clienter
+	signer  *v4.Signer
+	region  string
+	service string
+}
+
+// NewClient returns a client for calling elasticsearch, which does not retry failed requests.
+// If signRequests is set, requests are signed using credentials from the standard AWS
+// environment and shared config chain.
+func NewClient(signRequests bool, region string) (dphttp.Clienter, error) {
+	cli := dphttp.NewClient()
+
+	// retries are made by the API so they can follow its retry policy and circuit breaker
+	cli.SetMaxRetries(0)
+	if !signRequests {
+		return cli, nil
+	}
+
+	return NewSigningClient(cli, region)
+}
+
+// NewSigningClient creates a SigningClient which retrieves credentials from the environment,
+// the shared config and credentials files or an attached role, in that order
+func NewSigningClient(clienter dphttp.Clienter, region string) (*SigningClient, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigningClientWithCredentials(clienter, sess.Config.Credentials, region), nil
+}
+
+// NewSigningClientWithCredentials creates a SigningClient which signs requests with the given credentials
+func NewSigningClientWithCredentials(clienter dphttp.Clienter, creds *credentials.Credentials, region string) *SigningClient {
+	return &SigningClient{
+		Clienter: clienter,
+		signer:   v4.NewSigner(creds),
+		region:   region,
+		service:  awsService,
+	}
+}
+
+// Do signs the request and then sends it using the wrapped client
+func (c *SigningClient) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	var body io.ReadSeeker
+
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		body = bytes.NewReader(b)
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+	}
+
+	if _, err := c.signer.Sign(req, body, c.service, c.region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return c.Clienter.Do(ctx, req)
+}
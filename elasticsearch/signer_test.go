@@ -0,0 +1,118 @@
+package elasticsearch_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testRegion = "eu-west-1"
+
+var stubCredentials = credentials.NewStaticCredentials("AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "")
+
+// newStubElasticsearch returns a server that only accepts requests carrying a valid signature
+func newStubElasticsearch() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validSignature(r) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Write([]byte(`{"status":"green"}`))
+	}))
+}
+
+// validSignature re-signs the received request with the stub credentials and compares the result
+func validSignature(r *http.Request) bool {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" {
+		return false
+	}
+
+	signingTime, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return false
+	}
+
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	if err != nil {
+		return false
+	}
+
+	signedHeaders := authorization[strings.Index(authorization, "SignedHeaders=")+len("SignedHeaders="):]
+	signedHeaders = signedHeaders[:strings.Index(signedHeaders, ",")]
+	for _, header := range strings.Split(signedHeaders, ";") {
+		if header != "host" {
+			req.Header.Set(header, r.Header.Get(header))
+		}
+	}
+
+	if _, err = v4.NewSigner(stubCredentials).Sign(req, bytes.NewReader(body), "es", testRegion, signingTime); err != nil {
+		return false
+	}
+
+	return req.Header.Get("Authorization") == authorization
+}
+
+func TestSigningClient(t *testing.T) {
+	ctx := context.Background()
+	server := newStubElasticsearch()
+	defer server.Close()
+
+	Convey("Given requests are signed with valid credentials", t, func() {
+		cli := es.NewSigningClientWithCredentials(dphttp.NewClient(), stubCredentials, testRegion)
+		esAPI := es.NewElasticSearchAPI(cli, server.URL)
+
+		Convey("When a request without a body is sent", func() {
+			_, status, err := esAPI.CallElastic(ctx, server.URL+"/_cluster/health", "GET", nil)
+
+			Convey("Then the stub verifies the signature", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+			})
+		})
+
+		Convey("When a request with a body is sent", func() {
+			_, status, err := esAPI.CallElastic(ctx, server.URL+"/test_geo/_search", "POST", []byte(`{"query":{"match_all":{}}}`))
+
+			Convey("Then the stub verifies the signature", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+
+	Convey("Given requests are signed with the wrong credentials", t, func() {
+		wrongCredentials := credentials.NewStaticCredentials("AKIDEXAMPLE", "incorrect", "")
+		cli := es.NewSigningClientWithCredentials(dphttp.NewClient(), wrongCredentials, testRegion)
+		esAPI := es.NewElasticSearchAPI(cli, server.URL)
+
+		_, status, err := esAPI.CallElastic(ctx, server.URL+"/test_geo/_search", "POST", []byte(`{"query":{"match_all":{}}}`))
+		So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+		So(status, ShouldEqual, http.StatusForbidden)
+	})
+
+	Convey("Given requests are not signed", t, func() {
+		esAPI := es.NewElasticSearchAPI(dphttp.NewClient(), server.URL)
+
+		_, status, err := esAPI.CallElastic(ctx, server.URL+"/_cluster/health", "GET", nil)
+		So(err, ShouldEqual, es.ErrorUnexpectedStatusCode)
+		So(status, ShouldEqual, http.StatusForbidden)
+	})
+}
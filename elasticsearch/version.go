@@ -0,0 +1,108 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/log.go/log"
+)
+
+// ErrUnsupportedVersion is returned when the cluster runs a version of elasticsearch older than 6
+var ErrUnsupportedVersion = errors.New("unsupported elasticsearch version")
+
+const (
+	minimumMajorVersion  = 6
+	typelessMajorVersion = 7
+)
+
+type clusterInfo struct {
+	Version struct {
+		Number string `json:"number"`
+	} `json:"version"`
+}
+
+// DetectVersion asks the cluster for its version so that index mappings and bulk requests
+// are sent in the form it expects. Until it is called requests are sent in the form
+// elasticsearch 6 expects.
+func (api *API) DetectVersion(ctx context.Context) (string, error) {
+	body, _, err := api.CallElastic(ctx, api.url, "GET", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var info clusterInfo
+	if err = json.Unmarshal(body, &info); err != nil {
+		return "", err
+	}
+
+	logData := log.Data{"version": info.Version.Number}
+
+	major, err := strconv.Atoi(strings.SplitN(info.Version.Number, ".", 2)[0])
+	if err != nil {
+		log.Event(ctx, "failed to parse elasticsearch version", log.ERROR, log.Error(err), logData)
+		return info.Version.Number, err
+	}
+
+	if major < minimumMajorVersion {
+		log.Event(ctx, "elasticsearch version is not supported", log.ERROR, log.Error(ErrUnsupportedVersion), logData)
+		return info.Version.Number, ErrUnsupportedVersion
+	}
+
+	api.majorVersion = major
+
+	log.Event(ctx, "detected elasticsearch version", log.INFO, logData)
+
+	return info.Version.Number, nil
+}
+
+// typeless reports whether the cluster has dropped mapping types
+func (api *API) typeless() bool {
+	return api.majorVersion >= typelessMajorVersion
+}
+
+// bulkAction returns the action line preceding each document in a bulk request. When id is
+// set the document is indexed with it, so sending the document again replaces it.
+func (api *API) bulkAction(indexName, id string) []byte {
+	action := "{ \"index\": {\"_index\": \"" + indexName + "\""
+	if !api.typeless() {
+		action += ", \"_type\": \"_doc\""
+	}
+
+	if id != "" {
+		b, _ := json.Marshal(id)
+		action += ", \"_id\": " + string(b)
+	}
+
+	return []byte(action + "} }\n")
+}
+
+// adaptMappings removes the mapping type the embedded mapping files nest their fields
+// under when the cluster no longer supports mapping types
+func (api *API) adaptMappings(indexMappings []byte) ([]byte, error) {
+	if !api.typeless() {
+		return indexMappings, nil
+	}
+
+	var index map[string]json.RawMessage
+	if err := json.Unmarshal(indexMappings, &index); err != nil {
+		return nil, err
+	}
+
+	var mappings map[string]json.RawMessage
+	if err := json.Unmarshal(index["mappings"], &mappings); err != nil || len(mappings) != 1 {
+		return indexMappings, nil
+	}
+
+	for name, mapping := range mappings {
+		if name == "properties" {
+			return indexMappings, nil
+		}
+
+		index["mappings"] = mapping
+	}
+
+	return json.Marshal(index)
+}
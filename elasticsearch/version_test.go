@@ -0,0 +1,126 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newVersionedElasticsearch returns a server reporting the given version and recording
+// the body of every other request by path
+func newVersionedElasticsearch(version string, bodies map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`{"name":"node-1","version":{"number":"` + version + `"}}`))
+		case "/_bulk":
+			bodies[r.URL.Path] = string(body)
+			w.Write([]byte(`{"took":1,"errors":false,"items":[{"index":{"status":201}}]}`))
+		default:
+			bodies[r.URL.Path] = string(body)
+			w.Write([]byte(`{"acknowledged":true}`))
+		}
+	}))
+}
+
+func TestDetectVersion(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given an elasticsearch 6 cluster", t, func() {
+		bodies := make(map[string]string)
+		server := newVersionedElasticsearch("6.8.13", bodies)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+
+		version, err := esAPI.DetectVersion(ctx)
+		So(err, ShouldBeNil)
+		So(version, ShouldEqual, "6.8.13")
+
+		Convey("Then mappings keep their mapping type", func() {
+			_, err := esAPI.CreateSearchIndex(ctx, "test_postcode", "postcode-mappings.json")
+			So(err, ShouldBeNil)
+
+			mappings := decodeMappings(bodies["/test_postcode"])
+			So(mappings, ShouldContainKey, "doc")
+			So(mappings, ShouldNotContainKey, "properties")
+		})
+
+		Convey("Then bulk actions name the document type", func() {
+			_, err := esAPI.BulkRequest(ctx, "test_postcode", []interface{}{testDoc{"E01"}})
+			So(err, ShouldBeNil)
+			So(bodies["/_bulk"], ShouldStartWith, `{ "index": {"_index": "test_postcode", "_type": "_doc"} }`)
+		})
+
+		Convey("Then geo searches leave hit totals to the default", func() {
+			point := &models.GeoLocation{Type: "point", Coordinates: []float64{-3.1791, 51.4816}}
+			_, _, err := esAPI.QueryGeoLocation(ctx, "test_geo", point, 50, 0, "intersects")
+			So(err, ShouldBeNil)
+			So(bodies["/test_geo/_search"], ShouldNotContainSubstring, "track_total_hits")
+		})
+	})
+
+	Convey("Given an elasticsearch 7 cluster", t, func() {
+		bodies := make(map[string]string)
+		server := newVersionedElasticsearch("7.10.2", bodies)
+		defer server.Close()
+
+		esAPI := newTestAPI(server.URL, nil)
+
+		_, err := esAPI.DetectVersion(ctx)
+		So(err, ShouldBeNil)
+
+		Convey("Then fields are mapped without a mapping type", func() {
+			_, err := esAPI.CreateSearchIndex(ctx, "test_postcode", "postcode-mappings.json")
+			So(err, ShouldBeNil)
+
+			mappings := decodeMappings(bodies["/test_postcode"])
+			So(mappings, ShouldContainKey, "properties")
+			So(mappings, ShouldNotContainKey, "doc")
+			So(bodies["/test_postcode"], ShouldContainSubstring, `"settings"`)
+		})
+
+		Convey("Then bulk actions leave out the document type", func() {
+			_, err := esAPI.BulkRequest(ctx, "test_postcode", []interface{}{testDoc{"E01"}})
+			So(err, ShouldBeNil)
+			So(bodies["/_bulk"], ShouldStartWith, `{ "index": {"_index": "test_postcode"} }`)
+			So(bodies["/_bulk"], ShouldNotContainSubstring, "_type")
+		})
+
+		Convey("Then geo searches count every hit", func() {
+			point := &models.GeoLocation{Type: "point", Coordinates: []float64{-3.1791, 51.4816}}
+			_, _, err := esAPI.QueryGeoLocation(ctx, "test_geo", point, 50, 0, "intersects")
+			So(err, ShouldBeNil)
+			So(bodies["/test_geo/_search"], ShouldContainSubstring, `"track_total_hits":true`)
+		})
+	})
+
+	Convey("Given an elasticsearch 5 cluster", t, func() {
+		server := newVersionedElasticsearch("5.6.16", map[string]string{})
+		defer server.Close()
+
+		Convey("Then the version is not supported", func() {
+			_, err := newTestAPI(server.URL, nil).DetectVersion(ctx)
+			So(err, ShouldEqual, es.ErrUnsupportedVersion)
+		})
+	})
+}
+
+func decodeMappings(body string) map[string]interface{} {
+	var index struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	json.NewDecoder(strings.NewReader(body)).Decode(&index)
+
+	return index.Mappings
+}
@@ -0,0 +1,262 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+)
+
+// ErrUnsupportedShape is returned for geo shapes other than points, polygons and multipolygons
+var ErrUnsupportedShape = errors.New("unsupported geo shape type")
+
+// point is a longitude, latitude pair in the order geojson and elasticsearch use
+type point [2]float64
+
+type ring []point
+
+// polygon holds an outer ring followed by any holes
+type polygon []ring
+
+// shape is either a set of points or a set of polygons. Coordinates are treated as
+// planar, which is close enough to elasticsearch for areas the size of the UK.
+type shape struct {
+	points   []point
+	polygons []polygon
+}
+
+type bbox struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (b bbox) intersects(o bbox) bool {
+	return b.minX <= o.maxX && o.minX <= b.maxX && b.minY <= o.maxY && o.minY <= b.maxY
+}
+
+func (b bbox) extend(o bbox) bbox {
+	return bbox{math.Min(b.minX, o.minX), math.Min(b.minY, o.minY), math.Max(b.maxX, o.maxX), math.Max(b.maxY, o.maxY)}
+}
+
+func emptyBox() bbox {
+	return bbox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+// parseShape converts a geo location, whose coordinates may be decoded json or typed
+// slices, into a shape
+func parseShape(location models.GeoLocation) (*shape, error) {
+	b, err := json.Marshal(location.Coordinates)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &shape{}
+
+	switch strings.ToLower(location.Type) {
+	case "point":
+		var p point
+		err = json.Unmarshal(b, &p)
+		s.points = []point{p}
+	case "multipoint":
+		err = json.Unmarshal(b, &s.points)
+	case "polygon":
+		var p polygon
+		err = json.Unmarshal(b, &p)
+		s.polygons = []polygon{p}
+	case "multipolygon":
+		err = json.Unmarshal(b, &s.polygons)
+	default:
+		return nil, ErrUnsupportedShape
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *shape) bounds() bbox {
+	box := emptyBox()
+
+	s.eachVertex(func(p point) {
+		box = box.extend(bbox{p[0], p[1], p[0], p[1]})
+	})
+
+	return box
+}
+
+func (s *shape) eachVertex(fn func(point)) {
+	for _, p := range s.points {
+		fn(p)
+	}
+
+	for _, poly := range s.polygons {
+		for _, r := range poly {
+			for _, p := range r {
+				fn(p)
+			}
+		}
+	}
+}
+
+func (s *shape) eachEdge(fn func(a, b point) bool) bool {
+	for _, poly := range s.polygons {
+		for _, r := range poly {
+			for i := 1; i < len(r); i++ {
+				if fn(r[i-1], r[i]) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// contains reports whether p is inside or on the boundary of the shape
+func (s *shape) contains(p point) bool {
+	for _, q := range s.points {
+		if q == p {
+			return true
+		}
+	}
+
+	for _, poly := range s.polygons {
+		if len(poly) == 0 || !ringContains(poly[0], p) {
+			continue
+		}
+
+		inHole := false
+		for _, hole := range poly[1:] {
+			if ringContains(hole, p) && !onRing(hole, p) {
+				inHole = true
+				break
+			}
+		}
+
+		if !inHole {
+			return true
+		}
+	}
+
+	return false
+}
+
+// intersects reports whether the shapes share any point
+func intersects(a, b *shape) bool {
+	if !a.bounds().intersects(b.bounds()) {
+		return false
+	}
+
+	found := false
+	a.eachVertex(func(p point) {
+		if !found && b.contains(p) {
+			found = true
+		}
+	})
+	if found {
+		return true
+	}
+
+	b.eachVertex(func(p point) {
+		if !found && a.contains(p) {
+			found = true
+		}
+	})
+	if found {
+		return true
+	}
+
+	return a.eachEdge(func(p1, p2 point) bool {
+		return b.eachEdge(func(q1, q2 point) bool {
+			return segmentsIntersect(p1, p2, q1, q2)
+		})
+	})
+}
+
+// within reports whether a lies entirely inside b
+func within(a, b *shape) bool {
+	inside := true
+	a.eachVertex(func(p point) {
+		if inside && !b.contains(p) {
+			inside = false
+		}
+	})
+	if !inside {
+		return false
+	}
+
+	return !a.eachEdge(func(p1, p2 point) bool {
+		return b.eachEdge(func(q1, q2 point) bool {
+			return segmentsCross(p1, p2, q1, q2)
+		})
+	})
+}
+
+// ringContains uses ray casting, counting points on the ring as inside
+func ringContains(r ring, p point) bool {
+	if onRing(r, p) {
+		return true
+	}
+
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[i], r[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+func onRing(r ring, p point) bool {
+	for i := 1; i < len(r); i++ {
+		if orientation(r[i-1], r[i], p) == 0 && onSegment(r[i-1], r[i], p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func orientation(a, b, c point) int {
+	v := (b[1]-a[1])*(c[0]-b[0]) - (b[0]-a[0])*(c[1]-b[1])
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+
+	return 0
+}
+
+// onSegment reports whether c, known to be collinear with a and b, lies between them
+func onSegment(a, b, c point) bool {
+	return c[0] >= math.Min(a[0], b[0]) && c[0] <= math.Max(a[0], b[0]) &&
+		c[1] >= math.Min(a[1], b[1]) && c[1] <= math.Max(a[1], b[1])
+}
+
+// segmentsIntersect reports whether two segments share any point, including touching
+func segmentsIntersect(p1, p2, q1, q2 point) bool {
+	o1, o2 := orientation(p1, p2, q1), orientation(p1, p2, q2)
+	o3, o4 := orientation(q1, q2, p1), orientation(q1, q2, p2)
+
+	if o1 != o2 && o3 != o4 {
+		return true
+	}
+
+	return (o1 == 0 && onSegment(p1, p2, q1)) || (o2 == 0 && onSegment(p1, p2, q2)) ||
+		(o3 == 0 && onSegment(q1, q2, p1)) || (o4 == 0 && onSegment(q1, q2, p2))
+}
+
+// segmentsCross reports whether two segments cross each other rather than just touch
+func segmentsCross(p1, p2, q1, q2 point) bool {
+	o1, o2 := orientation(p1, p2, q1), orientation(p1, p2, q2)
+	o3, o4 := orientation(q1, q2, p1), orientation(q1, q2, p2)
+
+	return o1*o2 < 0 && o3*o4 < 0
+}
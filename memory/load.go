@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnknownSnapshotFormat is returned for snapshot files that are not ndjson or geojson
+var ErrUnknownSnapshotFormat = errors.New("unknown snapshot format, expected .ndjson, .geojson or .json")
+
+// maxLineSize is the longest ndjson line accepted, large enough for detailed boundaries
+const maxLineSize = 64 * 1024 * 1024
+
+type featureCollection struct {
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   *geometry              `json:"geometry"`
+}
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// LoadFile loads a snapshot into an index, choosing the format from the file extension
+func (s *Store) LoadFile(indexName, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson":
+		return s.LoadNDJSON(indexName, f)
+	case ".geojson", ".json":
+		return s.LoadGeoJSON(indexName, f)
+	}
+
+	return 0, ErrUnknownSnapshotFormat
+}
+
+// LoadNDJSON loads one document per line, such as the body of a bulk request or an
+// elasticsearch export. Bulk action lines are skipped.
+func (s *Store) LoadNDJSON(indexName string, r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	count := 0
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 || isBulkAction(b) {
+			continue
+		}
+
+		if err := s.Add(indexName, b); err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		count++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+
+	s.Create(indexName)
+
+	return count, nil
+}
+
+// LoadGeoJSON loads the features of a feature collection. Each document holds the
+// feature properties, with lower case names, and the geometry as its location.
+func (s *Store) LoadGeoJSON(indexName string, r io.Reader) (int, error) {
+	var collection featureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return 0, err
+	}
+
+	for i, f := range collection.Features {
+		doc := make(map[string]interface{})
+		for name, value := range f.Properties {
+			doc[strings.ToLower(name)] = value
+		}
+
+		if f.Geometry != nil {
+			doc["location"] = geometry{Type: strings.ToLower(f.Geometry.Type), Coordinates: f.Geometry.Coordinates}
+		}
+
+		b, err := json.Marshal(doc)
+		if err != nil {
+			return i, err
+		}
+
+		if err = s.Add(indexName, b); err != nil {
+			return i, fmt.Errorf("feature %d: %w", i, err)
+		}
+	}
+
+	s.Create(indexName)
+
+	return len(collection.Features), nil
+}
+
+// Create creates an empty index if it does not already exist
+func (s *Store) Create(indexName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.indices[indexName]; !ok {
+		s.indices[indexName] = newIndex()
+	}
+}
+
+func isBulkAction(b []byte) bool {
+	var action map[string]json.RawMessage
+	if err := json.Unmarshal(b, &action); err != nil || len(action) != 1 {
+		return false
+	}
+
+	for name := range action {
+		switch name {
+		case "index", "create", "update", "delete":
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,499 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+)
+
+// textFields are analysed into words for match queries, every other string field is
+// matched exactly like an elasticsearch keyword
+var textFields = map[string]bool{
+	"name": true,
+}
+
+// Store is an in-memory stand in for elasticsearch that answers the searches made by the
+// api. Documents are held per index; their location is indexed in an R-tree for geo shape
+// queries and their text fields in an inverted index for name searches.
+type Store struct {
+	mu      sync.RWMutex
+	indices map[string]*index
+}
+
+type index struct {
+	docs     []*document
+	boxes    []bbox
+	located  []int
+	tree     *rtree
+	terms    map[string]map[string][]int
+	keywords map[string]map[string][]int
+}
+
+type document struct {
+	source json.RawMessage
+	fields map[string]interface{}
+	shape  *shape
+}
+
+type hit struct {
+	id    int
+	score float64
+}
+
+// New creates an empty store
+func New() *Store {
+	return &Store{indices: make(map[string]*index)}
+}
+
+// Add stores a document in an index, creating the index if it does not exist. A document
+// with a location must hold a point, polygon or multipolygon.
+func (s *Store) Add(indexName string, source []byte) error {
+	doc := &document{source: append(json.RawMessage(nil), source...)}
+
+	if err := json.Unmarshal(source, &doc.fields); err != nil {
+		return err
+	}
+
+	if location, ok := doc.fields["location"]; ok && location != nil {
+		var geoLocation models.GeoLocation
+		b, _ := json.Marshal(location)
+		if err := json.Unmarshal(b, &geoLocation); err != nil {
+			return err
+		}
+
+		shape, err := parseShape(geoLocation)
+		if err != nil {
+			return fmt.Errorf("location: %w", err)
+		}
+		doc.shape = shape
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, ok := s.indices[indexName]
+	if !ok {
+		idx = newIndex()
+		s.indices[indexName] = idx
+	}
+
+	idx.add(doc)
+
+	return nil
+}
+
+// Count returns the number of documents in an index
+func (s *Store) Count(indexName string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, ok := s.indices[indexName]
+	if !ok {
+		return 0, errs.ErrIndexNotFound
+	}
+
+	return len(idx.docs), nil
+}
+
+func newIndex() *index {
+	return &index{
+		terms:    make(map[string]map[string][]int),
+		keywords: make(map[string]map[string][]int),
+	}
+}
+
+func (idx *index) add(doc *document) {
+	id := len(idx.docs)
+	idx.docs = append(idx.docs, doc)
+
+	box := emptyBox()
+	if doc.shape != nil {
+		box = doc.shape.bounds()
+		idx.located = append(idx.located, id)
+		idx.tree = nil
+	}
+	idx.boxes = append(idx.boxes, box)
+
+	for field, value := range doc.fields {
+		str, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		if textFields[field] {
+			if idx.terms[field] == nil {
+				idx.terms[field] = make(map[string][]int)
+			}
+
+			for _, token := range uniqueTokens(str) {
+				idx.terms[field][token] = append(idx.terms[field][token], id)
+			}
+			continue
+		}
+
+		if idx.keywords[field] == nil {
+			idx.keywords[field] = make(map[string][]int)
+		}
+		idx.keywords[field][str] = append(idx.keywords[field][str], id)
+	}
+}
+
+// readIndex returns the named index with its R-tree built, holding the read lock until
+// the returned func is called
+func (s *Store) readIndex(indexName string) (*index, func(), error) {
+	for {
+		s.mu.RLock()
+		idx, ok := s.indices[indexName]
+		if !ok {
+			s.mu.RUnlock()
+			return nil, nil, errs.ErrIndexNotFound
+		}
+
+		if idx.tree != nil {
+			return idx, s.mu.RUnlock, nil
+		}
+		s.mu.RUnlock()
+
+		s.mu.Lock()
+		if idx.tree == nil {
+			idx.tree = newRTree(idx.located, idx.boxes)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// AddBoundaryFile adds a boundary document to an index
+func (s *Store) AddBoundaryFile(ctx context.Context, indexName string, boundaryDoc *models.BoundaryDoc) (int, error) {
+	if boundaryDoc == nil || boundaryDoc.ID == "" {
+		return 0, errors.New("missing data")
+	}
+
+	b, err := json.Marshal(boundaryDoc)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = s.Add(indexName, b); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	return http.StatusCreated, nil
+}
+
+// GetBoundaryFile finds boundary documents by id
+func (s *Store) GetBoundaryFile(ctx context.Context, indexName, id string) (*models.BoundaryFileResponse, int, error) {
+	hits, status, err := s.keyword(indexName, "id", id)
+	if err != nil {
+		return nil, status, err
+	}
+
+	return &models.BoundaryFileResponse{Hits: hits}, status, nil
+}
+
+// GetPostcodes finds postcode documents by their normalised postcode
+func (s *Store) GetPostcodes(ctx context.Context, indexName, postcode string) (*models.PostcodeResponse, int, error) {
+	hits, status, err := s.keyword(indexName, "postcode", postcode)
+	if err != nil {
+		return nil, status, err
+	}
+
+	return &models.PostcodeResponse{Hits: hits}, status, nil
+}
+
+func (s *Store) keyword(indexName, field, value string) (models.EmbededHits, int, error) {
+	var hits models.EmbededHits
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	idx, ok := s.indices[indexName]
+	if !ok {
+		return hits, http.StatusNotFound, errs.ErrIndexNotFound
+	}
+
+	for _, id := range idx.keywords[field][value] {
+		var hit models.HitObj
+		if err := json.Unmarshal(idx.docs[id].source, &hit.Source); err != nil {
+			return hits, http.StatusInternalServerError, err
+		}
+		hits.Hits = append(hits.Hits, hit)
+	}
+
+	return hits, http.StatusOK, nil
+}
+
+// GetBoundaryFiles runs the subset of the elasticsearch query language the api uses for
+// code and name searches: term and terms filters and match queries, sorted by score
+func (s *Store) GetBoundaryFiles(ctx context.Context, indexName string, query interface{}) (*models.GeoResponseWithLocation, int, error) {
+	b, err := json.Marshal(query)
+	if err != nil {
+		return nil, 0, errs.ErrMarshallingQuery
+	}
+
+	var body models.Body
+	if err = json.Unmarshal(b, &body); err != nil {
+		return nil, 0, errs.ErrMarshallingQuery
+	}
+
+	idx, unlock, err := s.readIndex(indexName)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	defer unlock()
+
+	hits := idx.search(body.Query.Bool)
+
+	response := &models.GeoResponseWithLocation{}
+	response.Hits.Total = models.HitsTotal(len(hits))
+
+	for _, h := range page(hits, body.From, body.Size) {
+		result := models.HitListWithLocation{Score: h.score}
+		if err = json.Unmarshal(idx.docs[h.id].source, &result.Source); err != nil {
+			return nil, http.StatusInternalServerError, errs.ErrUnmarshallingJSON
+		}
+		response.Hits.HitList = append(response.Hits.HitList, result)
+	}
+
+	return response, http.StatusOK, nil
+}
+
+// QueryGeoLocation finds documents whose location intersects or lies within a shape
+func (s *Store) QueryGeoLocation(ctx context.Context, indexName string, geoLocation *models.GeoLocation, limit, offset int, relation string) (*models.GeoResponse, int, error) {
+	if geoLocation == nil {
+		return nil, 0, errors.New("missing data")
+	}
+
+	query, err := parseShape(*geoLocation)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("missing data")
+	}
+
+	idx, unlock, err := s.readIndex(indexName)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	defer unlock()
+
+	var hits []hit
+	idx.tree.search(query.bounds(), func(id int) {
+		shape := idx.docs[id].shape
+
+		if (relation == "within" && within(shape, query)) || (relation != "within" && intersects(shape, query)) {
+			hits = append(hits, hit{id: id, score: 1})
+		}
+	})
+
+	idx.sortHits(hits)
+
+	response := &models.GeoResponse{}
+	response.Hits.Total = models.HitsTotal(len(hits))
+
+	for _, h := range page(hits, offset, limit) {
+		result := models.HitList{Score: h.score}
+		if err = json.Unmarshal(idx.docs[h.id].source, &result.Source); err != nil {
+			return nil, http.StatusInternalServerError, errs.ErrUnmarshallingJSON
+		}
+		response.Hits.HitList = append(response.Hits.HitList, result)
+	}
+
+	return response, http.StatusOK, nil
+}
+
+// search scores the documents matching a bool query. With no must or should clauses
+// every document passing the filters matches with a score of 1.
+func (idx *index) search(query models.Bool) []hit {
+	candidates := make(map[int]bool)
+	for id := range idx.docs {
+		candidates[id] = true
+	}
+
+	for _, filter := range query.Filter {
+		for field, value := range filter.Term {
+			candidates = intersect(candidates, idx.keywords[field][value])
+		}
+
+		for field, values := range filter.Terms {
+			var ids []int
+			for _, value := range values {
+				ids = append(ids, idx.keywords[field][value]...)
+			}
+			candidates = intersect(candidates, ids)
+		}
+	}
+
+	scores := make(map[int]float64)
+
+	for _, must := range query.Must {
+		matched := idx.match(must)
+		for id := range candidates {
+			score, ok := matched[id]
+			if !ok {
+				delete(candidates, id)
+				continue
+			}
+			scores[id] += score
+		}
+	}
+
+	if len(query.Should) > 0 {
+		matchedAny := make(map[int]bool)
+		for _, should := range query.Should {
+			for id, score := range idx.match(should) {
+				if candidates[id] {
+					matchedAny[id] = true
+					scores[id] += score
+				}
+			}
+		}
+
+		if len(query.Must) == 0 {
+			candidates = matchedAny
+		}
+	}
+
+	hits := make([]hit, 0, len(candidates))
+	for id := range candidates {
+		score := scores[id]
+		if len(query.Must) == 0 && len(query.Should) == 0 {
+			score = 1
+		}
+		hits = append(hits, hit{id: id, score: score})
+	}
+
+	idx.sortHits(hits)
+
+	return hits
+}
+
+// match scores documents containing any word of a match query, weighting rarer words higher
+func (idx *index) match(match models.Match) map[int]float64 {
+	scores := make(map[int]float64)
+
+	for field, text := range match.Match {
+		for _, token := range uniqueTokens(text) {
+			ids := idx.termIDs(field, token)
+			if len(ids) == 0 {
+				continue
+			}
+
+			idf := math.Log(1 + float64(len(idx.docs))/float64(len(ids)))
+			for _, id := range ids {
+				scores[id] += idf
+			}
+		}
+	}
+
+	return scores
+}
+
+func (idx *index) termIDs(field, token string) []int {
+	if textFields[field] {
+		return idx.terms[field][token]
+	}
+
+	var ids []int
+	for value, valueIDs := range idx.keywords[field] {
+		if strings.EqualFold(value, token) {
+			ids = append(ids, valueIDs...)
+		}
+	}
+
+	return ids
+}
+
+// sortHits orders hits by score, then by code and name so results page consistently
+func (idx *index) sortHits(hits []hit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+
+		a, b := idx.docs[hits[i].id].fields, idx.docs[hits[j].id].fields
+		if fmt.Sprint(a["code"]) != fmt.Sprint(b["code"]) {
+			return fmt.Sprint(a["code"]) < fmt.Sprint(b["code"])
+		}
+		if fmt.Sprint(a["name"]) != fmt.Sprint(b["name"]) {
+			return fmt.Sprint(a["name"]) < fmt.Sprint(b["name"])
+		}
+
+		return hits[i].id < hits[j].id
+	})
+}
+
+// IndexChecker returns a checker for the existence and document count of an index, matching
+// the elasticsearch index checker
+func (s *Store) IndexChecker(indexName string) health.Checker {
+	return func(ctx context.Context, state *health.CheckState) error {
+		count, err := s.Count(indexName)
+		if err != nil {
+			state.Update(health.StatusCritical, fmt.Sprintf("index %s does not exist", indexName), http.StatusNotFound)
+			return err
+		}
+
+		if count < 1 {
+			state.Update(health.StatusWarning, fmt.Sprintf("index %s contains no documents", indexName), http.StatusOK)
+			return nil
+		}
+
+		state.Update(health.StatusOK, fmt.Sprintf("index %s contains %d documents", indexName, count), http.StatusOK)
+
+		return nil
+	}
+}
+
+func intersect(candidates map[int]bool, ids []int) map[int]bool {
+	result := make(map[int]bool)
+	for _, id := range ids {
+		if candidates[id] {
+			result[id] = true
+		}
+	}
+
+	return result
+}
+
+func page(hits []hit, offset, limit int) []hit {
+	if offset < 0 {
+		offset = 0
+	}
+
+	if offset >= len(hits) {
+		return nil
+	}
+
+	end := len(hits)
+	if limit >= 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	return hits[offset:end]
+}
+
+// uniqueTokens splits text into lower case words, much like the standard analyser
+func uniqueTokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			tokens = append(tokens, word)
+		}
+	}
+
+	return tokens
+}
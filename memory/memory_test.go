@@ -0,0 +1,285 @@
+package memory_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/memory"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// newGrid returns a store holding a 10 by 10 grid of unit squares in index "test_geo",
+// with codes "x,y" for the bottom left corner of each square
+func newGrid() *memory.Store {
+	store := memory.New()
+
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			doc, _ := json.Marshal(map[string]interface{}{
+				"code":      fmt.Sprintf("%d,%d", x, y),
+				"name":      fmt.Sprintf("Square %d %d", x, y),
+				"hierarchy": "grid",
+				"location": models.GeoLocation{
+					Type:        "polygon",
+					Coordinates: square(float64(x), float64(y), float64(x+1), float64(y+1)),
+				},
+			})
+
+			So(store.Add("test_geo", doc), ShouldBeNil)
+		}
+	}
+
+	return store
+}
+
+func square(minX, minY, maxX, maxY float64) [][][]float64 {
+	return [][][]float64{{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}, {minX, minY}}}
+}
+
+func TestQueryGeoLocation(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a grid of squares", t, func() {
+		store := newGrid()
+		query := &models.GeoLocation{Type: "polygon", Coordinates: square(2.5, 2.5, 5.5, 5.5)}
+
+		Convey("When searching for squares intersecting a polygon", func() {
+			response, status, err := store.QueryGeoLocation(ctx, "test_geo", query, 50, 0, "intersects")
+
+			Convey("Then every square the polygon overlaps is returned", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(int(response.Hits.Total), ShouldEqual, 16)
+				So(response.Hits.HitList, ShouldHaveLength, 16)
+				So(response.Hits.HitList[0].Source.Code, ShouldEqual, "2,2")
+			})
+		})
+
+		Convey("When searching for squares within a polygon", func() {
+			response, status, err := store.QueryGeoLocation(ctx, "test_geo", query, 50, 0, "within")
+
+			Convey("Then only the squares entirely inside it are returned", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(int(response.Hits.Total), ShouldEqual, 4)
+
+				var codes []string
+				for _, hit := range response.Hits.HitList {
+					codes = append(codes, hit.Source.Code)
+				}
+				So(codes, ShouldResemble, []string{"3,3", "3,4", "4,3", "4,4"})
+			})
+		})
+
+		Convey("When paging through the results", func() {
+			response, _, err := store.QueryGeoLocation(ctx, "test_geo", query, 5, 15, "intersects")
+
+			Convey("Then the total is unchanged and only the last page is returned", func() {
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 16)
+				So(response.Hits.HitList, ShouldHaveLength, 1)
+				So(response.Hits.HitList[0].Source.Code, ShouldEqual, "5,5")
+			})
+		})
+
+		Convey("When paging with a negative offset", func() {
+			response, _, err := store.QueryGeoLocation(ctx, "test_geo", query, 2, -5, "intersects")
+
+			Convey("Then the first page is returned", func() {
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 16)
+				So(response.Hits.HitList, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When searching with a point", func() {
+			point := &models.GeoLocation{Type: "point", Coordinates: []float64{7.5, 1.5}}
+			response, _, err := store.QueryGeoLocation(ctx, "test_geo", point, 50, 0, "intersects")
+
+			Convey("Then the square containing it is returned", func() {
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 1)
+				So(response.Hits.HitList[0].Source.Code, ShouldEqual, "7,1")
+			})
+		})
+
+		Convey("When a square with a hole is added", func() {
+			doc, _ := json.Marshal(map[string]interface{}{
+				"code": "donut",
+				"location": models.GeoLocation{
+					Type:        "polygon",
+					Coordinates: [][][]float64{square(20, 20, 30, 30)[0], square(22, 22, 28, 28)[0]},
+				},
+			})
+			So(store.Add("test_geo", doc), ShouldBeNil)
+
+			Convey("Then a point in the hole does not match it", func() {
+				point := &models.GeoLocation{Type: "point", Coordinates: []float64{25, 25}}
+				response, _, err := store.QueryGeoLocation(ctx, "test_geo", point, 50, 0, "intersects")
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 0)
+			})
+
+			Convey("Then a point in the ring does", func() {
+				point := &models.GeoLocation{Type: "point", Coordinates: []float64{21, 25}}
+				response, _, err := store.QueryGeoLocation(ctx, "test_geo", point, 50, 0, "intersects")
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 1)
+			})
+		})
+
+		Convey("When searching an index that does not exist", func() {
+			_, status, err := store.QueryGeoLocation(ctx, "missing", query, 50, 0, "intersects")
+
+			Convey("Then index not found is returned", func() {
+				So(err, ShouldEqual, errs.ErrIndexNotFound)
+				So(status, ShouldEqual, http.StatusNotFound)
+			})
+		})
+	})
+}
+
+func TestGetBoundaryFiles(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a set of named areas", t, func() {
+		store := memory.New()
+		areas, err := store.LoadNDJSON("test_geo", strings.NewReader(`{"index":{"_index":"test_geo","_id":"1"}}
+{"code":"E1","name":"Newport","hierarchy":"tcity"}
+{"code":"E2","name":"Newport Pagnell","hierarchy":"tcity"}
+{"code":"W1","name":"Newport","hierarchy":"lsoa"}
+{"code":"E3","name":"Cardiff","hierarchy":"tcity"}
+`))
+		So(err, ShouldBeNil)
+		So(areas, ShouldEqual, 4)
+
+		Convey("When searching by name", func() {
+			query := models.Body{
+				Size:  10,
+				Query: models.Query{Bool: models.Bool{Should: []models.Match{{Match: map[string]string{"name": "newport pagnell"}}}}},
+			}
+			response, status, err := store.GetBoundaryFiles(ctx, "test_geo", query)
+
+			Convey("Then areas matching any word are returned with the closest match first", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(int(response.Hits.Total), ShouldEqual, 3)
+				So(response.Hits.HitList[0].Source.Name, ShouldEqual, "Newport Pagnell")
+				So(response.Hits.HitList[0].Score, ShouldBeGreaterThan, response.Hits.HitList[1].Score)
+			})
+		})
+
+		Convey("When searching by name and filtering by hierarchy", func() {
+			query := models.Body{
+				Size: 10,
+				Query: models.Query{Bool: models.Bool{
+					Filter: []models.Filter{{Term: map[string]string{"hierarchy": "lsoa"}}},
+					Should: []models.Match{{Match: map[string]string{"name": "newport"}}},
+				}},
+			}
+			response, _, err := store.GetBoundaryFiles(ctx, "test_geo", query)
+
+			Convey("Then only areas in the hierarchy are returned", func() {
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 1)
+				So(response.Hits.HitList[0].Source.Code, ShouldEqual, "W1")
+			})
+		})
+
+		Convey("When filtering by code", func() {
+			query := models.Body{
+				Size:  10,
+				Query: models.Query{Bool: models.Bool{Filter: []models.Filter{{Terms: map[string][]string{"code": {"E1", "E3"}}}}}},
+			}
+			response, _, err := store.GetBoundaryFiles(ctx, "test_geo", query)
+
+			Convey("Then the areas with those codes are returned", func() {
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 2)
+				So(response.Hits.HitList[0].Source.Name, ShouldEqual, "Newport")
+				So(response.Hits.HitList[1].Source.Name, ShouldEqual, "Cardiff")
+			})
+		})
+	})
+}
+
+func TestPostcodesAndBoundaryFiles(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("Given a postcode index", t, func() {
+		store := memory.New()
+		So(store.Add("test_postcode", []byte(`{"postcode":"np108xg","postcode_raw":"NP10 8XG","pin":{"location":{"lat":51.56,"lon":-3.03}}}`)), ShouldBeNil)
+
+		Convey("When a postcode is requested", func() {
+			response, status, err := store.GetPostcodes(ctx, "test_postcode", "np108xg")
+
+			Convey("Then its document is returned", func() {
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(response.Hits.Hits, ShouldHaveLength, 1)
+				So(response.Hits.Hits[0].Source.RawPostcode, ShouldEqual, "NP10 8XG")
+				So(response.Hits.Hits[0].Source.Pin.Location.Lat, ShouldEqual, 51.56)
+			})
+		})
+
+		Convey("When an unknown postcode is requested", func() {
+			response, _, err := store.GetPostcodes(ctx, "test_postcode", "np109xx")
+
+			Convey("Then no documents are returned", func() {
+				So(err, ShouldBeNil)
+				So(response.Hits.Hits, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given an empty store", t, func() {
+		store := memory.New()
+
+		Convey("When a boundary file is added", func() {
+			status, err := store.AddBoundaryFile(ctx, "test_boundary_files", &models.BoundaryDoc{
+				ID:       "1234",
+				Location: models.GeoLocation{Type: "polygon", Coordinates: square(0, 0, 1, 1)},
+			})
+			So(err, ShouldBeNil)
+			So(status, ShouldEqual, http.StatusCreated)
+
+			Convey("Then it can be found by id", func() {
+				response, status, err := store.GetBoundaryFile(ctx, "test_boundary_files", "1234")
+				So(err, ShouldBeNil)
+				So(status, ShouldEqual, http.StatusOK)
+				So(response.Hits.Hits, ShouldHaveLength, 1)
+				So(response.Hits.Hits[0].Source.Location.Type, ShouldEqual, "polygon")
+			})
+		})
+
+		Convey("When a boundary file without an id is added", func() {
+			_, err := store.AddBoundaryFile(ctx, "test_boundary_files", &models.BoundaryDoc{})
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+
+		Convey("When a geojson snapshot is loaded", func() {
+			count, err := store.LoadGeoJSON("test_geo", strings.NewReader(`{"type":"FeatureCollection","features":[
+				{"type":"Feature","properties":{"CODE":"E1","NAME":"Newport"},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}}
+			]}`))
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+
+			Convey("Then its features can be found by location", func() {
+				point := &models.GeoLocation{Type: "point", Coordinates: []float64{0.5, 0.5}}
+				response, _, err := store.QueryGeoLocation(ctx, "test_geo", point, 10, 0, "intersects")
+				So(err, ShouldBeNil)
+				So(int(response.Hits.Total), ShouldEqual, 1)
+				So(response.Hits.HitList[0].Source.Name, ShouldEqual, "Newport")
+			})
+		})
+	})
+}
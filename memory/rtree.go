@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"math"
+	"sort"
+)
+
+// rtreeFanout is the most entries held by a node of the tree
+const rtreeFanout = 16
+
+// rtree is an R-tree bulk loaded with the sort-tile-recursive algorithm. Documents are
+// loaded far more often than they are added one at a time, so rather than supporting
+// inserts the tree is rebuilt on the next search after documents are added.
+type rtree struct {
+	root *rnode
+}
+
+type rnode struct {
+	box      bbox
+	children []*rnode
+	id       int
+}
+
+// newRTree builds a tree over the bounding boxes of the documents with the given ids
+func newRTree(ids []int, boxes []bbox) *rtree {
+	if len(ids) == 0 {
+		return &rtree{}
+	}
+
+	nodes := make([]*rnode, len(ids))
+	for i, id := range ids {
+		nodes[i] = &rnode{box: boxes[id], id: id}
+	}
+
+	for len(nodes) > 1 {
+		nodes = pack(nodes)
+	}
+
+	return &rtree{root: nodes[0]}
+}
+
+// search calls fn with the id of every document whose bounding box intersects box
+func (t *rtree) search(box bbox, fn func(id int)) {
+	if t.root != nil {
+		t.root.search(box, fn)
+	}
+}
+
+func (n *rnode) search(box bbox, fn func(id int)) {
+	if !n.box.intersects(box) {
+		return
+	}
+
+	if n.children == nil {
+		fn(n.id)
+		return
+	}
+
+	for _, child := range n.children {
+		child.search(box, fn)
+	}
+}
+
+// pack groups nodes into parents of up to rtreeFanout children, tiling them into
+// vertical slices by x and then runs by y so that parents cover compact areas
+func pack(nodes []*rnode) []*rnode {
+	parentCount := int(math.Ceil(float64(len(nodes)) / rtreeFanout))
+	sliceCount := int(math.Ceil(math.Sqrt(float64(parentCount))))
+	sliceSize := sliceCount * rtreeFanout
+
+	sort.Slice(nodes, func(i, j int) bool { return centreX(nodes[i].box) < centreX(nodes[j].box) })
+
+	var parents []*rnode
+
+	for start := 0; start < len(nodes); start += sliceSize {
+		slice := nodes[start:min(start+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool { return centreY(slice[i].box) < centreY(slice[j].box) })
+
+		for i := 0; i < len(slice); i += rtreeFanout {
+			children := slice[i:min(i+rtreeFanout, len(slice))]
+
+			parent := &rnode{box: emptyBox(), children: make([]*rnode, len(children))}
+			for j, child := range children {
+				parent.children[j] = child
+				parent.box = parent.box.extend(child.box)
+			}
+
+			parents = append(parents, parent)
+		}
+	}
+
+	return parents
+}
+
+func centreX(b bbox) float64 {
+	return (b.minX + b.maxX) / 2
+}
+
+func centreY(b bbox) float64 {
+	return (b.minY + b.maxY) / 2
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "dp_search_api"
+
+// A list of collectors for the search API and elasticsearch client
+var (
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of http requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of http requests by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	ElasticsearchRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "elasticsearch_request_duration_seconds",
+		Help:      "Latency of calls to elasticsearch by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	ElasticsearchErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "elasticsearch_errors_total",
+		Help:      "Total number of failed calls to elasticsearch by operation.",
+	}, []string{"operation"})
+
+	SearchResultsSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_results_size",
+		Help:      "Number of items returned by each type of search.",
+		Buckets:   []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000},
+	}, []string{"search"})
+)
+
+// knownMethods are recorded by name, any other method is recorded as "other" so clients
+// cannot create new series by sending made up methods
+var knownMethods = map[string]bool{
+	http.MethodConnect: true,
+	http.MethodDelete:  true,
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPatch:   true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodTrace:   true,
+}
+
+// ObserveElasticsearchCall records the latency of a call to elasticsearch and whether it failed
+func ObserveElasticsearchCall(method, path string, start time.Time, err error) {
+	operation := Operation(method, path)
+
+	ElasticsearchRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ElasticsearchErrorsTotal.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveResultSize records the number of items returned by a search
+func ObserveResultSize(search string, count int) {
+	SearchResultsSize.WithLabelValues(search).Observe(float64(count))
+}
+
+// Operation derives a low cardinality name for an elasticsearch call from the
+// method and url path, e.g. "_search" or "_cluster/health". Calls against the
+// index itself, such as creating or deleting an index, are named by method.
+func Operation(method, path string) string {
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+		if j := strings.Index(path, "/"); j >= 0 {
+			path = path[j:]
+		} else {
+			path = ""
+		}
+	}
+
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "_") {
+			return strings.Join(segments[i:], "/")
+		}
+	}
+
+	if segments[0] == "" {
+		return "ping"
+	}
+
+	return strings.ToLower(method) + "_index"
+}
+
+// statusRecorder wraps a response writer to capture the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Middleware records the number and latency of requests against each route. The route
+// is resolved by routeName so the path template is used rather than the raw url, and
+// methods other than the standard http methods are recorded as "other".
+func Middleware(routeName func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			h.ServeHTTP(rec, r)
+
+			method := r.Method
+			if !knownMethods[method] {
+				method = "other"
+			}
+
+			labels := []string{routeName(r), method, strconv.Itoa(rec.status)}
+			HTTPRequestsTotal.WithLabelValues(labels...).Inc()
+			HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// Instrument records the requests served by router. Matched routes are recorded by router
+// middleware, which is not run for requests matching no route, so those are recorded by
+// wrapping the not found and method not allowed handlers.
+func Instrument(router *mux.Router, routeName func(*http.Request) string) {
+	middleware := Middleware(routeName)
+
+	router.Use(middleware)
+
+	notFound := router.NotFoundHandler
+	if notFound == nil {
+		notFound = http.NotFoundHandler()
+	}
+	router.NotFoundHandler = middleware(notFound)
+
+	methodNotAllowed := router.MethodNotAllowedHandler
+	if methodNotAllowed == nil {
+		methodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		})
+	}
+	router.MethodNotAllowedHandler = middleware(methodNotAllowed)
+}
@@ -0,0 +1,112 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/metrics"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOperation(t *testing.T) {
+	Convey("Given calls to elasticsearch endpoints", t, func() {
+		So(metrics.Operation("GET", "http://localhost:9200/test_geo/_search"), ShouldEqual, "_search")
+		So(metrics.Operation("POST", "http://localhost:9200/_bulk"), ShouldEqual, "_bulk")
+		So(metrics.Operation("GET", "http://localhost:9200/_cluster/health"), ShouldEqual, "_cluster/health")
+		So(metrics.Operation("GET", "http://localhost:9200/test_geo/_count?q=*"), ShouldEqual, "_count")
+		So(metrics.Operation("POST", "http://localhost:9200/test_boundary_files/_doc"), ShouldEqual, "_doc")
+	})
+
+	Convey("Given calls against an index or the cluster root", t, func() {
+		So(metrics.Operation("PUT", "http://localhost:9200/test_geo"), ShouldEqual, "put_index")
+		So(metrics.Operation("DELETE", "http://localhost:9200/test_geo"), ShouldEqual, "delete_index")
+		So(metrics.Operation("GET", "http://localhost:9200"), ShouldEqual, "ping")
+	})
+}
+
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return "unknown"
+}
+
+// observations returns the number of latencies recorded with the given labels
+func observations(labels ...string) uint64 {
+	var m dto.Metric
+	So(metrics.HTTPRequestDuration.WithLabelValues(labels...).(prometheus.Metric).Write(&m), ShouldBeNil)
+
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrument(t *testing.T) {
+	Convey("Given an instrumented router with a route on a subrouter", t, func() {
+		router := mux.NewRouter()
+		metrics.Instrument(router, routeName)
+
+		search := router.NewRoute().Subrouter()
+		search.HandleFunc("/test/instrument/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}).Methods("GET")
+
+		serve := func(method, path string) int {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			return w.Code
+		}
+
+		Convey("When a route is requested", func() {
+			labels := []string{"/test/instrument/{id}", "GET", "418"}
+			count := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...))
+			latencies := observations(labels...)
+
+			So(serve("GET", "/test/instrument/1"), ShouldEqual, http.StatusTeapot)
+
+			Convey("Then it is counted and timed by route template, method and status code", func() {
+				So(testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...)), ShouldEqual, count+1)
+				So(observations(labels...), ShouldEqual, latencies+1)
+			})
+		})
+
+		Convey("When a path matching no route is requested", func() {
+			labels := []string{"unknown", "GET", "404"}
+			count := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...))
+
+			So(serve("GET", "/test/missing"), ShouldEqual, http.StatusNotFound)
+
+			Convey("Then it is counted as not found", func() {
+				So(testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...)), ShouldEqual, count+1)
+			})
+		})
+
+		Convey("When a route is requested with a method it does not allow", func() {
+			labels := []string{"unknown", "DELETE", "405"}
+			count := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...))
+
+			So(serve("DELETE", "/test/instrument/1"), ShouldEqual, http.StatusMethodNotAllowed)
+
+			Convey("Then it is counted as method not allowed", func() {
+				So(testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...)), ShouldEqual, count+1)
+			})
+		})
+
+		Convey("When a route is requested with a made up method", func() {
+			labels := []string{"unknown", "other", "405"}
+			count := testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...))
+
+			So(serve("FROBNICATE", "/test/instrument/1"), ShouldEqual, http.StatusMethodNotAllowed)
+
+			Convey("Then it is counted under other rather than its own method", func() {
+				So(testutil.ToFloat64(metrics.HTTPRequestsTotal.WithLabelValues(labels...)), ShouldEqual, count+1)
+			})
+		})
+	})
+}
@@ -2,7 +2,7 @@ package models
 
 import (
 	"context"
-	"errors"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -32,8 +32,13 @@ var miles = map[string]bool{
 
 // ErrorInvalidDistance - return error
 func ErrorInvalidDistance(m string) error {
-	err := errors.New("invalid distance value: " + m + ". Should contain a number and unit of distance separated by a comma e.g. 40,km")
-	return err
+	return &errs.Error{
+		Code:    errs.CodeInvalidDistance,
+		Message: "invalid distance value: " + m + ". Should contain a number and unit of distance separated by a comma e.g. 40,km",
+		Field:   "distance",
+		Details: map[string]interface{}{"value": m},
+		Status:  http.StatusBadRequest,
+	}
 }
 
 // ValidateDistance ...
@@ -1,8 +1,11 @@
 package models
 
 import (
-	"errors"
+	"encoding/json"
+	"net/http"
 	"strings"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
 )
 
 type GeoDoc struct {
@@ -18,7 +21,10 @@ type GeoLocation struct {
 // ------------------------------------------------------------------------
 
 type GeoLocationRequest struct {
-	Query GeoLocationQuery `json:"query"`
+	From      int              `json:"from"`
+	Size      int              `json:"size"`
+	Query     GeoLocationQuery `json:"query"`
+	TotalHits bool             `json:"track_total_hits,omitempty"`
 }
 
 type GeoLocationQuery struct {
@@ -56,10 +62,37 @@ type GeoResponse struct {
 }
 
 type Hits struct {
-	Total   int       `json:"total"`
+	Total   HitsTotal `json:"total"`
 	HitList []HitList `json:"hits"`
 }
 
+// HitsTotal is the total number of hits for a search, which elasticsearch 6 returns
+// as a number and elasticsearch 7 as an object holding the value
+type HitsTotal int
+
+// UnmarshalJSON decodes the total number of hits from either form
+func (t *HitsTotal) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '{' {
+		var total struct {
+			Value int `json:"value"`
+		}
+		if err := json.Unmarshal(b, &total); err != nil {
+			return err
+		}
+
+		*t = HitsTotal(total.Value)
+		return nil
+	}
+
+	var total int
+	if err := json.Unmarshal(b, &total); err != nil {
+		return err
+	}
+
+	*t = HitsTotal(total)
+	return nil
+}
+
 type HitList struct {
 	Score  float64      `json:"_score"`
 	Source SearchResult `json:"_source"`
@@ -70,24 +103,27 @@ type SearchResults struct {
 	Count      int            `json:"count"`
 	Items      []SearchResult `json:"items"`
 	Limit      int            `json:"limit"`
+	Links      *PageLinks     `json:"links,omitempty"`
 	Offset     int            `json:"offset"`
+	Postcode   *Postcode      `json:"postcode,omitempty"`
 	TotalCount int            `json:"total_count"`
 }
 
 // SearchResult represents data on a single item of search results
 type SearchResult struct {
-	Name         string  `json:"name"`
-	Code         string  `json:"code"`
-	Hierarchy    string  `json:"hierarchy"`
-	LSOA11NM     string  `json:"lsoa11nm,omitempty"`
-	LSOA11NMW    string  `json:"lsoa11nmw,omitempty"`
-	MSOA11NM     string  `json:"msoa11nm,omitempty"`
-	MSOA11NMW    string  `json:"msoa11nmw,omitempty"`
-	ShapeArea    float64 `json:"shape_area,omitempty"`
-	ShapeLength  float64 `json:"shape_length,omitempty"`
-	StatedArea   float64 `json:"stated_area,omitempty"`
-	StatedLength float64 `json:"stated_length,omitempty"`
-	TCITY15NM    string  `json:"tcity15nm,omitempty"`
+	Name         string     `json:"name"`
+	Code         string     `json:"code"`
+	Hierarchy    string     `json:"hierarchy"`
+	LSOA11NM     string     `json:"lsoa11nm,omitempty"`
+	LSOA11NMW    string     `json:"lsoa11nmw,omitempty"`
+	MSOA11NM     string     `json:"msoa11nm,omitempty"`
+	MSOA11NMW    string     `json:"msoa11nmw,omitempty"`
+	ShapeArea    float64    `json:"shape_area,omitempty"`
+	ShapeLength  float64    `json:"shape_length,omitempty"`
+	StatedArea   float64    `json:"stated_area,omitempty"`
+	StatedLength float64    `json:"stated_length,omitempty"`
+	TCITY15NM    string     `json:"tcity15nm,omitempty"`
+	Links        *ItemLinks `json:"links,omitempty"`
 }
 
 // ------------------------------------------------------------------------
@@ -97,7 +133,7 @@ type GeoResponseWithLocation struct {
 }
 
 type HitsWithLocation struct {
-	Total   int                   `json:"total"`
+	Total   HitsTotal             `json:"total"`
 	HitList []HitListWithLocation `json:"hits"`
 }
 
@@ -111,6 +147,7 @@ type SearchResultsWithLocation struct {
 	Count      int                        `json:"count"`
 	Items      []SearchResultWithLocation `json:"items"`
 	Limit      int                        `json:"limit"`
+	Links      *PageLinks                 `json:"links,omitempty"`
 	Offset     int                        `json:"offset"`
 	TotalCount int                        `json:"total_count"`
 }
@@ -121,12 +158,18 @@ type SearchResultWithLocation struct {
 	Code      string      `json:"code"`
 	Hierarchy string      `json:"hierarchy"`
 	Location  GeoLocation `json:"location,omitempty"`
+	Links     *ItemLinks  `json:"links,omitempty"`
 }
 
 // ErrorInvalidRelationValue - return error
 func ErrorInvalidRelationValue(m string) error {
-	err := errors.New(`incorrect relation value: ` + m + `. It Should be either "within" or "intersects"`)
-	return err
+	return &errs.Error{
+		Code:    errs.CodeInvalidRelation,
+		Message: `incorrect relation value: ` + m + `. It Should be either "within" or "intersects"`,
+		Field:   "relation",
+		Details: map[string]interface{}{"value": m},
+		Status:  http.StatusBadRequest,
+	}
 }
 
 var validRelation = map[string]bool{
@@ -0,0 +1,28 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHitsTotal(t *testing.T) {
+	Convey("Given an elasticsearch 6 search response", t, func() {
+		var response models.GeoResponse
+		So(json.Unmarshal([]byte(`{"hits":{"total":42,"hits":[]}}`), &response), ShouldBeNil)
+		So(response.Hits.Total, ShouldEqual, 42)
+	})
+
+	Convey("Given an elasticsearch 7 search response", t, func() {
+		var response models.GeoResponseWithLocation
+		So(json.Unmarshal([]byte(`{"hits":{"total":{"value":42,"relation":"eq"},"hits":[]}}`), &response), ShouldBeNil)
+		So(response.Hits.Total, ShouldEqual, 42)
+	})
+
+	Convey("Given a malformed total", t, func() {
+		var response models.GeoResponse
+		So(json.Unmarshal([]byte(`{"hits":{"total":"many"}}`), &response), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,20 @@
+package models
+
+// LinkObject represents a generic structure for all links
+type LinkObject struct {
+	HRef string `json:"href"`
+}
+
+// PageLinks represents the links used to navigate a paginated list of search results
+type PageLinks struct {
+	Self  *LinkObject `json:"self"`
+	First *LinkObject `json:"first"`
+	Prev  *LinkObject `json:"prev,omitempty"`
+	Next  *LinkObject `json:"next,omitempty"`
+	Last  *LinkObject `json:"last"`
+}
+
+// ItemLinks represents the links of a single search result
+type ItemLinks struct {
+	Geography *LinkObject `json:"geography"`
+}
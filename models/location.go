@@ -0,0 +1,160 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/bng"
+)
+
+var errNotFinite = errors.New("value is not a finite number")
+
+// Location is the point a search is made from, as given in the query parameters of a
+// request: a WGS84 latitude and longitude, a British National Grid easting and northing in
+// metres or an Ordnance Survey grid reference
+type Location struct {
+	Lat      string
+	Lon      string
+	Easting  string
+	Northing string
+	GridRef  string
+}
+
+// ErrorInvalidLocation - return error
+func ErrorInvalidLocation(field, value, reason string) error {
+	return &errs.Error{
+		Code:    errs.CodeInvalidLocation,
+		Message: "invalid " + field + " value: " + value + ". " + reason,
+		Field:   field,
+		Details: map[string]interface{}{"value": value},
+		Status:  http.StatusBadRequest,
+	}
+}
+
+// Coordinate validates the location and returns it as a WGS84 latitude and longitude,
+// converting an easting and northing from the national grid. A grid reference gives the
+// centre of the grid square it names.
+func (l Location) Coordinate() (lat, lon float64, err error) {
+	hasLatLon := l.Lat != "" || l.Lon != ""
+	hasGrid := l.Easting != "" || l.Northing != ""
+	hasGridRef := l.GridRef != ""
+
+	switch {
+	case hasLatLon && hasGrid, hasLatLon && hasGridRef, hasGrid && hasGridRef:
+		return 0, 0, errs.ErrConflictingLocation
+	case hasLatLon:
+		return l.latLon()
+	case hasGrid:
+		return l.grid()
+	case hasGridRef:
+		ref, err := l.gridRef()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		easting, northing := ref.Centre()
+		return toWGS84("gridref", l.GridRef, easting, northing)
+	}
+
+	return 0, 0, errs.ErrMissingLocation
+}
+
+// GridSquare returns the polygon covered by a grid reference coarser than a metre, e.g. the
+// kilometre square of ST 18 76, so that searches can match everything in it. It returns
+// nil for any other location.
+func (l Location) GridSquare() *GeoLocation {
+	if l.GridRef == "" {
+		return nil
+	}
+
+	ref, err := l.gridRef()
+	if err != nil || ref.Precision <= 1 {
+		return nil
+	}
+
+	var ring [][]float64
+	for _, corner := range ref.Corners() {
+		lat, lon, err := bng.ToWGS84(corner[0], corner[1])
+		if err != nil {
+			return nil
+		}
+		ring = append(ring, []float64{lon, lat})
+	}
+
+	return &GeoLocation{
+		Type:        "polygon",
+		Coordinates: [][][]float64{ring},
+	}
+}
+
+func (l Location) latLon() (lat, lon float64, err error) {
+	if l.Lat == "" || l.Lon == "" {
+		return 0, 0, errs.ErrMissingLocation
+	}
+
+	if lat, err = parseFloat(l.Lat); err != nil || lat < -90 || lat > 90 {
+		return 0, 0, ErrorInvalidLocation("lat", l.Lat, "Should be a latitude in degrees between -90 and 90")
+	}
+
+	if lon, err = parseFloat(l.Lon); err != nil || lon < -180 || lon > 180 {
+		return 0, 0, ErrorInvalidLocation("lon", l.Lon, "Should be a longitude in degrees between -180 and 180")
+	}
+
+	return lat, lon, nil
+}
+
+func (l Location) grid() (lat, lon float64, err error) {
+	if l.Easting == "" || l.Northing == "" {
+		return 0, 0, errs.ErrMissingLocation
+	}
+
+	easting, err := parseFloat(l.Easting)
+	if err != nil || easting < 0 || easting > bng.MaxEasting {
+		return 0, 0, ErrorInvalidLocation("easting", l.Easting, "Should be a national grid easting in metres between 0 and 700000")
+	}
+
+	northing, err := parseFloat(l.Northing)
+	if err != nil || northing < 0 || northing > bng.MaxNorthing {
+		return 0, 0, ErrorInvalidLocation("northing", l.Northing, "Should be a national grid northing in metres between 0 and 1300000")
+	}
+
+	return toWGS84("northing", l.Northing, easting, northing)
+}
+
+// toWGS84 converts an easting and northing, reporting a failure against the field they
+// were read from
+func toWGS84(field, value string, easting, northing float64) (lat, lon float64, err error) {
+	if lat, lon, err = bng.ToWGS84(easting, northing); err != nil {
+		return 0, 0, ErrorInvalidLocation(field, value, "Should be a location on the national grid")
+	}
+
+	return lat, lon, nil
+}
+
+func (l Location) gridRef() (bng.GridRef, error) {
+	ref, err := bng.ParseGridRef(l.GridRef)
+	if err != nil {
+		return bng.GridRef{}, ErrorInvalidLocation("gridref", l.GridRef, "Should be an Ordnance Survey grid reference of two letters followed by an even number of up to 10 digits e.g. ST 18 76 or ST1830076500")
+	}
+
+	return ref, nil
+}
+
+// parseFloat reads a number from a query parameter, rejecting NaN and infinity, which
+// strconv accepts but no location can be given as
+func parseFloat(value string) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errNotFinite
+	}
+
+	return f, nil
+}
@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"net/http"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLocationCoordinate(t *testing.T) {
+	Convey("Given a latitude and longitude", t, func() {
+		lat, lon, err := models.Location{Lat: "51.4822", Lon: "-3.1812"}.Coordinate()
+		So(err, ShouldBeNil)
+		So(lat, ShouldEqual, 51.4822)
+		So(lon, ShouldEqual, -3.1812)
+	})
+
+	Convey("Given a national grid easting and northing", t, func() {
+		lat, lon, err := models.Location{Easting: "318100", Northing: "176600"}.Coordinate()
+		So(err, ShouldBeNil)
+		So(lat, ShouldAlmostEqual, 51.4824, 0.001)
+		So(lon, ShouldAlmostEqual, -3.1808, 0.001)
+	})
+
+	Convey("Given an Ordnance Survey grid reference", t, func() {
+		location := models.Location{GridRef: "ST 18 76"}
+
+		Convey("Then its coordinate is the centre of the grid square", func() {
+			lat, lon, err := location.Coordinate()
+			So(err, ShouldBeNil)
+			So(lat, ShouldAlmostEqual, 51.4816, 0.001)
+			So(lon, ShouldAlmostEqual, -3.1750, 0.001)
+		})
+
+		Convey("Then its grid square is a polygon of the kilometre square", func() {
+			square := location.GridSquare()
+			So(square, ShouldNotBeNil)
+			So(square.Type, ShouldEqual, "polygon")
+
+			ring := square.Coordinates.([][][]float64)[0]
+			So(ring, ShouldHaveLength, 5)
+			So(ring[0], ShouldResemble, ring[4])
+		})
+	})
+
+	Convey("Given a grid reference to the nearest metre", t, func() {
+		So(models.Location{GridRef: "ST1830076500"}.GridSquare(), ShouldBeNil)
+	})
+
+	Convey("Given a grid reference that is not valid", t, func() {
+		_, _, err := models.Location{GridRef: "ST 183 76"}.Coordinate()
+		So(errs.ToAPIError(err).Field, ShouldEqual, "gridref")
+	})
+
+	Convey("Given a location that is missing a value", t, func() {
+		_, _, err := models.Location{Easting: "318100"}.Coordinate()
+		So(err, ShouldEqual, errs.ErrMissingLocation)
+
+		_, _, err = models.Location{}.Coordinate()
+		So(err, ShouldEqual, errs.ErrMissingLocation)
+	})
+
+	Convey("Given more than one kind of location", t, func() {
+		_, _, err := models.Location{Lat: "51.4822", Lon: "-3.1812", Easting: "318100", Northing: "176600"}.Coordinate()
+		So(err, ShouldEqual, errs.ErrConflictingLocation)
+
+		_, _, err = models.Location{Easting: "318100", Northing: "176600", GridRef: "ST 18 76"}.Coordinate()
+		So(err, ShouldEqual, errs.ErrConflictingLocation)
+	})
+
+	Convey("Given values that are out of range or not numbers", t, func() {
+		_, _, err := models.Location{Lat: "91", Lon: "0"}.Coordinate()
+		So(errs.ToAPIError(err).Field, ShouldEqual, "lat")
+
+		_, _, err = models.Location{Easting: "318100", Northing: "north"}.Coordinate()
+		So(errs.ToAPIError(err).Field, ShouldEqual, "northing")
+		So(errs.ToAPIError(err).Code, ShouldEqual, errs.CodeInvalidLocation)
+	})
+
+	Convey("Given values that are not finite numbers", t, func() {
+		_, _, err := models.Location{Easting: "100", Northing: "NaN"}.Coordinate()
+		So(errs.ToAPIError(err).Field, ShouldEqual, "northing")
+		So(errs.ToAPIError(err).Status, ShouldEqual, http.StatusBadRequest)
+
+		_, _, err = models.Location{Easting: "+Inf", Northing: "100"}.Coordinate()
+		So(errs.ToAPIError(err).Field, ShouldEqual, "easting")
+
+		_, _, err = models.Location{Lat: "NaN", Lon: "0"}.Coordinate()
+		So(errs.ToAPIError(err).Field, ShouldEqual, "lat")
+
+		_, _, err = models.Location{Lat: "0", Lon: "-Inf"}.Coordinate()
+		So(errs.ToAPIError(err).Field, ShouldEqual, "lon")
+	})
+}
@@ -1,8 +1,11 @@
 package models
 
 import (
-	"errors"
+	"net/http"
+	"net/url"
 	"strconv"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
 )
 
 // PageVariables are the necessary fields to determine paging
@@ -14,12 +17,36 @@ type PageVariables struct {
 
 // ErrorMaximumOffsetReached - return error
 func ErrorMaximumOffsetReached(m int) error {
-	err := errors.New("the maximum offset has been reached, the offset cannot be more than " + strconv.Itoa(m))
-	return err
+	return &errs.Error{
+		Code:    errs.CodeMaximumOffsetReached,
+		Message: "the maximum offset has been reached, the offset cannot be more than " + strconv.Itoa(m),
+		Field:   "offset",
+		Details: map[string]interface{}{"maximum_offset": m},
+		Status:  http.StatusBadRequest,
+	}
+}
+
+// ErrorNegativePageValue - return error
+func ErrorNegativePageValue(field string, value int) error {
+	return &errs.Error{
+		Code:    errs.CodeInvalidQueryParameter,
+		Message: "the " + field + " cannot be negative",
+		Field:   field,
+		Details: map[string]interface{}{"value": value},
+		Status:  http.StatusBadRequest,
+	}
 }
 
 // Validate represents a model for validating pagination variables
 func (page *PageVariables) Validate() error {
+	if page.Limit < 0 {
+		return ErrorNegativePageValue("limit", page.Limit)
+	}
+
+	if page.Offset < 0 {
+		return ErrorNegativePageValue("offset", page.Offset)
+	}
+
 	if page.Offset >= page.DefaultMaxResults {
 		return ErrorMaximumOffsetReached(page.DefaultMaxResults)
 	}
@@ -30,3 +57,50 @@ func (page *PageVariables) Validate() error {
 
 	return nil
 }
+
+// Links builds the self, first, prev, next and last links from the request url,
+// the validated paging variables and the total number of results. The number of
+// results that can be paged through is capped at the maximum offset.
+func (page *PageVariables) Links(requestURL *url.URL, totalCount int) *PageLinks {
+	links := &PageLinks{
+		Self:  pageLink(requestURL, page.Limit, page.Offset),
+		First: pageLink(requestURL, page.Limit, 0),
+	}
+
+	available := totalCount
+	if available > page.DefaultMaxResults {
+		available = page.DefaultMaxResults
+	}
+
+	if page.Limit < 1 || available < 1 {
+		links.Last = links.First
+		return links
+	}
+
+	if page.Offset > 0 {
+		prev := page.Offset - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links.Prev = pageLink(requestURL, page.Limit, prev)
+	}
+
+	if page.Offset+page.Limit < available {
+		links.Next = pageLink(requestURL, page.Limit, page.Offset+page.Limit)
+	}
+
+	links.Last = pageLink(requestURL, page.Limit, ((available-1)/page.Limit)*page.Limit)
+
+	return links
+}
+
+func pageLink(requestURL *url.URL, limit, offset int) *LinkObject {
+	u := *requestURL
+
+	query := u.Query()
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = query.Encode()
+
+	return &LinkObject{HRef: u.String()}
+}
@@ -0,0 +1,74 @@
+package models_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidate(t *testing.T) {
+	Convey("Given a negative limit", t, func() {
+		page := &models.PageVariables{DefaultMaxResults: 1000, Limit: -1, Offset: 0}
+
+		Convey("Then bad request is returned naming the limit", func() {
+			err := errs.ToAPIError(page.Validate())
+			So(err.Status, ShouldEqual, http.StatusBadRequest)
+			So(err.Field, ShouldEqual, "limit")
+		})
+	})
+
+	Convey("Given a negative offset", t, func() {
+		page := &models.PageVariables{DefaultMaxResults: 1000, Limit: 10, Offset: -10}
+
+		Convey("Then bad request is returned naming the offset", func() {
+			err := errs.ToAPIError(page.Validate())
+			So(err.Status, ShouldEqual, http.StatusBadRequest)
+			So(err.Field, ShouldEqual, "offset")
+		})
+	})
+
+	Convey("Given an offset and limit beyond the maximum number of results", t, func() {
+		page := &models.PageVariables{DefaultMaxResults: 100, Limit: 30, Offset: 90}
+
+		Convey("Then the limit is reduced to the results remaining", func() {
+			So(page.Validate(), ShouldBeNil)
+			So(page.Limit, ShouldEqual, 10)
+		})
+	})
+}
+
+func TestPageLinks(t *testing.T) {
+	requestURL, _ := url.Parse("http://localhost:10000/search/placenames/bradford?limit=10&offset=20")
+
+	Convey("Given a page in the middle of the results", t, func() {
+		page := &models.PageVariables{DefaultMaxResults: 1000, Limit: 10, Offset: 20}
+
+		links := page.Links(requestURL, 45)
+		So(links.Self.HRef, ShouldEqual, "http://localhost:10000/search/placenames/bradford?limit=10&offset=20")
+		So(links.First.HRef, ShouldEqual, "http://localhost:10000/search/placenames/bradford?limit=10&offset=0")
+		So(links.Prev.HRef, ShouldEqual, "http://localhost:10000/search/placenames/bradford?limit=10&offset=10")
+		So(links.Next.HRef, ShouldEqual, "http://localhost:10000/search/placenames/bradford?limit=10&offset=30")
+		So(links.Last.HRef, ShouldEqual, "http://localhost:10000/search/placenames/bradford?limit=10&offset=40")
+	})
+
+	Convey("Given the first and only page of results", t, func() {
+		page := &models.PageVariables{DefaultMaxResults: 1000, Limit: 10, Offset: 0}
+
+		links := page.Links(requestURL, 5)
+		So(links.Prev, ShouldBeNil)
+		So(links.Next, ShouldBeNil)
+		So(links.Last.HRef, ShouldEqual, links.First.HRef)
+	})
+
+	Convey("Given the total number of results exceeds the maximum offset", t, func() {
+		page := &models.PageVariables{DefaultMaxResults: 100, Limit: 30, Offset: 60}
+
+		links := page.Links(requestURL, 5000)
+		So(links.Next.HRef, ShouldEqual, "http://localhost:10000/search/placenames/bradford?limit=30&offset=90")
+		So(links.Last.HRef, ShouldEqual, "http://localhost:10000/search/placenames/bradford?limit=30&offset=90")
+	})
+}
@@ -4,6 +4,33 @@ type PostcodeDoc struct {
 	Postcode    string `json:"postcode"`
 	PostcodeRaw string `json:"postcode_raw"`
 	Pin         PinObj `json:"pin"`
+	PostcodeGeography
+}
+
+// PostcodeGeography holds the geography codes and other attributes of a postcode taken from
+// the national statistics postcode lookup (NSPL), so the areas a postcode is in can be
+// looked up exactly rather than approximated from its location. Codes are empty where the
+// NSPL has none for the postcode.
+type PostcodeGeography struct {
+	OA                        string `json:"oa,omitempty"`
+	LSOA                      string `json:"lsoa,omitempty"`
+	MSOA                      string `json:"msoa,omitempty"`
+	LAD                       string `json:"lad,omitempty"`
+	Ward                      string `json:"ward,omitempty"`
+	ParliamentaryConstituency string `json:"parliamentary_constituency,omitempty"`
+	Region                    string `json:"region,omitempty"`
+	Country                   string `json:"country,omitempty"`
+	IMDRank                   string `json:"imd_rank,omitempty"`
+	RuralUrban                string `json:"rural_urban,omitempty"`
+	TerminationDate           string `json:"termination_date,omitempty"`
+}
+
+// Postcode describes the postcode a search was made from
+type Postcode struct {
+	Postcode string  `json:"postcode"`
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	PostcodeGeography
 }
 
 type PinObj struct {
@@ -49,6 +76,7 @@ type Source struct {
 	Pin         Pin         `json:"pin,omitempty"`
 	ID          string      `json:"id,omitempty"`
 	Location    GeoLocation `json:"location,omitempty"`
+	PostcodeGeography
 }
 
 type Pin struct {
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/bng"
+)
+
+// List of query types detected by the smart search
+const (
+	QueryTypeCode      = "code"
+	QueryTypeGridRef   = "gridref"
+	QueryTypePlaceName = "placename"
+	QueryTypePoint     = "point"
+	QueryTypePostcode  = "postcode"
+)
+
+var (
+	// matches full uk postcodes with or without the space, e.g. CF24 4NY or cf244ny
+	postcodeRegex = regexp.MustCompile(`^[A-Z]{1,2}[0-9][A-Z0-9]? ?[0-9][A-Z]{2}$`)
+
+	// matches GSS codes, a country letter followed by 8 digits, e.g. E01000001 or W02000382
+	gssCodeRegex = regexp.MustCompile(`^[EKLMNSW][0-9]{8}$`)
+
+	// matches Ordnance Survey grid references to at least the nearest kilometre, e.g. ST 18 76
+	// or ST1830076500, fewer digits would mistake postcode districts such as SO14 for them
+	gridRefRegex = regexp.MustCompile(`^[A-Z]{2} ?([0-9]{4,10}|[0-9]{2,5} [0-9]{2,5})$`)
+
+	// matches a latitude and longitude pair separated by a comma, e.g. 51.4816,-3.1791
+	pointRegex = regexp.MustCompile(`^(-?[0-9]+(\.[0-9]+)?) *, *(-?[0-9]+(\.[0-9]+)?)$`)
+)
+
+// SmartSearchResults represents a structure for a list of returned objects
+// along with the type of search the query was identified as
+type SmartSearchResults struct {
+	Type       string      `json:"type"`
+	Count      int         `json:"count"`
+	Items      interface{} `json:"items"`
+	Limit      int         `json:"limit"`
+	Links      *PageLinks  `json:"links,omitempty"`
+	Offset     int         `json:"offset"`
+	Postcode   *Postcode   `json:"postcode,omitempty"`
+	TotalCount int         `json:"total_count"`
+}
+
+// ClassifyQuery determines the type of search the query term represents
+func ClassifyQuery(q string) string {
+	term := strings.ToUpper(strings.TrimSpace(q))
+
+	switch {
+	case postcodeRegex.MatchString(term):
+		return QueryTypePostcode
+	case gssCodeRegex.MatchString(term):
+		return QueryTypeCode
+	case gridRefRegex.MatchString(term) && isGridRef(term):
+		return QueryTypeGridRef
+	case pointRegex.MatchString(term):
+		return QueryTypePoint
+	default:
+		return QueryTypePlaceName
+	}
+}
+
+// isGridRef reports whether the term names a square on the national grid
+func isGridRef(term string) bool {
+	_, err := bng.ParseGridRef(term)
+	return err == nil
+}
+
+// ParsePoint converts a "lat,lon" query term into a coordinate pair
+func ParsePoint(q string) (lat, lon float64, err error) {
+	values := pointRegex.FindStringSubmatch(strings.TrimSpace(q))
+	if values == nil {
+		return 0, 0, errs.ErrInvalidPoint
+	}
+
+	if lat, err = strconv.ParseFloat(values[1], 64); err != nil {
+		return 0, 0, errs.ErrInvalidPoint
+	}
+
+	if lon, err = strconv.ParseFloat(values[3], 64); err != nil {
+		return 0, 0, errs.ErrInvalidPoint
+	}
+
+	if lat > 90 || lat < -90 || lon > 180 || lon < -180 {
+		return 0, 0, errs.ErrInvalidPoint
+	}
+
+	return lat, lon, nil
+}
@@ -0,0 +1,62 @@
+package models_test
+
+import (
+	"testing"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClassifyQuery(t *testing.T) {
+	Convey("Given a uk postcode", t, func() {
+		So(models.ClassifyQuery("CF24 4NY"), ShouldEqual, models.QueryTypePostcode)
+		So(models.ClassifyQuery("cf244ny"), ShouldEqual, models.QueryTypePostcode)
+		So(models.ClassifyQuery("BR3 3DA"), ShouldEqual, models.QueryTypePostcode)
+		So(models.ClassifyQuery("W1A 1AA"), ShouldEqual, models.QueryTypePostcode)
+	})
+
+	Convey("Given a GSS code", t, func() {
+		So(models.ClassifyQuery("E01000001"), ShouldEqual, models.QueryTypeCode)
+		So(models.ClassifyQuery("w02000382"), ShouldEqual, models.QueryTypeCode)
+	})
+
+	Convey("Given a latitude and longitude pair", t, func() {
+		So(models.ClassifyQuery("51.4816,-3.1791"), ShouldEqual, models.QueryTypePoint)
+		So(models.ClassifyQuery("51.4816, -3.1791"), ShouldEqual, models.QueryTypePoint)
+	})
+
+	Convey("Given an Ordnance Survey grid reference to at least the nearest kilometre", t, func() {
+		So(models.ClassifyQuery("ST 18 76"), ShouldEqual, models.QueryTypeGridRef)
+		So(models.ClassifyQuery("st1876"), ShouldEqual, models.QueryTypeGridRef)
+		So(models.ClassifyQuery("ST1830076500"), ShouldEqual, models.QueryTypeGridRef)
+		So(models.ClassifyQuery("ST 18300 76500"), ShouldEqual, models.QueryTypeGridRef)
+	})
+
+	Convey("Given any other search term", t, func() {
+		So(models.ClassifyQuery("SO14"), ShouldEqual, models.QueryTypePlaceName)
+		So(models.ClassifyQuery("IT 18 76"), ShouldEqual, models.QueryTypePlaceName)
+		So(models.ClassifyQuery("bradford"), ShouldEqual, models.QueryTypePlaceName)
+		So(models.ClassifyQuery("Newport, Wales"), ShouldEqual, models.QueryTypePlaceName)
+		So(models.ClassifyQuery("E0100001"), ShouldEqual, models.QueryTypePlaceName)
+	})
+}
+
+func TestParsePoint(t *testing.T) {
+	Convey("Given a valid latitude and longitude pair", t, func() {
+		lat, lon, err := models.ParsePoint("51.4816, -3.1791")
+		So(err, ShouldBeNil)
+		So(lat, ShouldEqual, 51.4816)
+		So(lon, ShouldEqual, -3.1791)
+	})
+
+	Convey("Given the latitude is out of range", t, func() {
+		_, _, err := models.ParsePoint("91.0,-3.1791")
+		So(err, ShouldResemble, errs.ErrInvalidPoint)
+	})
+
+	Convey("Given the value is not a point", t, func() {
+		_, _, err := models.ParsePoint("bradford")
+		So(err, ShouldResemble, errs.ErrInvalidPoint)
+	})
+}
@@ -0,0 +1,199 @@
+package ratelimit
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/auth"
+	"github.com/ONSdigital/log.go/log"
+	"golang.org/x/time/rate"
+)
+
+// buckets that have not been used for this long are discarded
+const idleTimeout = 10 * time.Minute
+
+// ErrInvalidLimit is returned when a rate limit cannot be parsed
+var ErrInvalidLimit = errors.New("invalid rate limit, expected a positive number of requests per second and burst separated by a slash e.g. 10/20")
+
+// Limit represents the sustained rate and burst size of a token bucket
+type Limit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// ParseLimit reads a limit in the form "<requests per second>/<burst>", e.g. 0.5/2
+func ParseLimit(value string) (Limit, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	requestsPerSecond, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	burst, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	limit := Limit{RequestsPerSecond: requestsPerSecond, Burst: burst}
+
+	return limit, limit.validate()
+}
+
+func (l Limit) validate() error {
+	if l.RequestsPerSecond <= 0 || l.Burst < 1 {
+		return ErrInvalidLimit
+	}
+
+	return nil
+}
+
+// Limiter holds a token bucket for each route and client pair
+type Limiter struct {
+	defaultLimit Limit
+	routes       map[string]Limit
+	trustProxy   bool
+	verifier     auth.Verifier
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+type bucketKey struct {
+	route  string
+	client string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New creates a Limiter applying the limit for the matched route, or the default
+// limit for any route without one. When trustProxy is set the client IP address
+// is taken from the X-Forwarded-For header. Clients whose credentials are accepted
+// by verifier get a bucket of their own, which may be nil to limit every client by IP.
+func New(defaultLimit Limit, routes map[string]Limit, trustProxy bool, verifier auth.Verifier) (*Limiter, error) {
+	if err := defaultLimit.validate(); err != nil {
+		return nil, err
+	}
+
+	for _, limit := range routes {
+		if err := limit.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Limiter{
+		defaultLimit: defaultLimit,
+		routes:       routes,
+		trustProxy:   trustProxy,
+		verifier:     verifier,
+		buckets:      make(map[bucketKey]*bucket),
+		lastSweep:    time.Now(),
+	}, nil
+}
+
+// Middleware rejects requests with a 429 once a client has used up the tokens for
+// the matched route, setting Retry-After to the number of seconds until the next
+// token is available. Clients are identified by the identity their API key or token
+// verifies as, otherwise by IP address, so sending made up credentials does not get
+// a client a fresh bucket. Preflight requests are never limited.
+func (l *Limiter) Middleware(routeName func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route := routeName(r)
+
+			retryAfter, ok := l.allow(bucketKey{route: route, client: l.client(r)}, time.Now())
+			if !ok {
+				seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+
+				log.Event(r.Context(), "rate limit exceeded", log.WARN, log.Data{"route": route, "retry_after": seconds})
+
+				w.Header().Set("Retry-After", seconds)
+				errs.WriteError(w, errs.ErrTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow takes a token from the bucket, returning how long to wait when none are left
+func (l *Limiter) allow(key bucketKey, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > idleTimeout {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > idleTimeout {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		limit, ok := l.routes[key.route]
+		if !ok {
+			limit = l.defaultLimit
+		}
+
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+
+	return 0, true
+}
+
+func (l *Limiter) client(r *http.Request) string {
+	if l.verifier != nil && hasCredentials(r) {
+		if identity, err := l.verifier.Verify(r); err == nil {
+			return "identity:" + identity
+		}
+	}
+
+	if l.trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return "ip:" + strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// hasCredentials reports whether the request sends an API key or a token, saving the
+// work of verifying requests that send neither
+func hasCredentials(r *http.Request) bool {
+	return r.Header.Get(auth.APIKeyHeader) != "" || r.Header.Get("Authorization") != ""
+}
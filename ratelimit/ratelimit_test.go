@@ -0,0 +1,101 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/auth"
+	"github.com/ONSdigital/dp-census-search-prototypes/ratelimit"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+func routeName(r *http.Request) string {
+	return r.URL.Path
+}
+
+func doRequest(handler http.Handler, method, path, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		r.Header.Set(auth.APIKeyHeader, apiKey)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestParseLimit(t *testing.T) {
+	Convey("Given a valid limit, it is parsed into a rate and burst", t, func() {
+		limit, err := ratelimit.ParseLimit("0.5/2")
+		So(err, ShouldBeNil)
+		So(limit, ShouldResemble, ratelimit.Limit{RequestsPerSecond: 0.5, Burst: 2})
+	})
+
+	Convey("Given an invalid limit, an error is returned", t, func() {
+		for _, value := range []string{"", "10", "a/2", "1/b", "0/5", "1/0", "1/2/3"} {
+			_, err := ratelimit.ParseLimit(value)
+			So(err, ShouldEqual, ratelimit.ErrInvalidLimit)
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	Convey("Given a limiter with a lower limit for the postcode route", t, func() {
+		limiter, err := ratelimit.New(
+			ratelimit.Limit{RequestsPerSecond: 10, Burst: 5},
+			map[string]ratelimit.Limit{"/search/postcodes": {RequestsPerSecond: 0.5, Burst: 2}},
+			false,
+			auth.NewStaticKeys(map[string]string{"loader": "abc123"}),
+		)
+		So(err, ShouldBeNil)
+
+		handler := limiter.Middleware(routeName)(okHandler)
+
+		Convey("When a client exceeds the route burst", func() {
+			So(doRequest(handler, "GET", "/search/postcodes", "10.0.0.1:1234", "").Code, ShouldEqual, http.StatusOK)
+			So(doRequest(handler, "GET", "/search/postcodes", "10.0.0.1:1234", "").Code, ShouldEqual, http.StatusOK)
+			w := doRequest(handler, "GET", "/search/postcodes", "10.0.0.1:5678", "")
+
+			Convey("Then a 429 is returned with the seconds until the next token", func() {
+				So(w.Code, ShouldEqual, http.StatusTooManyRequests)
+				So(w.Header().Get("Retry-After"), ShouldEqual, "2")
+			})
+
+			Convey("Then other routes use their own bucket", func() {
+				So(doRequest(handler, "GET", "/search/placenames", "10.0.0.1:1234", "").Code, ShouldEqual, http.StatusOK)
+			})
+
+			Convey("Then other clients are not limited", func() {
+				So(doRequest(handler, "GET", "/search/postcodes", "10.0.0.2:1234", "").Code, ShouldEqual, http.StatusOK)
+			})
+
+			Convey("Then a client with a valid api key uses its own bucket", func() {
+				So(doRequest(handler, "GET", "/search/postcodes", "10.0.0.1:1234", "abc123").Code, ShouldEqual, http.StatusOK)
+			})
+
+			Convey("Then a made up api key does not reset the bucket of the IP address", func() {
+				So(doRequest(handler, "GET", "/search/postcodes", "10.0.0.1:1234", "abc").Code, ShouldEqual, http.StatusTooManyRequests)
+				So(doRequest(handler, "GET", "/search/postcodes", "10.0.0.1:1234", "xyz").Code, ShouldEqual, http.StatusTooManyRequests)
+			})
+
+			Convey("Then preflight requests are not limited", func() {
+				So(doRequest(handler, "OPTIONS", "/search/postcodes", "10.0.0.1:1234", "").Code, ShouldEqual, http.StatusOK)
+			})
+		})
+	})
+
+	Convey("Given an invalid route limit, New returns an error", t, func() {
+		_, err := ratelimit.New(
+			ratelimit.Limit{RequestsPerSecond: 10, Burst: 5},
+			map[string]ratelimit.Limit{"/search": {RequestsPerSecond: 1}},
+			false,
+			nil,
+		)
+		So(err, ShouldEqual, ratelimit.ErrInvalidLimit)
+	})
+}
@@ -0,0 +1,62 @@
+package featureserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint records how far a load from a feature server layer has got, so that it can
+// carry on into the same index version after being stopped
+type Checkpoint struct {
+	URL     string `json:"url"`
+	Index   string `json:"index"`
+	Offset  int    `json:"offset"`
+	Indexed int    `json:"indexed"`
+}
+
+// LoadCheckpoint reads the checkpoint saved at path, returning nil if there is none
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err = json.Unmarshal(b, &checkpoint); err != nil {
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// Save writes the checkpoint to path. It is written to a temporary file first so that a
+// checkpoint is never left half written.
+func (c *Checkpoint) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,222 @@
+package featureserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	dphttp "github.com/ONSdigital/dp-net/http"
+)
+
+// defaultPageSize is used for layers that do not say how many features they return at once
+const defaultPageSize = 1000
+
+// A list of errors returned by the client
+var (
+	ErrMissingObjectID    = errors.New("feature has no object id")
+	ErrPagingNotSupported = errors.New("feature server layer does not support paging")
+	ErrUnexpectedStatus   = errors.New("unexpected status code from feature server")
+)
+
+// Error is an error reported in the body of a feature server response, which ArcGIS
+// sends with a 200 status
+type Error struct {
+	Code    int      `json:"code"`
+	Message string   `json:"message"`
+	Details []string `json:"details"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Details) > 0 {
+		return fmt.Sprintf("feature server error %d: %s: %s", e.Code, e.Message, strings.Join(e.Details, ", "))
+	}
+
+	return fmt.Sprintf("feature server error %d: %s", e.Code, e.Message)
+}
+
+// Layer describes a feature server layer
+type Layer struct {
+	Name           string `json:"name"`
+	ObjectIDField  string `json:"objectIdField"`
+	MaxRecordCount int    `json:"maxRecordCount"`
+	AdvancedQuery  struct {
+		SupportsPagination bool `json:"supportsPagination"`
+	} `json:"advancedQueryCapabilities"`
+
+	Error *Error `json:"error,omitempty"`
+}
+
+// Page is a page of features read from a layer. Invalid holds the features that could not
+// be converted, indexed by their position in the layer.
+type Page struct {
+	Offset   int
+	Next     int
+	Features []*features.Feature
+	Invalid  []*features.FeatureError
+
+	// ObjectIDField is the attribute holding the unique id of each feature in the layer
+	ObjectIDField string
+
+	// More is set when the server has more features after this page
+	More bool
+}
+
+// ObjectID returns the object id of a feature of the page, which is unique within the layer
+func (p *Page) ObjectID(f *features.Feature) (string, error) {
+	id, ok := f.Property(p.ObjectIDField)
+	if !ok || id == "" {
+		return "", ErrMissingObjectID
+	}
+
+	return id, nil
+}
+
+type queryResponse struct {
+	Features []struct {
+		Attributes map[string]interface{} `json:"attributes"`
+		Geometry   *esriGeometry          `json:"geometry"`
+	} `json:"features"`
+	ExceededTransferLimit bool `json:"exceededTransferLimit"`
+
+	Error *Error `json:"error,omitempty"`
+}
+
+// Client pages through the features of a feature server layer, e.g.
+// https://services1.arcgis.com/.../FeatureServer/0
+type Client struct {
+	clienter dphttp.Clienter
+	layerURL string
+}
+
+// New returns a client for the feature server layer at layerURL
+func New(clienter dphttp.Clienter, layerURL string) *Client {
+	return &Client{
+		clienter: clienter,
+		layerURL: strings.TrimRight(layerURL, "/"),
+	}
+}
+
+// Layer returns the description of the layer, checking it can be paged through
+func (c *Client) Layer(ctx context.Context) (*Layer, error) {
+	var layer Layer
+	if err := c.get(ctx, c.layerURL+"?f=json", &layer); err != nil {
+		return nil, err
+	}
+
+	if layer.Error != nil {
+		return nil, layer.Error
+	}
+
+	if !layer.AdvancedQuery.SupportsPagination {
+		return nil, ErrPagingNotSupported
+	}
+
+	return &layer, nil
+}
+
+// Query returns up to count features starting at offset, in WGS84 and ordered by object
+// id so that pages do not overlap. The server may return fewer than count features, in
+// which case More is set if it has more to give.
+func (c *Client) Query(ctx context.Context, layer *Layer, offset, count int) (*Page, error) {
+	params := url.Values{}
+	params.Set("where", "1=1")
+	params.Set("outFields", "*")
+	params.Set("outSR", "4326")
+	params.Set("f", "json")
+	params.Set("orderByFields", layer.ObjectIDField)
+	params.Set("resultOffset", strconv.Itoa(offset))
+	params.Set("resultRecordCount", strconv.Itoa(count))
+
+	var response queryResponse
+	if err := c.get(ctx, c.layerURL+"/query?"+params.Encode(), &response); err != nil {
+		return nil, err
+	}
+
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	page := &Page{
+		Offset:        offset,
+		Next:          offset + len(response.Features),
+		ObjectIDField: layer.ObjectIDField,
+		More:          response.ExceededTransferLimit && len(response.Features) > 0,
+	}
+
+	for i, f := range response.Features {
+		geometry, err := f.Geometry.toGeometry()
+		if err != nil {
+			page.Invalid = append(page.Invalid, &features.FeatureError{Index: offset + i, Err: err})
+			continue
+		}
+
+		page.Features = append(page.Features, &features.Feature{Properties: f.Attributes, Geometry: geometry})
+	}
+
+	return page, nil
+}
+
+// Pages calls fn with each page of features from offset to the end of the layer, asking
+// for pageSize features at a time or the most the layer allows if that is fewer. It stops
+// at the first error from fn.
+func (c *Client) Pages(ctx context.Context, offset, pageSize int, fn func(*Page) error) error {
+	layer, err := c.Layer(ctx)
+	if err != nil {
+		return err
+	}
+
+	if pageSize <= 0 || (layer.MaxRecordCount > 0 && pageSize > layer.MaxRecordCount) {
+		pageSize = layer.MaxRecordCount
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	for {
+		page, err := c.Query(ctx, layer, offset, pageSize)
+		if err != nil {
+			return err
+		}
+
+		if err = fn(page); err != nil {
+			return err
+		}
+
+		if !page.More {
+			return nil
+		}
+
+		offset = page.Next
+	}
+}
+
+func (c *Client) get(ctx context.Context, address string, v interface{}) error {
+	resp, err := c.clienter.Get(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	// numbers are kept as written so codes and areas are not rounded
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
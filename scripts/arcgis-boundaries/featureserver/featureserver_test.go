@@ -0,0 +1,303 @@
+package featureserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/arcgis-boundaries/featureserver"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	dphttp "github.com/ONSdigital/dp-net/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// stubFeatures are the features served by the stub, the third has two parts, the second
+// with a hole, and the fourth a ring that is not closed
+var stubFeatures = []map[string]interface{}{
+	{"attributes": map[string]interface{}{"OBJECTID": 1, "LSOA11CD": "E01000001", "Shape__Area": 133320.77}, "geometry": map[string]interface{}{"rings": [][][]float64{{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}}}},
+	{"attributes": map[string]interface{}{"OBJECTID": 2, "LSOA11CD": "E01000002"}, "geometry": nil},
+	{"attributes": map[string]interface{}{"OBJECTID": 3, "LSOA11CD": "E01000003"}, "geometry": map[string]interface{}{"rings": [][][]float64{
+		{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}},
+		{{2, 0}, {2, 5}, {7, 5}, {7, 0}, {2, 0}},
+		{{3, 1}, {4, 1}, {4, 2}, {3, 2}, {3, 1}},
+	}}},
+	{"attributes": map[string]interface{}{"OBJECTID": 4, "LSOA11CD": "E01000004"}, "geometry": map[string]interface{}{"rings": [][][]float64{{{0, 0}, {0, 1}, {1, 1}}}}},
+	{"attributes": map[string]interface{}{"OBJECTID": 5, "LSOA11CD": "E01000005"}, "geometry": map[string]interface{}{"rings": [][][]float64{{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}}}}},
+}
+
+// stubServer is a feature server layer returning at most transferLimit features a request,
+// whatever it says its maxRecordCount is
+type stubServer struct {
+	*httptest.Server
+
+	maxRecordCount int
+	transferLimit  int
+	pagination     bool
+	failQuery      bool
+
+	mu      sync.Mutex
+	queries []map[string]string
+}
+
+func newStubServer(maxRecordCount, transferLimit int) *stubServer {
+	s := &stubServer{maxRecordCount: maxRecordCount, transferLimit: transferLimit, pagination: true}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/FeatureServer/0", s.layer)
+	mux.HandleFunc("/FeatureServer/0/query", s.query)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+func (s *stubServer) layer(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":                      "LSOA_DEC_2011_EW_BFC",
+		"objectIdField":             "OBJECTID",
+		"maxRecordCount":            s.maxRecordCount,
+		"advancedQueryCapabilities": map[string]bool{"supportsPagination": s.pagination},
+	})
+}
+
+func (s *stubServer) query(w http.ResponseWriter, r *http.Request) {
+	params := map[string]string{}
+	for key := range r.URL.Query() {
+		params[key] = r.URL.Query().Get(key)
+	}
+
+	s.mu.Lock()
+	s.queries = append(s.queries, params)
+	s.mu.Unlock()
+
+	if s.failQuery {
+		fmt.Fprint(w, `{"error": {"code": 400, "message": "Cannot perform query. Invalid query parameters.", "details": ["'resultOffset' parameter is invalid"]}}`)
+		return
+	}
+
+	offset, _ := strconv.Atoi(params["resultOffset"])
+	count, _ := strconv.Atoi(params["resultRecordCount"])
+	if count > s.transferLimit {
+		count = s.transferLimit
+	}
+
+	end := offset + count
+	if end > len(stubFeatures) {
+		end = len(stubFeatures)
+	}
+
+	var page []map[string]interface{}
+	if offset < end {
+		page = stubFeatures[offset:end]
+	}
+
+	response := map[string]interface{}{"features": page}
+	if end < len(stubFeatures) {
+		response["exceededTransferLimit"] = true
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func readPages(client *featureserver.Client, offset, pageSize int) ([]*featureserver.Page, error) {
+	var pages []*featureserver.Page
+	err := client.Pages(context.Background(), offset, pageSize, func(page *featureserver.Page) error {
+		pages = append(pages, page)
+		return nil
+	})
+
+	return pages, err
+}
+
+func TestPages(t *testing.T) {
+	Convey("Given a feature server layer that returns at most 2 features at a time", t, func() {
+		server := newStubServer(2, 2)
+		defer server.Close()
+
+		client := featureserver.New(dphttp.NewClient(), server.URL+"/FeatureServer/0")
+
+		Convey("When every page is read asking for more features than the layer allows", func() {
+			pages, err := readPages(client, 0, 10)
+
+			Convey("Then the layer is paged through in object id order, in WGS84, the most it allows at a time", func() {
+				So(err, ShouldBeNil)
+				So(pages, ShouldHaveLength, 3)
+				So(server.queries, ShouldHaveLength, 3)
+
+				for i, query := range server.queries {
+					So(query["resultOffset"], ShouldEqual, strconv.Itoa(i*2))
+					So(query["resultRecordCount"], ShouldEqual, "2")
+					So(query["orderByFields"], ShouldEqual, "OBJECTID")
+					So(query["outSR"], ShouldEqual, "4326")
+					So(query["outFields"], ShouldEqual, "*")
+				}
+
+				So(pages[0].Offset, ShouldEqual, 0)
+				So(pages[0].Next, ShouldEqual, 2)
+				So(pages[0].More, ShouldBeTrue)
+				So(pages[2].More, ShouldBeFalse)
+			})
+
+			Convey("Then attributes are returned as properties", func() {
+				code, _ := pages[0].Features[0].Property("LSOA11CD")
+				So(code, ShouldEqual, "E01000001")
+
+				area, _ := pages[0].Features[0].Property("Shape__Area")
+				So(area, ShouldEqual, "133320.77")
+			})
+
+			Convey("Then features are identified by their object id", func() {
+				id, err := pages[0].ObjectID(pages[0].Features[0])
+				So(err, ShouldBeNil)
+				So(id, ShouldEqual, "1")
+
+				_, err = pages[0].ObjectID(&features.Feature{Properties: map[string]interface{}{}})
+				So(err, ShouldEqual, featureserver.ErrMissingObjectID)
+			})
+
+			Convey("Then a feature without a geometry has none", func() {
+				So(pages[0].Features[1].Geometry, ShouldBeNil)
+			})
+
+			Convey("Then rings are sorted into the polygons of a multipolygon by winding", func() {
+				geometry := pages[1].Features[0].Geometry
+				So(geometry.Type, ShouldEqual, "MultiPolygon")
+
+				polygons := geometry.Coordinates.([][][][]float64)
+				So(polygons, ShouldHaveLength, 2)
+				So(polygons[0], ShouldHaveLength, 1)
+				So(polygons[1], ShouldHaveLength, 2)
+				So(polygons[1][0], ShouldResemble, [][]float64{{2, 0}, {7, 0}, {7, 5}, {2, 5}, {2, 0}})
+			})
+
+			Convey("Then a feature that cannot be converted is returned as invalid with its position", func() {
+				So(pages[1].Features, ShouldHaveLength, 1)
+				So(pages[1].Invalid, ShouldHaveLength, 1)
+				So(pages[1].Invalid[0].Index, ShouldEqual, 3)
+				So(errors.Is(pages[1].Invalid[0], features.ErrInvalidCoordinates), ShouldBeTrue)
+			})
+		})
+
+		Convey("When reading is resumed from an offset", func() {
+			pages, err := readPages(client, 4, 0)
+
+			Convey("Then the pages after it are read", func() {
+				So(err, ShouldBeNil)
+				So(pages, ShouldHaveLength, 1)
+				So(server.queries[0]["resultOffset"], ShouldEqual, "4")
+
+				code, _ := pages[0].Features[0].Property("LSOA11CD")
+				So(code, ShouldEqual, "E01000005")
+			})
+		})
+
+		Convey("When the function reading pages fails", func() {
+			stop := errors.New("stop")
+			err := client.Pages(context.Background(), 0, 0, func(*featureserver.Page) error { return stop })
+
+			Convey("Then no more pages are read", func() {
+				So(err, ShouldEqual, stop)
+				So(server.queries, ShouldHaveLength, 1)
+			})
+		})
+	})
+
+	Convey("Given a feature server layer that returns fewer features than its maxRecordCount", t, func() {
+		server := newStubServer(1000, 2)
+		defer server.Close()
+
+		client := featureserver.New(dphttp.NewClient(), server.URL+"/FeatureServer/0")
+
+		Convey("When every page is read", func() {
+			pages, err := readPages(client, 0, 0)
+
+			Convey("Then each page starts after the features actually returned", func() {
+				So(err, ShouldBeNil)
+				So(pages, ShouldHaveLength, 3)
+				So(server.queries[0]["resultRecordCount"], ShouldEqual, "1000")
+				So(server.queries[1]["resultOffset"], ShouldEqual, "2")
+				So(server.queries[2]["resultOffset"], ShouldEqual, "4")
+			})
+		})
+	})
+
+	Convey("Given a feature server layer that does not support paging", t, func() {
+		server := newStubServer(2, 2)
+		server.pagination = false
+		defer server.Close()
+
+		client := featureserver.New(dphttp.NewClient(), server.URL+"/FeatureServer/0")
+
+		Convey("When every page is read", func() {
+			_, err := readPages(client, 0, 0)
+
+			Convey("Then paging not supported is returned without querying features", func() {
+				So(err, ShouldEqual, featureserver.ErrPagingNotSupported)
+				So(server.queries, ShouldBeEmpty)
+			})
+		})
+	})
+
+	Convey("Given a feature server layer that reports an error in the body of a response", t, func() {
+		server := newStubServer(2, 2)
+		server.failQuery = true
+		defer server.Close()
+
+		client := featureserver.New(dphttp.NewClient(), server.URL+"/FeatureServer/0")
+
+		Convey("When every page is read", func() {
+			_, err := readPages(client, 0, 0)
+
+			Convey("Then the error is returned", func() {
+				var serverErr *featureserver.Error
+				So(errors.As(err, &serverErr), ShouldBeTrue)
+				So(serverErr.Code, ShouldEqual, 400)
+				So(err.Error(), ShouldContainSubstring, "resultOffset")
+			})
+		})
+	})
+}
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint.json")
+
+	Convey("Given no checkpoint has been saved", t, func() {
+		checkpoint, err := featureserver.LoadCheckpoint(path)
+
+		Convey("Then none is loaded", func() {
+			So(err, ShouldBeNil)
+			So(checkpoint, ShouldBeNil)
+		})
+	})
+
+	Convey("Given a checkpoint is saved", t, func() {
+		saved := &featureserver.Checkpoint{URL: "http://arcgis/FeatureServer/0", Index: "test_arcgis_20200301090000", Offset: 4000, Indexed: 3998}
+		So(saved.Save(path), ShouldBeNil)
+
+		Convey("When it is loaded", func() {
+			checkpoint, err := featureserver.LoadCheckpoint(path)
+
+			Convey("Then it is the checkpoint saved", func() {
+				So(err, ShouldBeNil)
+				So(checkpoint, ShouldResemble, saved)
+
+				files, _ := ioutil.ReadDir(dir)
+				So(files, ShouldHaveLength, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package featureserver
+
+import (
+	"fmt"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+)
+
+// esriGeometry is an Esri JSON geometry, which is a point, multipoint, polyline or polygon
+// depending on which fields are set
+type esriGeometry struct {
+	X      *float64      `json:"x"`
+	Y      *float64      `json:"y"`
+	Points [][]float64   `json:"points"`
+	Paths  [][][]float64 `json:"paths"`
+	Rings  [][][]float64 `json:"rings"`
+}
+
+// toGeometry converts an Esri geometry to geojson. Polygon rings are sorted into outer
+// rings and holes by winding, so a polygon with several parts becomes a multipolygon. A
+// missing or empty geometry has no geometry.
+func (g *esriGeometry) toGeometry() (*features.Geometry, error) {
+	switch {
+	case g == nil:
+		return nil, nil
+	case g.Rings != nil:
+		return features.FromRings(g.Rings)
+	case g.Paths != nil:
+		if len(g.Paths) == 1 {
+			return &features.Geometry{Type: "LineString", Coordinates: g.Paths[0]}, nil
+		}
+		return &features.Geometry{Type: "MultiLineString", Coordinates: g.Paths}, nil
+	case g.Points != nil:
+		return &features.Geometry{Type: "MultiPoint", Coordinates: g.Points}, nil
+	case g.X != nil && g.Y != nil:
+		return &features.Geometry{Type: "Point", Coordinates: []float64{*g.X, *g.Y}}, nil
+	case g.X == nil && g.Y == nil:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("%w: point without both x and y", features.ErrInvalidCoordinates)
+}
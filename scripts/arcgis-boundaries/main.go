@@ -2,203 +2,216 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"io"
-	"io/ioutil"
-	"net/http"
+	"flag"
 	"os"
-	"strconv"
-	"time"
 
-	errs "github.com/ONSdigital/dp-census-search-prototypes/apierrors"
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
 	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/arcgis-boundaries/featureserver"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/layers"
 	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
 )
 
 const (
-	elasticsearchAPIURL = "http://localhost:9200"
-	geoFileIndex        = "test_arcgis"
-	lsoaURL             = "https://services1.arcgis.com/ESMARspQHYMw9BZ9/arcgis/rest/services/LSOA_DEC_2011_EW_BFC/FeatureServer/0/query?where=1%3D1&outFields=*&outSR=4326&f=json"
-	mappingsFile        = "geography-mappings.json"
+	geoFileIndex      = "test_arcgis"
+	lsoaURL           = "https://services1.arcgis.com/ESMARspQHYMw9BZ9/arcgis/rest/services/LSOA_DEC_2011_EW_BFC/FeatureServer/0"
+	mappingsFile      = "geography-mappings.json"
+	defaultCheckpoint = "arcgis-checkpoint.json"
 )
 
-var countCh = make(chan int)
+// lsoa maps the attributes of the lsoa layer to documents
+var lsoa = layers.Layer{
+	Name:      "2011-lsoa",
+	Index:     geoFileIndex,
+	Hierarchy: "lsoa",
+	CodeKey:   "LSOA11CD",
+	NameKey:   "LSOA11NM",
+	Fields: map[string]string{
+		"lsoa11nm":     "LSOA11NM",
+		"lsoa11nmw":    "LSOA11NMW",
+		"shape_area":   "Shape__Area",
+		"shape_length": "Shape__Length",
+	},
+}
 
 func main() {
 	ctx := context.Background()
 
-	cli := dphttp.NewClient()
-	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
+	layerURL := flag.String("url", lsoaURL, "url of the feature server layer to load")
+	checkpointFile := flag.String("checkpoint", defaultCheckpoint, "file recording progress, a load that was stopped carries on from it")
+	pageSize := flag.Int("page-size", 0, "features to request at a time, the most the layer allows if 0")
+	flag.Parse()
+
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
-	// delete existing elasticsearch index if already exists
-	status, err := esAPI.DeleteSearchIndex(ctx, geoFileIndex)
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
 	if err != nil {
-		if status != http.StatusNotFound {
-			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to detect elasticsearch version", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	if cfg.BulkDeadLetterFile != "" {
+		deadLetter, err := es.NewDeadLetterFile(cfg.BulkDeadLetterFile)
+		if err != nil {
+			log.Event(ctx, "failed to open dead letter file", log.FATAL, log.Error(err), log.Data{"file": cfg.BulkDeadLetterFile})
 			os.Exit(1)
 		}
+		defer deadLetter.Close()
 
-		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+		esAPI.SetDeadLetter(deadLetter)
 	}
 
-	// create elasticsearch index with settings/mapping
-	status, err = esAPI.CreateSearchIndex(ctx, geoFileIndex, mappingsFile)
+	checkpoint, err := startOrResume(ctx, esAPI, *layerURL, *checkpointFile)
 	if err != nil {
-		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
 		os.Exit(1)
 	}
 
-	go trackCounts(ctx)
+	logData := log.Data{"url": *layerURL, "index": checkpoint.Index, "checkpoint": *checkpointFile}
 
-	// make lsoa request
-	docs, err := callArcGis(ctx, lsoaURL)
+	client := featureserver.New(dphttp.NewClient(), *layerURL)
+
+	err = client.Pages(ctx, checkpoint.Offset, *pageSize, func(page *featureserver.Page) error {
+		return storePage(ctx, esAPI, cfg, page, checkpoint, *checkpointFile)
+	})
 	if err != nil {
-		log.Event(ctx, "failed to retrieve lsoa data from arcgis", log.FATAL, log.Error(err))
+		log.Event(ctx, "failed to load features from arcgis, run again to carry on from the checkpoint", log.FATAL, log.Error(err), logData)
 		os.Exit(1)
 	}
 
-	// Iterate items for individual geo boundaries and store documents in elasticsearch
-	if err = storeDocs(ctx, esAPI, geoFileIndex, docs); err != nil {
-		log.Event(ctx, "failed to store lsoa data in elasticsearch", log.FATAL, log.Error(err))
+	if err = esAPI.PublishIndex(ctx, geoFileIndex, checkpoint.Index, checkpoint.Indexed, cfg.IndexVersionsToKeep); err != nil {
+		log.Event(ctx, "failed to publish lsoa index", log.FATAL, log.Error(err), logData)
 		os.Exit(1)
 	}
 
-	log.Event(ctx, "successfully got geo docs, see data", log.INFO)
-}
+	if err = os.Remove(*checkpointFile); err != nil {
+		log.Event(ctx, "failed to remove checkpoint", log.WARN, log.Error(err), logData)
+	}
 
-func callArcGis(ctx context.Context, url string) (*geoDocs, error) {
-	logData := log.Data{"url": url}
+	logData["indexed"] = checkpoint.Indexed
+	log.Event(ctx, "successfully loaded features from arcgis", log.INFO, logData)
+}
 
-	resp, err := http.Get(url)
-	defer resp.Body.Close()
-	if err != nil {
-		log.Event(ctx, "request to argis failed", log.ERROR, log.Error(err), logData)
-		return nil, err
-	}
+// startOrResume carries on from the checkpoint of an earlier load of the same layer if its
+// index version still exists, or creates a new version of the index to load into
+func startOrResume(ctx context.Context, esAPI *es.API, layerURL, checkpointFile string) (*featureserver.Checkpoint, error) {
+	logData := log.Data{"url": layerURL, "checkpoint": checkpointFile}
 
-	geoDocs, err := createGeoDoc(resp.Body)
+	checkpoint, err := featureserver.LoadCheckpoint(checkpointFile)
 	if err != nil {
-		log.Event(ctx, "failed to create geoDocs", log.ERROR, log.Error(err), logData)
+		log.Event(ctx, "failed to read checkpoint", log.FATAL, log.Error(err), logData)
 		return nil, err
 	}
 
-	return geoDocs, nil
-}
-
-func trackCounts(ctx context.Context) {
-	var (
-		totalCounter = 0
-	)
+	if checkpoint != nil && checkpoint.URL == layerURL {
+		exists, err := esAPI.IndexExists(ctx, checkpoint.Index)
+		if err != nil {
+			log.Event(ctx, "failed to check index in checkpoint exists", log.FATAL, log.Error(err), logData)
+			return nil, err
+		}
 
-	t := time.NewTicker(5 * time.Second)
+		logData["index"] = checkpoint.Index
+		logData["offset"] = checkpoint.Offset
 
-	for {
-		select {
-		case n := <-countCh:
-			totalCounter += n
-		case <-t.C:
-			log.Event(ctx, "Total uploaded: "+strconv.Itoa(totalCounter), log.INFO)
+		if exists {
+			log.Event(ctx, "resuming load from checkpoint", log.INFO, logData)
+			return checkpoint, nil
 		}
-	}
-}
 
-type geoDocs struct {
-	items []geoDoc `json:"features"`
-}
+		log.Event(ctx, "index in checkpoint no longer exists, starting again", log.WARN, logData)
+	}
 
-type geoDoc struct {
-	name        string        `json:"name"`
-	code        string        `json:"code"`
-	lsoa11nm    string        `json:"lsoa11nm"`
-	lsoa11nmw   string        `json:"lsoa11nmw"`
-	shapeArea   float64       `json:"shape_area"`
-	shapeLength float64       `json:"shape_length"`
-	location    geoLocation   `json:"location"`
-	attributes  *attributeDoc `json:"attributes,omitempty`
-	geometry    *geometryDoc  `json:"geometry",omitempty`
-}
+	// create a new version of the index with settings/mapping, the alias keeps pointing
+	// at the current version until this one is loaded
+	indexName, status, err := esAPI.CreateIndexVersion(ctx, geoFileIndex, mappingsFile)
+	if err != nil {
+		log.Event(ctx, "failed to create index", log.FATAL, log.Error(err), log.Data{"status": status})
+		return nil, err
+	}
 
-type geoLocation struct {
-	gtype       string        `json:"type"`
-	coordinates [][][]float64 `json:"coordinates"`
-}
+	checkpoint = &featureserver.Checkpoint{URL: layerURL, Index: indexName}
+	if err = checkpoint.Save(checkpointFile); err != nil {
+		log.Event(ctx, "failed to save checkpoint", log.FATAL, log.Error(err), logData)
+		return nil, err
+	}
 
-type attributeDoc struct {
-	code        string  `json:"LSOA11CD"`
-	lsoa11nm    string  `json:"LSOA11NM"`
-	lsoa11nmw   string  `json:"LSOA11NMW"`
-	shapeArea   float64 `json:"Shape__Area"`
-	shapeLength float64 `json:"Shape__Length"`
+	return checkpoint, nil
 }
 
-type geometryDoc struct {
-	coordinates [][][]float64 `json:"rings"`
-}
+// storePage indexes the features of a page and, once every document has been sent, moves
+// the checkpoint past it. A page interrupted before then is loaded again on resume; its
+// documents are indexed with the object id of their feature, so those already indexed are
+// replaced rather than stored twice.
+func storePage(ctx context.Context, esAPI *es.API, cfg *config.Config, page *featureserver.Page, checkpoint *featureserver.Checkpoint, checkpointFile string) error {
+	logData := log.Data{"offset": page.Offset, "features": len(page.Features)}
 
-func createGeoDoc(reader io.Reader) (*geoDocs, error) {
-	b, err := ioutil.ReadAll(reader)
-	if err != nil {
-		return nil, errs.ErrUnableToReadMessage
+	for _, invalid := range page.Invalid {
+		log.Event(ctx, "skipping feature that is not valid", log.WARN, log.Error(invalid), log.Data{"feature": invalid.Index})
 	}
 
-	var docs geoDocs
-
-	err = json.Unmarshal(b, &docs)
-	if err != nil {
-		return nil, errs.ErrUnableToParseJSON
-	}
-	return &docs, nil
-}
-
-func storeDocs(ctx context.Context, esAPI *es.API, indexName string, docs *geoDocs) (err error) {
-	count := 0
-	var geoDocs []interface{}
-
-	// Iterate through the records
-	for _, doc := range docs.items {
-		count++
-
-		newDoc := &geoDoc{
-			code:        doc.attributes.code,
-			name:        doc.attributes.lsoa11nm,
-			lsoa11nm:    doc.attributes.lsoa11nm,
-			lsoa11nmw:   doc.attributes.lsoa11nmw,
-			shapeArea:   doc.attributes.shapeArea,
-			shapeLength: doc.attributes.shapeLength,
-			location: geoLocation{
-				gtype:       "polygon",
-				coordinates: doc.geometry.coordinates,
-			},
+	indexer := esAPI.NewBulkIndexer(ctx, es.BulkIndexerConfig{
+		Index:          checkpoint.Index,
+		Workers:        cfg.BulkWorkers,
+		FlushBytes:     cfg.BulkFlushBytes,
+		FlushDocuments: cfg.BulkFlushDocuments,
+		FlushInterval:  cfg.BulkFlushInterval,
+	})
+
+	for _, feature := range page.Features {
+		if feature.Geometry == nil {
+			log.Event(ctx, "skipping feature without a geometry", log.WARN, log.Data{"attributes": feature.Properties})
+			continue
 		}
 
-		geoDocs = append(geoDocs, newDoc)
-
-		if count == 500 {
-			if _, err = esAPI.BulkRequest(ctx, indexName, geoDocs); err != nil {
-				log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
-				return
-			}
+		doc, err := lsoa.Doc(feature.StringProperties(), layers.Location(feature.Geometry))
+		if err != nil {
+			log.Event(ctx, "failed to create document from feature attributes", log.ERROR, log.Error(err), logData)
+			indexer.Close(ctx)
+			return err
+		}
 
-			countCh <- count
+		id, err := page.ObjectID(feature)
+		if err != nil {
+			log.Event(ctx, "failed to read object id of feature", log.ERROR, log.Error(err), log.Data{"attributes": feature.Properties, "field": page.ObjectIDField})
+			indexer.Close(ctx)
+			return err
+		}
 
-			count = 0
-			geoDocs = nil
+		if err = indexer.AddWithID(ctx, id, doc); err != nil {
+			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), logData)
+			indexer.Close(ctx)
+			return err
 		}
 	}
 
-	// Capture last bulk
-	if count != 0 {
-		if _, err = esAPI.BulkRequest(ctx, indexName, geoDocs); err != nil {
-			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
-			return
-		}
+	stats, err := indexer.Close(ctx)
+	logData["stats"] = stats
+	if err != nil {
+		log.Event(ctx, "failed to finish indexing page", log.ERROR, log.Error(err), logData)
+		return err
+	}
 
-		countCh <- count
+	checkpoint.Offset = page.Next
+	checkpoint.Indexed += stats.Indexed
 
-		count = 0
-		geoDocs = nil
+	if err = checkpoint.Save(checkpointFile); err != nil {
+		log.Event(ctx, "failed to save checkpoint", log.ERROR, log.Error(err), logData)
+		return err
 	}
 
-	return
+	logData["indexed"] = checkpoint.Indexed
+	log.Event(ctx, "indexed page of features", log.INFO, logData)
+
+	return nil
 }
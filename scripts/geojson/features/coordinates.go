@@ -0,0 +1,187 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// coordinates parses nested arrays of numbers straight into typed slices. Coordinates are
+// most of a boundary file, so this avoids the reflection and repeated scanning that
+// decoding them with encoding/json costs.
+type coordinates struct {
+	b []byte
+	i int
+}
+
+func parsePosition(b []byte) ([]float64, error) {
+	c := &coordinates{b: b}
+	p, err := c.position()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, c.end()
+}
+
+func parseLine(b []byte) ([][]float64, error) {
+	c := &coordinates{b: b}
+	l, err := c.line()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, c.end()
+}
+
+func parseLines(b []byte) ([][][]float64, error) {
+	c := &coordinates{b: b}
+	l, err := c.lines()
+	if err != nil {
+		return nil, err
+	}
+
+	return l, c.end()
+}
+
+func parsePolygons(b []byte) ([][][][]float64, error) {
+	c := &coordinates{b: b}
+
+	var polygons [][][][]float64
+	err := c.array(func() error {
+		polygon, err := c.lines()
+		polygons = append(polygons, polygon)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return polygons, c.end()
+}
+
+func (c *coordinates) position() ([]float64, error) {
+	position := make([]float64, 0, 2)
+	err := c.array(func() error {
+		f, err := c.number()
+		position = append(position, f)
+		return err
+	})
+
+	return position, err
+}
+
+func (c *coordinates) line() ([][]float64, error) {
+	var line [][]float64
+	err := c.array(func() error {
+		position, err := c.position()
+		line = append(line, position)
+		return err
+	})
+
+	return line, err
+}
+
+func (c *coordinates) lines() ([][][]float64, error) {
+	var lines [][][]float64
+	err := c.array(func() error {
+		line, err := c.line()
+		lines = append(lines, line)
+		return err
+	})
+
+	return lines, err
+}
+
+// array reads an array, calling element to read each of its values
+func (c *coordinates) array(element func() error) error {
+	if err := c.expect('['); err != nil {
+		return err
+	}
+
+	c.skipSpace()
+	if c.i < len(c.b) && c.b[c.i] == ']' {
+		c.i++
+		return nil
+	}
+
+	for {
+		if err := element(); err != nil {
+			return err
+		}
+
+		c.skipSpace()
+		if c.i >= len(c.b) {
+			return errors.New("unexpected end of coordinates")
+		}
+
+		switch c.b[c.i] {
+		case ',':
+			c.i++
+		case ']':
+			c.i++
+			return nil
+		default:
+			return fmt.Errorf("unexpected %q in coordinates", c.b[c.i])
+		}
+	}
+}
+
+func (c *coordinates) number() (float64, error) {
+	c.skipSpace()
+
+	start := c.i
+	for c.i < len(c.b) && isNumberByte(c.b[c.i]) {
+		c.i++
+	}
+
+	if start == c.i {
+		if c.i >= len(c.b) {
+			return 0, errors.New("unexpected end of coordinates")
+		}
+		return 0, fmt.Errorf("unexpected %q in coordinates, expected a number", c.b[c.i])
+	}
+
+	return strconv.ParseFloat(string(c.b[start:c.i]), 64)
+}
+
+func (c *coordinates) expect(b byte) error {
+	c.skipSpace()
+
+	if c.i >= len(c.b) {
+		return errors.New("unexpected end of coordinates")
+	}
+
+	if c.b[c.i] != b {
+		return fmt.Errorf("unexpected %q in coordinates, expected %q", c.b[c.i], b)
+	}
+
+	c.i++
+	return nil
+}
+
+// end checks nothing follows the coordinates
+func (c *coordinates) end() error {
+	c.skipSpace()
+
+	if c.i != len(c.b) {
+		return fmt.Errorf("unexpected %q after coordinates", c.b[c.i])
+	}
+
+	return nil
+}
+
+func (c *coordinates) skipSpace() {
+	for c.i < len(c.b) {
+		switch c.b[c.i] {
+		case ' ', '\t', '\n', '\r':
+			c.i++
+		default:
+			return
+		}
+	}
+}
+
+func isNumberByte(b byte) bool {
+	return (b >= '0' && b <= '9') || b == '-' || b == '+' || b == '.' || b == 'e' || b == 'E'
+}
@@ -0,0 +1,43 @@
+package features
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/bng"
+)
+
+// ErrUnsupportedCRS is returned for coordinates that are neither WGS84 longitude and
+// latitude nor British National Grid eastings and northings
+var ErrUnsupportedCRS = errors.New("coordinate reference system is not supported, only WGS84 and British National Grid (EPSG:27700) are")
+
+// IsBNG reports whether the name of a coordinate reference system, e.g.
+// urn:ogc:def:crs:EPSG::27700 or OSGB_1936_British_National_Grid, is British National Grid
+func IsBNG(name string) bool {
+	name = strings.ToUpper(strings.ReplaceAll(name, "_", " "))
+
+	return strings.HasSuffix(name, ":27700") || strings.Contains(name, "BRITISH NATIONAL GRID")
+}
+
+// isWGS84 reports whether the name of a coordinate reference system is WGS84 longitude and
+// latitude, which is what geojson without a crs member uses
+func isWGS84(name string) bool {
+	name = strings.ToUpper(name)
+
+	return name == "" || strings.HasSuffix(name, "CRS84") || strings.HasSuffix(name, ":4326")
+}
+
+// FromBNG reprojects a geometry with British National Grid eastings and northings to WGS84
+// longitude and latitude, returning an error if any position cannot be converted
+func FromBNG(g *Geometry) error {
+	var err error
+	g.Transform(func(easting, northing float64) (float64, float64) {
+		lat, lon, convertErr := bng.ToWGS84(easting, northing)
+		if convertErr != nil && err == nil {
+			err = convertErr
+		}
+		return lon, lat
+	})
+
+	return err
+}
@@ -0,0 +1,230 @@
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// A list of errors returned while decoding a feature collection
+var (
+	ErrNoFeatures = errors.New("geojson is not a feature collection with a features array")
+	ErrNotFeature = errors.New("object in features array is not a feature")
+)
+
+// FeatureError is returned for a feature that was read but is not valid. Decoding can
+// carry on with the next feature.
+type FeatureError struct {
+	Index int
+	Err   error
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("feature %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the reason the feature is not valid
+func (e *FeatureError) Unwrap() error {
+	return e.Err
+}
+
+// Feature is a geojson feature. Properties hold strings, json.Number, bools, nil or
+// nested values as found in the file. Geometry is nil for features without one.
+type Feature struct {
+	Properties map[string]interface{}
+	Geometry   *Geometry
+}
+
+// rawCRS is the crs member of a feature collection, from the 2008 geojson specification,
+// which names the coordinate reference system of every geometry in the file
+type rawCRS struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Name string `json:"name"`
+	} `json:"properties"`
+}
+
+type rawFeature struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   *rawGeometry           `json:"geometry"`
+}
+
+// Decoder reads the features of a geojson feature collection one at a time, so only one
+// feature is held in memory however large the file. Geometries in British National Grid,
+// named by a crs member before the features array, are reprojected to WGS84.
+type Decoder struct {
+	dec        *json.Decoder
+	crs        string
+	inFeatures bool
+	index      int
+	err        error
+}
+
+// NewDecoder returns a decoder reading a feature collection from r
+func NewDecoder(r io.Reader) *Decoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	return &Decoder{dec: dec}
+}
+
+// Next returns the next feature, or io.EOF once every feature has been read. A
+// *FeatureError is returned for a feature that is not valid, after which Next returns the
+// feature following it. Any other error means the rest of the file cannot be read and is
+// returned again by every later call.
+func (d *Decoder) Next() (*Feature, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	if !d.inFeatures {
+		if err := d.findFeatures(); err != nil {
+			d.err = err
+			return nil, err
+		}
+		d.inFeatures = true
+	}
+
+	if !d.dec.More() {
+		// Consume the end of the features array
+		if _, err := d.dec.Token(); err != nil {
+			d.err = err
+			return nil, err
+		}
+
+		d.err = io.EOF
+		return nil, io.EOF
+	}
+
+	index := d.index
+	d.index++
+
+	var raw rawFeature
+	if err := d.dec.Decode(&raw); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			// The whole feature was read so the next one can still be decoded
+			return nil, &FeatureError{Index: index, Err: err}
+		}
+
+		d.err = fmt.Errorf("feature %d: %w", index, err)
+		return nil, d.err
+	}
+
+	if raw.Type != "Feature" {
+		return nil, &FeatureError{Index: index, Err: ErrNotFeature}
+	}
+
+	feature := &Feature{Properties: raw.Properties}
+
+	if raw.Geometry != nil {
+		geometry, err := raw.Geometry.decode()
+		if err != nil {
+			return nil, &FeatureError{Index: index, Err: err}
+		}
+
+		if IsBNG(d.crs) {
+			if err = FromBNG(geometry); err != nil {
+				return nil, &FeatureError{Index: index, Err: err}
+			}
+		}
+		feature.Geometry = geometry
+	}
+
+	return feature, nil
+}
+
+// CRS returns the name of the coordinate reference system of the feature collection, empty
+// when it does not have one and so is WGS84. It is known once the first feature is read.
+func (d *Decoder) CRS() string {
+	return d.crs
+}
+
+// findFeatures reads up to the start of the features array, keeping the crs and skipping
+// any other members of the feature collection
+func (d *Decoder) findFeatures() error {
+	if err := d.expectDelim('{'); err != nil {
+		return err
+	}
+
+	for d.dec.More() {
+		token, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case "features":
+			return d.expectDelim('[')
+		case "crs":
+			var crs *rawCRS
+			if err = d.dec.Decode(&crs); err != nil {
+				return err
+			}
+
+			if crs != nil {
+				d.crs = crs.Properties.Name
+			}
+
+			if !IsBNG(d.crs) && !isWGS84(d.crs) {
+				return fmt.Errorf("%w: %s", ErrUnsupportedCRS, d.crs)
+			}
+			continue
+		}
+
+		var skip json.RawMessage
+		if err = d.dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+
+	return ErrNoFeatures
+}
+
+func (d *Decoder) expectDelim(delim json.Delim) error {
+	token, err := d.dec.Token()
+	if err == io.EOF {
+		return ErrNoFeatures
+	}
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return ErrNoFeatures
+	}
+
+	return nil
+}
+
+// Property returns a property as a string, with numbers as they are written in the file.
+// It returns false for properties that are missing, null or not a string, number or bool.
+func (f *Feature) Property(key string) (string, bool) {
+	switch value := f.Properties[key].(type) {
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	case bool:
+		if value {
+			return "true", true
+		}
+		return "false", true
+	}
+
+	return "", false
+}
+
+// StringProperties returns every property that can be read as a string
+func (f *Feature) StringProperties() map[string]string {
+	properties := make(map[string]string, len(f.Properties))
+	for key := range f.Properties {
+		if value, ok := f.Property(key); ok {
+			properties[key] = value
+		}
+	}
+
+	return properties
+}
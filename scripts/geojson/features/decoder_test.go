@@ -0,0 +1,226 @@
+package features_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const collection = `{
+  "type": "FeatureCollection",
+  "name": "Lower_Layer_Super_Output_Areas",
+  "crs": {"type": "name", "properties": {"name": "urn:ogc:def:crs:OGC:1.3:CRS84"}},
+  "features": [
+    {"type": "Feature", "properties": {"LSOA11CD": "W01001689", "Shape__Area": 104329.5, "Welsh": true},
+     "geometry": {"type": "Polygon", "coordinates": [[[-3.19, 51.47], [-3.15, 51.47], [-3.15, 51.49], [-3.19, 51.47]]]}},
+    {"type": "Feature", "properties": {"LSOA11CD": "W01001690"}, "geometry": null},
+    {"type": "Feature", "properties": {"LSOA11CD": "W01001691"},
+     "geometry": {"type": "GeometryCollection", "geometries": [
+       {"type": "Point", "coordinates": [-3.16, 51.48]},
+       {"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 0]]], [[[2, 2], [3, 2], [3, 3], [2, 2]]]]}
+     ]}},
+    {"type": "Feature", "properties": {"LSOA11CD": "W01001692"}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0]]]}},
+    {"type": "Feature", "properties": "W01001693", "geometry": null},
+    {"type": "Feature", "properties": {"LSOA11CD": "W01001694"}, "geometry": {"type": "Circle", "coordinates": [0, 0]}},
+    {"type": "Feature", "properties": {"LSOA11CD": "W01001695"}, "geometry": {"type": "Point", "coordinates": ["0", "0"]}},
+    {"type": "Feature", "properties": {"LSOA11CD": null}, "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}}
+  ]
+}`
+
+func TestDecoder(t *testing.T) {
+	Convey("Given a feature collection", t, func() {
+		decoder := features.NewDecoder(strings.NewReader(collection))
+
+		Convey("When every feature is read", func() {
+			var (
+				read   []*features.Feature
+				errs   []*features.FeatureError
+				err    error
+				reads  int
+				closed bool
+			)
+
+			for reads = 0; reads < 20; reads++ {
+				var feature *features.Feature
+				feature, err = decoder.Next()
+				if err == io.EOF {
+					closed = true
+					break
+				}
+
+				var featureErr *features.FeatureError
+				if errors.As(err, &featureErr) {
+					errs = append(errs, featureErr)
+					continue
+				}
+				So(err, ShouldBeNil)
+
+				read = append(read, feature)
+			}
+
+			Convey("Then valid features are returned in order followed by the end of the file", func() {
+				So(closed, ShouldBeTrue)
+				So(read, ShouldHaveLength, 4)
+
+				_, err = decoder.Next()
+				So(err, ShouldEqual, io.EOF)
+			})
+
+			Convey("Then numbers and bools can be read as strings", func() {
+				code, ok := read[0].Property("LSOA11CD")
+				So(ok, ShouldBeTrue)
+				So(code, ShouldEqual, "W01001689")
+
+				So(read[0].StringProperties(), ShouldResemble, map[string]string{
+					"LSOA11CD":    "W01001689",
+					"Shape__Area": "104329.5",
+					"Welsh":       "true",
+				})
+			})
+
+			Convey("Then coordinates are typed by geometry", func() {
+				So(read[0].Geometry.Type, ShouldEqual, "Polygon")
+				So(read[0].Geometry.Coordinates, ShouldResemble, [][][]float64{{{-3.19, 51.47}, {-3.15, 51.47}, {-3.15, 51.49}, {-3.19, 51.47}}})
+				So(read[3].Geometry.Coordinates, ShouldResemble, [][]float64{{0, 0}, {1, 1}})
+			})
+
+			Convey("Then a null geometry is returned as nil", func() {
+				So(read[1].Geometry, ShouldBeNil)
+			})
+
+			Convey("Then the members of a geometry collection are returned", func() {
+				So(read[2].Geometry.Type, ShouldEqual, "GeometryCollection")
+				So(read[2].Geometry.Geometries, ShouldHaveLength, 2)
+				So(read[2].Geometry.Geometries[0].Coordinates, ShouldResemble, []float64{-3.16, 51.48})
+				So(read[2].Geometry.Geometries[1].Coordinates, ShouldHaveLength, 2)
+			})
+
+			Convey("Then null properties cannot be read as strings", func() {
+				_, ok := read[3].Property("LSOA11CD")
+				So(ok, ShouldBeFalse)
+			})
+
+			Convey("Then features that are not valid are returned as errors with their position", func() {
+				So(errs, ShouldHaveLength, 4)
+				So(errs[0].Index, ShouldEqual, 3)
+				So(errors.Is(errs[0], features.ErrInvalidCoordinates), ShouldBeTrue)
+				So(errs[1].Index, ShouldEqual, 4)
+				So(errs[2].Index, ShouldEqual, 5)
+				So(errors.Is(errs[2], features.ErrUnknownGeometry), ShouldBeTrue)
+				So(errs[3].Index, ShouldEqual, 6)
+				So(errors.Is(errs[3], features.ErrInvalidCoordinates), ShouldBeTrue)
+			})
+		})
+	})
+
+	Convey("Given json that is not a feature collection", t, func() {
+		decoder := features.NewDecoder(strings.NewReader(`{"type": "Feature", "geometry": null}`))
+
+		Convey("When a feature is read", func() {
+			_, err := decoder.Next()
+
+			Convey("Then no features is returned", func() {
+				So(err, ShouldEqual, features.ErrNoFeatures)
+			})
+		})
+	})
+
+	Convey("Given a feature collection that is cut short", t, func() {
+		decoder := features.NewDecoder(strings.NewReader(`{"features": [{"type": "Feature", "geometry": null}, {"type": "Feat`))
+
+		Convey("When every feature is read", func() {
+			_, err := decoder.Next()
+			So(err, ShouldBeNil)
+
+			_, err = decoder.Next()
+
+			Convey("Then an error is returned and returned again on the next read", func() {
+				So(err, ShouldNotBeNil)
+
+				var featureErr *features.FeatureError
+				So(errors.As(err, &featureErr), ShouldBeFalse)
+
+				_, again := decoder.Next()
+				So(again, ShouldEqual, err)
+			})
+		})
+	})
+
+	Convey("Given a feature collection in British National Grid", t, func() {
+		decoder := features.NewDecoder(strings.NewReader(`{
+		  "crs": {"type": "name", "properties": {"name": "urn:ogc:def:crs:EPSG::27700"}},
+		  "features": [{"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [651409.903, 313177.270]}}]
+		}`))
+
+		Convey("When a feature is read", func() {
+			feature, err := decoder.Next()
+
+			Convey("Then its geometry is reprojected to WGS84 longitude and latitude", func() {
+				So(err, ShouldBeNil)
+				So(decoder.CRS(), ShouldEqual, "urn:ogc:def:crs:EPSG::27700")
+
+				position := feature.Geometry.Coordinates.([]float64)
+				So(position[0], ShouldAlmostEqual, 1.71605, 0.00001)
+				So(position[1], ShouldAlmostEqual, 52.65798, 0.00001)
+			})
+		})
+	})
+
+	Convey("Given a feature collection in some other projection", t, func() {
+		decoder := features.NewDecoder(strings.NewReader(`{
+		  "crs": {"type": "name", "properties": {"name": "urn:ogc:def:crs:EPSG::3857"}},
+		  "features": []
+		}`))
+
+		Convey("When a feature is read", func() {
+			_, err := decoder.Next()
+
+			Convey("Then unsupported crs is returned", func() {
+				So(errors.Is(err, features.ErrUnsupportedCRS), ShouldBeTrue)
+			})
+		})
+	})
+}
+
+// benchmarkCollection builds a feature collection of polygons shaped like the output area
+// boundaries, with numeric properties and a few hundred positions each
+func benchmarkCollection(n int) string {
+	var b strings.Builder
+
+	b.WriteString(`{"type": "FeatureCollection", "features": [`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+
+		b.WriteString(`{"type": "Feature", "properties": {"OA11CD": "E00000001", "LAD11CD": "E09000001", "Shape__Area": 6949.151, "Shape__Length": 421.166}, "geometry": {"type": "Polygon", "coordinates": [[`)
+		for j := 0; j < 300; j++ {
+			b.WriteString(`[-0.0974361284891239, 51.5205109907813],`)
+		}
+		b.WriteString(`[-0.0974361284891239, 51.5205109907813]]]}}`)
+	}
+	b.WriteString(`]}`)
+
+	return b.String()
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	data := benchmarkCollection(1000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		decoder := features.NewDecoder(strings.NewReader(data))
+		for {
+			if _, err := decoder.Next(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
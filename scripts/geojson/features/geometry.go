@@ -0,0 +1,164 @@
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// A list of errors returned for geometries that are not valid
+var (
+	ErrInvalidCoordinates = errors.New("geometry coordinates are not valid")
+	ErrUnknownGeometry    = errors.New("unknown geometry type")
+)
+
+// Geometry is a geojson geometry. Coordinates are []float64 for a Point, [][]float64 for a
+// MultiPoint or LineString, [][][]float64 for a Polygon or MultiLineString and
+// [][][][]float64 for a MultiPolygon. A GeometryCollection has Geometries instead.
+type Geometry struct {
+	Type        string
+	Coordinates interface{}
+	Geometries  []*Geometry
+}
+
+type rawGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometries  []*rawGeometry  `json:"geometries"`
+}
+
+func (g *rawGeometry) decode() (*Geometry, error) {
+	geometry := &Geometry{Type: g.Type}
+
+	var err error
+	switch g.Type {
+	case "Point":
+		var c []float64
+		if c, err = parsePosition(g.Coordinates); err == nil {
+			err = checkPosition(c)
+		}
+		geometry.Coordinates = c
+	case "MultiPoint", "LineString":
+		var c [][]float64
+		if c, err = parseLine(g.Coordinates); err == nil {
+			err = checkLine(c, g.Type == "LineString")
+		}
+		geometry.Coordinates = c
+	case "Polygon", "MultiLineString":
+		var c [][][]float64
+		if c, err = parseLines(g.Coordinates); err == nil {
+			err = checkLines(c, g.Type == "Polygon")
+		}
+		geometry.Coordinates = c
+	case "MultiPolygon":
+		var c [][][][]float64
+		if c, err = parsePolygons(g.Coordinates); err == nil {
+			for i := 0; i < len(c) && err == nil; i++ {
+				err = checkLines(c[i], true)
+			}
+		}
+		geometry.Coordinates = c
+	case "GeometryCollection":
+		for i, raw := range g.Geometries {
+			if raw == nil {
+				return nil, fmt.Errorf("geometry %d of collection: %w", i, ErrUnknownGeometry)
+			}
+
+			member, err := raw.decode()
+			if err != nil {
+				return nil, fmt.Errorf("geometry %d of collection: %w", i, err)
+			}
+			geometry.Geometries = append(geometry.Geometries, member)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownGeometry, g.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", g.Type, ErrInvalidCoordinates, err)
+	}
+
+	return geometry, nil
+}
+
+func checkPosition(position []float64) error {
+	if len(position) < 2 {
+		return errors.New("position needs at least two numbers")
+	}
+
+	return nil
+}
+
+func checkLine(line [][]float64, isLine bool) error {
+	if isLine && len(line) < 2 {
+		return errors.New("line needs at least two positions")
+	}
+
+	for _, position := range line {
+		if err := checkPosition(position); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkLines checks each line, or each ring of a polygon which must have four or more
+// positions and end where it starts
+func checkLines(lines [][][]float64, isPolygon bool) error {
+	if isPolygon && len(lines) == 0 {
+		return errors.New("polygon needs at least one ring")
+	}
+
+	for _, line := range lines {
+		if err := checkLine(line, true); err != nil {
+			return err
+		}
+
+		if !isPolygon {
+			continue
+		}
+
+		first, last := line[0], line[len(line)-1]
+		if len(line) < 4 || first[0] != last[0] || first[1] != last[1] {
+			return errors.New("polygon ring must have at least four positions and be closed")
+		}
+	}
+
+	return nil
+}
+
+// Transform replaces the x and y of every position in the geometry with those returned by
+// fn. Any further values of a position, such as its height, are kept.
+func (g *Geometry) Transform(fn func(x, y float64) (float64, float64)) {
+	position := func(p []float64) {
+		p[0], p[1] = fn(p[0], p[1])
+	}
+	line := func(l [][]float64) {
+		for _, p := range l {
+			position(p)
+		}
+	}
+	lines := func(ls [][][]float64) {
+		for _, l := range ls {
+			line(l)
+		}
+	}
+
+	switch c := g.Coordinates.(type) {
+	case []float64:
+		position(c)
+	case [][]float64:
+		line(c)
+	case [][][]float64:
+		lines(c)
+	case [][][][]float64:
+		for _, ls := range c {
+			lines(ls)
+		}
+	}
+
+	for _, member := range g.Geometries {
+		member.Transform(fn)
+	}
+}
@@ -0,0 +1,108 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoRings is returned when there are no rings to build a polygon from
+var ErrNoRings = errors.New("polygon has no rings")
+
+// FromRings builds a Polygon or MultiPolygon from the rings of a shapefile or Esri polygon.
+// These list every ring of every part together, telling outer rings and holes apart by
+// winding: outer rings are clockwise and holes anticlockwise. Each hole is given to the
+// smallest outer ring containing it, and rings are rewound to the geojson convention of
+// anticlockwise outer rings and clockwise holes.
+func FromRings(rings [][][]float64) (*Geometry, error) {
+	type polygon struct {
+		rings [][][]float64
+		area  float64
+	}
+
+	var (
+		polygons []*polygon
+		holes    [][][]float64
+	)
+
+	for _, ring := range rings {
+		if err := checkLines([][][]float64{ring}, true); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCoordinates, err)
+		}
+
+		area := signedArea(ring)
+		if area < 0 {
+			polygons = append(polygons, &polygon{rings: [][][]float64{reversed(ring)}, area: -area})
+		} else {
+			holes = append(holes, reversed(ring))
+		}
+	}
+
+	for _, hole := range holes {
+		var outer *polygon
+		for _, p := range polygons {
+			if (outer == nil || p.area < outer.area) && contains(p.rings[0], hole[0]) {
+				outer = p
+			}
+		}
+
+		if outer != nil {
+			outer.rings = append(outer.rings, hole)
+			continue
+		}
+
+		// A hole outside every outer ring is wound the wrong way rather than being a hole,
+		// so it becomes a polygon of its own
+		polygons = append(polygons, &polygon{rings: [][][]float64{reversed(hole)}})
+	}
+
+	switch len(polygons) {
+	case 0:
+		return nil, ErrNoRings
+	case 1:
+		return &Geometry{Type: "Polygon", Coordinates: polygons[0].rings}, nil
+	}
+
+	multiPolygon := make([][][][]float64, len(polygons))
+	for i, p := range polygons {
+		multiPolygon[i] = p.rings
+	}
+
+	return &Geometry{Type: "MultiPolygon", Coordinates: multiPolygon}, nil
+}
+
+// signedArea is positive for anticlockwise rings and negative for clockwise rings
+func signedArea(ring [][]float64) float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+
+	return area / 2
+}
+
+// contains reports whether a point is inside a ring, by counting the edges a ray from the
+// point crosses
+func contains(ring [][]float64, point []float64) bool {
+	x, y := point[0], point[1]
+
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+func reversed(ring [][]float64) [][]float64 {
+	r := make([][]float64, len(ring))
+	for i, position := range ring {
+		r[len(ring)-1-i] = position
+	}
+
+	return r
+}
@@ -0,0 +1,72 @@
+package features_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFromRings(t *testing.T) {
+	// Rings wound as in shapefiles, outer rings clockwise and holes anticlockwise
+	outer := [][]float64{{0, 0}, {0, 10}, {10, 10}, {10, 0}, {0, 0}}
+	hole := [][]float64{{2, 2}, {4, 2}, {4, 4}, {2, 4}, {2, 2}}
+	island := [][]float64{{20, 0}, {20, 5}, {25, 5}, {25, 0}, {20, 0}}
+	islandHole := [][]float64{{21, 1}, {22, 1}, {22, 2}, {21, 2}, {21, 1}}
+
+	Convey("Given the rings of a polygon with a hole", t, func() {
+		geometry, err := features.FromRings([][][]float64{outer, hole})
+
+		Convey("Then a polygon is returned wound anticlockwise with a clockwise hole", func() {
+			So(err, ShouldBeNil)
+			So(geometry.Type, ShouldEqual, "Polygon")
+			So(geometry.Coordinates, ShouldResemble, [][][]float64{
+				{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+				{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+			})
+		})
+	})
+
+	Convey("Given the rings of several parts with holes listed after every outer ring", t, func() {
+		geometry, err := features.FromRings([][][]float64{outer, island, islandHole, hole})
+
+		Convey("Then a multipolygon is returned with each hole in the part containing it", func() {
+			So(err, ShouldBeNil)
+			So(geometry.Type, ShouldEqual, "MultiPolygon")
+
+			polygons := geometry.Coordinates.([][][][]float64)
+			So(polygons, ShouldHaveLength, 2)
+			So(polygons[0], ShouldHaveLength, 2)
+			So(polygons[0][1][0], ShouldResemble, []float64{2, 2})
+			So(polygons[1], ShouldHaveLength, 2)
+			So(polygons[1][1][0], ShouldResemble, []float64{21, 1})
+		})
+	})
+
+	Convey("Given a ring wound as a hole that is outside every outer ring", t, func() {
+		geometry, err := features.FromRings([][][]float64{outer, {{30, 0}, {35, 0}, {35, 5}, {30, 5}, {30, 0}}})
+
+		Convey("Then it is returned as a polygon of its own", func() {
+			So(err, ShouldBeNil)
+			So(geometry.Type, ShouldEqual, "MultiPolygon")
+			So(geometry.Coordinates, ShouldHaveLength, 2)
+		})
+	})
+
+	Convey("Given a ring that is not closed", t, func() {
+		_, err := features.FromRings([][][]float64{{{0, 0}, {0, 10}, {10, 10}, {10, 0}}})
+
+		Convey("Then invalid coordinates is returned", func() {
+			So(errors.Is(err, features.ErrInvalidCoordinates), ShouldBeTrue)
+		})
+	})
+
+	Convey("Given no rings", t, func() {
+		_, err := features.FromRings(nil)
+
+		Convey("Then no rings is returned", func() {
+			So(err, ShouldEqual, features.ErrNoRings)
+		})
+	})
+}
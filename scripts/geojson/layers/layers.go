@@ -0,0 +1,231 @@
+package layers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/models"
+)
+
+// A list of errors returned for an invalid manifest
+var (
+	ErrMissingCodeKey   = errors.New("layer must set the property holding the area code")
+	ErrMissingFiles     = errors.New("layer must list at least one file")
+	ErrMissingHierarchy = errors.New("layer must set a hierarchy")
+	ErrMissingIndex     = errors.New("layer must set the index to load into")
+	ErrUnknownLayer     = errors.New("layer not found in manifest")
+)
+
+// Manifest lists the geojson layers that can be loaded
+type Manifest struct {
+	Layers []Layer `json:"layers"`
+
+	dir string
+}
+
+// Layer describes how the features of a set of geojson files become documents. Fields maps
+// the json name of a document field, e.g. lsoa11nm, to the feature property it is read from.
+type Layer struct {
+	Name      string            `json:"name"`
+	Files     []string          `json:"files"`
+	Index     string            `json:"index"`
+	Hierarchy string            `json:"hierarchy"`
+	CodeKey   string            `json:"code_key"`
+	NameKey   string            `json:"name_key,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+
+	dir string
+}
+
+// docFields holds the kind of each field of a document by its json name
+var docFields = fieldKinds(reflect.TypeOf(models.GeoDoc{}))
+
+// LoadManifest reads and validates a manifest. File globs are relative to the manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err = json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	manifest.dir = filepath.Dir(path)
+
+	for i := range manifest.Layers {
+		manifest.Layers[i].dir = manifest.dir
+
+		if err = manifest.Layers[i].Validate(); err != nil {
+			return nil, fmt.Errorf("%s: layer %q: %w", path, manifest.Layers[i].Name, err)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// Select returns the named layers in the order given, or every layer if none are named
+func (m *Manifest) Select(names []string) ([]Layer, error) {
+	if len(names) == 0 {
+		return m.Layers, nil
+	}
+
+	var selected []Layer
+	for _, name := range names {
+		found := false
+		for _, layer := range m.Layers {
+			if layer.Name == name {
+				selected = append(selected, layer)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownLayer, name)
+		}
+	}
+
+	return selected, nil
+}
+
+// Validate checks a layer has what is needed to build documents and that every field it
+// maps is a document field
+func (l Layer) Validate() error {
+	switch {
+	case len(l.Files) == 0:
+		return ErrMissingFiles
+	case l.Index == "":
+		return ErrMissingIndex
+	case l.Hierarchy == "":
+		return ErrMissingHierarchy
+	case l.CodeKey == "":
+		return ErrMissingCodeKey
+	}
+
+	for field := range l.Fields {
+		kind, ok := docFields[field]
+		if !ok || field == "location" {
+			return fmt.Errorf("unknown document field %q", field)
+		}
+
+		if kind != reflect.String && kind != reflect.Float64 {
+			return fmt.Errorf("document field %q cannot be read from a property", field)
+		}
+	}
+
+	return nil
+}
+
+// Paths returns the files matching the layer's globs, sorted and without duplicates
+func (l Layer) Paths() ([]string, error) {
+	seen := make(map[string]bool)
+	var paths []string
+
+	for _, pattern := range l.Files {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(l.dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %s", pattern)
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				paths = append(paths, match)
+			}
+		}
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// Doc builds the document for a feature from its properties, which are strings as read
+// from the geojson, and its location
+func (l Layer) Doc(properties map[string]string, location models.GeoLocation) (*models.GeoDoc, error) {
+	fields := map[string]interface{}{
+		"code":      properties[l.CodeKey],
+		"hierarchy": l.Hierarchy,
+	}
+
+	if l.NameKey != "" {
+		fields["name"] = properties[l.NameKey]
+	}
+
+	for field, key := range l.Fields {
+		value, ok := properties[key]
+		if !ok || value == "" {
+			continue
+		}
+
+		if docFields[field] != reflect.Float64 {
+			fields[field] = value
+			continue
+		}
+
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", key, err)
+		}
+		fields[field] = f
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc models.GeoDoc
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	doc.Location = location
+
+	return &doc, nil
+}
+
+// Location converts the geometry of a feature to the location stored on documents
+func Location(geometry *features.Geometry) models.GeoLocation {
+	location := models.GeoLocation{
+		Type:        geometry.Type,
+		Coordinates: geometry.Coordinates,
+	}
+
+	for _, member := range geometry.Geometries {
+		location.Geometries = append(location.Geometries, Location(member))
+	}
+
+	return location
+}
+
+func fieldKinds(t reflect.Type) map[string]reflect.Kind {
+	kinds := make(map[string]reflect.Kind)
+
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			kinds[name] = t.Field(i).Type.Kind()
+		}
+	}
+
+	return kinds
+}
@@ -0,0 +1,197 @@
+package layers_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/layers"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/models"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const manifest = `{
+  "layers": [
+    {
+      "name": "2011-lsoa",
+      "files": ["data/lsoa_*.geojson"],
+      "index": "test_geo",
+      "hierarchy": "lsoa",
+      "code_key": "LSOA11CD",
+      "name_key": "LSOA11NM",
+      "fields": {"lsoa11nm": "LSOA11NM", "shape_area": "Shape__Area"}
+    },
+    {
+      "name": "2015-tcity",
+      "files": ["data/tcity.geojson"],
+      "index": "test_geo",
+      "hierarchy": "tcity",
+      "code_key": "tcity15cd",
+      "name_key": "tcity15nm"
+    }
+  ]
+}`
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "layers.json"), manifest)
+	writeFile(t, filepath.Join(dir, "data", "lsoa_b.geojson"), "{}")
+	writeFile(t, filepath.Join(dir, "data", "lsoa_a.geojson"), "{}")
+
+	Convey("Given a manifest of layers", t, func() {
+		m, err := layers.LoadManifest(filepath.Join(dir, "layers.json"))
+		So(err, ShouldBeNil)
+		So(m.Layers, ShouldHaveLength, 2)
+
+		Convey("When no layers are selected", func() {
+			selected, err := m.Select(nil)
+
+			Convey("Then every layer is returned", func() {
+				So(err, ShouldBeNil)
+				So(selected, ShouldHaveLength, 2)
+			})
+		})
+
+		Convey("When a layer is selected by name", func() {
+			selected, err := m.Select([]string{"2015-tcity"})
+
+			Convey("Then only that layer is returned", func() {
+				So(err, ShouldBeNil)
+				So(selected, ShouldHaveLength, 1)
+				So(selected[0].Hierarchy, ShouldEqual, "tcity")
+			})
+		})
+
+		Convey("When a layer that is not in the manifest is selected", func() {
+			_, err := m.Select([]string{"2021-oa"})
+
+			Convey("Then unknown layer is returned", func() {
+				So(errors.Is(err, layers.ErrUnknownLayer), ShouldBeTrue)
+			})
+		})
+
+		Convey("When the files of a layer are listed", func() {
+			paths, err := m.Layers[0].Paths()
+
+			Convey("Then the files matching its globs are returned relative to the manifest in order", func() {
+				So(err, ShouldBeNil)
+				So(paths, ShouldResemble, []string{
+					filepath.Join(dir, "data", "lsoa_a.geojson"),
+					filepath.Join(dir, "data", "lsoa_b.geojson"),
+				})
+			})
+		})
+
+		Convey("When a glob matches no files", func() {
+			_, err := m.Layers[1].Paths()
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Given a layer", t, func() {
+		layer := layers.Layer{
+			Name:      "2011-lsoa",
+			Files:     []string{"lsoa.geojson"},
+			Index:     "test_geo",
+			Hierarchy: "lsoa",
+			CodeKey:   "LSOA11CD",
+		}
+
+		Convey("When it has everything needed to build documents", func() {
+			Convey("Then it is valid", func() {
+				So(layer.Validate(), ShouldBeNil)
+			})
+		})
+
+		Convey("When it has no code key", func() {
+			layer.CodeKey = ""
+
+			Convey("Then missing code key is returned", func() {
+				So(layer.Validate(), ShouldEqual, layers.ErrMissingCodeKey)
+			})
+		})
+
+		Convey("When it maps a property to a field documents do not have", func() {
+			layer.Fields = map[string]string{"population": "POP"}
+
+			Convey("Then an error is returned", func() {
+				So(layer.Validate(), ShouldNotBeNil)
+			})
+		})
+
+		Convey("When it maps a property to the location", func() {
+			layer.Fields = map[string]string{"location": "geometry"}
+
+			Convey("Then an error is returned", func() {
+				So(layer.Validate(), ShouldNotBeNil)
+			})
+		})
+	})
+}
+
+func TestDoc(t *testing.T) {
+	Convey("Given a layer mapping feature properties to document fields", t, func() {
+		layer := layers.Layer{
+			Hierarchy: "lsoa",
+			CodeKey:   "LSOA11CD",
+			NameKey:   "LSOA11NM",
+			Fields: map[string]string{
+				"lsoa11nm":   "LSOA11NM",
+				"lsoa11nmw":  "LSOA11NMW",
+				"shape_area": "Shape__Area",
+			},
+		}
+
+		location := models.GeoLocation{Type: "Polygon", Coordinates: [][][]float64{{{-3.19, 51.47}}}}
+
+		Convey("When a document is built from a feature", func() {
+			doc, err := layer.Doc(map[string]string{
+				"LSOA11CD":    "W01001689",
+				"LSOA11NM":    "Cardiff 032A",
+				"LSOA11NMW":   "",
+				"Shape__Area": "104329.5",
+			}, location)
+
+			Convey("Then the document holds the mapped properties", func() {
+				So(err, ShouldBeNil)
+				So(doc.Code, ShouldEqual, "W01001689")
+				So(doc.Name, ShouldEqual, "Cardiff 032A")
+				So(doc.Hierarchy, ShouldEqual, "lsoa")
+				So(doc.LSOA11NM, ShouldEqual, "Cardiff 032A")
+				So(doc.LSOA11NMW, ShouldBeEmpty)
+				So(doc.ShapeArea, ShouldEqual, 104329.5)
+				So(doc.Location, ShouldResemble, location)
+			})
+		})
+
+		Convey("When a numeric property is not a number", func() {
+			_, err := layer.Doc(map[string]string{"LSOA11CD": "W01001689", "Shape__Area": "large"}, location)
+
+			Convey("Then an error is returned", func() {
+				So(err, ShouldNotBeNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/layers"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/shapefile"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	defaultManifest = "geojson/layers.json"
+)
+
+var (
+	countCh             = make(chan int)
+	polygonCountCh      = make(chan int)
+	multiPolygonCountCh = make(chan int)
+)
+
+func main() {
+	ctx := context.Background()
+
+	manifestFile := flag.String("manifest", defaultManifest, "file listing the geojson layers that can be loaded")
+	layerNames := flag.String("layers", "", "comma separated names of the layers to load, all layers in the manifest if empty")
+	flag.Parse()
+
+	manifest, err := layers.LoadManifest(*manifestFile)
+	if err != nil {
+		log.Event(ctx, "failed to read layer manifest", log.FATAL, log.Error(err), log.Data{"manifest": *manifestFile})
+		os.Exit(1)
+	}
+
+	var names []string
+	if *layerNames != "" {
+		names = strings.Split(*layerNames, ",")
+	}
+
+	selected, err := manifest.Select(names)
+	if err != nil {
+		log.Event(ctx, "failed to select layers to load", log.FATAL, log.Error(err), log.Data{"manifest": *manifestFile})
+		os.Exit(1)
+	}
+
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to detect elasticsearch version", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	if cfg.BulkDeadLetterFile != "" {
+		deadLetter, err := es.NewDeadLetterFile(cfg.BulkDeadLetterFile)
+		if err != nil {
+			log.Event(ctx, "failed to open dead letter file", log.FATAL, log.Error(err), log.Data{"file": cfg.BulkDeadLetterFile})
+			os.Exit(1)
+		}
+		defer deadLetter.Close()
+
+		esAPI.SetDeadLetter(deadLetter)
+	}
+
+	for _, layer := range selected {
+		if err = loadLayer(ctx, esAPI, cfg, layer); err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// loadLayer loads every file of a layer into the version of its index created by the refresh script
+func loadLayer(ctx context.Context, esAPI *es.API, cfg *config.Config, layer layers.Layer) error {
+	logData := log.Data{"layer": layer.Name, "alias": layer.Index}
+
+	paths, err := layer.Paths()
+	if err != nil {
+		log.Event(ctx, "failed to find files for layer", log.FATAL, log.Error(err), logData)
+		return err
+	}
+
+	indexName, err := esAPI.BuildIndex(ctx, layer.Index)
+	if err != nil {
+		log.Event(ctx, "failed to find new version of index, run the refresh script first", log.FATAL, log.Error(err), logData)
+		return err
+	}
+
+	logData["index"] = indexName
+
+	indexer := esAPI.NewBulkIndexer(ctx, es.BulkIndexerConfig{
+		Index:          indexName,
+		Workers:        cfg.BulkWorkers,
+		FlushBytes:     cfg.BulkFlushBytes,
+		FlushDocuments: cfg.BulkFlushDocuments,
+		FlushInterval:  cfg.BulkFlushInterval,
+	})
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go trackCounts(ctx, indexer, done)
+
+	for _, path := range paths {
+		logData["file"] = path
+
+		reader, closer, err := openFeatures(path)
+		if err != nil {
+			log.Event(ctx, "failed to open boundary file", log.FATAL, log.Error(err), logData)
+			return err
+		}
+
+		log.Event(ctx, "about to store docs in elastic search", log.INFO, logData)
+
+		// Iterate items for individual geo boundaries and store documents in elasticsearch
+		err = storeDocs(ctx, indexer, reader, layer)
+		closer.Close()
+		if err != nil {
+			log.Event(ctx, "failed to store layer in elasticsearch", log.FATAL, log.Error(err), logData)
+			return err
+		}
+	}
+
+	delete(logData, "file")
+
+	stats, err := indexer.Close(ctx)
+	logData["stats"] = stats
+	if err != nil {
+		log.Event(ctx, "failed to finish indexing documents", log.FATAL, log.Error(err), logData)
+		return err
+	}
+
+	log.Event(ctx, "successfully added "+layer.Name+" data to "+indexName+" index", log.INFO, logData)
+
+	return nil
+}
+
+// featureReader is implemented by the geojson decoder and the shapefile reader
+type featureReader interface {
+	Next() (*features.Feature, error)
+}
+
+// openFeatures opens a boundary file for reading its features, as a shapefile for .shp
+// and .zip files and as geojson otherwise
+func openFeatures(path string) (featureReader, io.Closer, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".shp", ".zip":
+		r, err := shapefile.Open(path)
+		return r, r, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return features.NewDecoder(bufio.NewReaderSize(f, 65536)), f, nil
+}
+
+// trackCounts logs the number of features read alongside the indexer totals until done is closed
+func trackCounts(ctx context.Context, indexer *es.BulkIndexer, done chan struct{}) {
+	var (
+		totalCounter        = 0
+		polygonCounter      = 0
+		multiPolygonCounter = 0
+	)
+
+	t := time.NewTicker(5 * time.Second)
+	defer t.Stop()
+
+	for {
+		select {
+		case n := <-countCh:
+			totalCounter += n
+		case n := <-polygonCountCh:
+			polygonCounter += n
+		case n := <-multiPolygonCountCh:
+			multiPolygonCounter += n
+		case <-t.C:
+			stats := indexer.Stats()
+			log.Event(ctx, "Total read: "+strconv.Itoa(totalCounter)+" | Indexed: "+strconv.Itoa(stats.Indexed)+" | Failed: "+strconv.Itoa(stats.Failed)+" | Polygons: "+strconv.Itoa(polygonCounter)+" | MultiPolygons: "+strconv.Itoa(multiPolygonCounter), log.INFO)
+		case <-done:
+			return
+		}
+	}
+}
+
+func storeDocs(ctx context.Context, indexer *es.BulkIndexer, reader featureReader, layer layers.Layer) error {
+	count := 0
+	polygonCount := 0
+	multiPolygonCount := 0
+
+	// Iterate through the records
+	for {
+		feature, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		var featureErr *features.FeatureError
+		if errors.As(err, &featureErr) {
+			log.Event(ctx, "skipping feature that is not valid", log.WARN, log.Error(err), log.Data{"feature": featureErr.Index})
+			continue
+		}
+		if err != nil {
+			log.Event(ctx, "failed to read feature", log.ERROR, log.Error(err))
+			return err
+		}
+
+		if feature.Geometry == nil {
+			log.Event(ctx, "skipping feature without a geometry", log.WARN, log.Data{"properties": feature.Properties})
+			continue
+		}
+
+		count++
+
+		if feature.Geometry.Type == "MultiPolygon" {
+			multiPolygonCount++
+		} else {
+			polygonCount++
+		}
+
+		newDoc, err := layer.Doc(feature.StringProperties(), layers.Location(feature.Geometry))
+		if err != nil {
+			log.Event(ctx, "failed to create document from feature properties", log.ERROR, log.Error(err), log.Data{"count": count})
+			return err
+		}
+
+		if err = indexer.Add(ctx, newDoc); err != nil {
+			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
+			return err
+		}
+
+		if count == 100 {
+			countCh <- count
+			polygonCountCh <- polygonCount
+			multiPolygonCountCh <- multiPolygonCount
+
+			count = 0
+			polygonCount = 0
+			multiPolygonCount = 0
+		}
+	}
+
+	// Capture last counts, the indexer sends any remaining documents when closed
+	if count != 0 {
+		countCh <- count
+		polygonCountCh <- polygonCount
+		multiPolygonCountCh <- multiPolygonCount
+	}
+
+	return nil
+}
@@ -23,6 +23,7 @@ type GeoDoc struct {
 }
 
 type GeoLocation struct {
-	Type        string      `json:"type"`
-	Coordinates interface{} `json:"coordinates"`
+	Type        string        `json:"type"`
+	Coordinates interface{}   `json:"coordinates,omitempty"`
+	Geometries  []GeoLocation `json:"geometries,omitempty"`
 }
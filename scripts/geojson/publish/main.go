@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
+	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
+	"github.com/ONSdigital/log.go/log"
+)
+
+const (
+	geoFileIndex = "test_geo"
+)
+
+func main() {
+	ctx := context.Background()
+
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
+	if err != nil {
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+
+	indexName, err := esAPI.BuildIndex(ctx, geoFileIndex)
+	if err != nil {
+		log.Event(ctx, "failed to find new version of index", log.FATAL, log.Error(err), log.Data{"alias": geoFileIndex})
+		os.Exit(1)
+	}
+
+	// a new version should not hold fewer documents than the one it replaces
+	minDocuments := 1
+	if current, err := esAPI.AliasedIndices(ctx, geoFileIndex); err == nil && len(current) == 1 {
+		if minDocuments, err = esAPI.CountDocuments(ctx, current[0]); err != nil {
+			log.Event(ctx, "failed to count documents in current index", log.FATAL, log.Error(err), log.Data{"index": current[0]})
+			os.Exit(1)
+		}
+	}
+
+	if err = esAPI.PublishIndex(ctx, geoFileIndex, indexName, minDocuments, cfg.IndexVersionsToKeep); err != nil {
+		log.Event(ctx, "failed to publish index", log.FATAL, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	log.Event(ctx, "successfully published "+indexName+" as "+geoFileIndex, log.INFO)
+}
@@ -2,43 +2,47 @@ package main
 
 import (
 	"context"
-	"net/http"
 	"os"
 
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
 	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
-	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
 )
 
 const (
-	elasticsearchAPIURL = "http://localhost:9200"
-	geoFileIndex        = "test_geo"
-	mappingsFile        = "geography-mappings.json"
+	geoFileIndex = "test_geo"
+	mappingsFile = "geography-mappings.json"
 )
 
 func main() {
 	ctx := context.Background()
 
-	cli := dphttp.NewClient()
-	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
-	// delete existing elasticsearch index if already exists
-	status, err := esAPI.DeleteSearchIndex(ctx, geoFileIndex)
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
 	if err != nil {
-		if status != http.StatusNotFound {
-			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
-			os.Exit(1)
-		}
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
 
-		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to detect elasticsearch version", log.FATAL, log.Error(err))
+		os.Exit(1)
 	}
 
-	// create elasticsearch index with settings/mapping
-	status, err = esAPI.CreateSearchIndex(ctx, geoFileIndex, mappingsFile)
+	// create a new version of the index with settings/mapping for the geojson scripts to
+	// load into, the alias keeps pointing at the current version until it is published
+	indexName, status, err := esAPI.CreateIndexVersion(ctx, geoFileIndex, mappingsFile)
 	if err != nil {
 		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
 		os.Exit(1)
 	}
 
-	log.Event(ctx, "successfully refreshed "+geoFileIndex+" index", log.INFO)
+	log.Event(ctx, "successfully created new version of "+geoFileIndex+" index", log.INFO, log.Data{"index": indexName})
 }
@@ -0,0 +1,339 @@
+package shapefile
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	shp "github.com/jonas-p/go-shp"
+)
+
+// A list of errors returned when opening a shapefile
+var (
+	ErrMissingDBF            = errors.New("shapefile has no .dbf file of attributes")
+	ErrNoShapefile           = errors.New("archive does not contain a .shp file")
+	ErrTooManyShapefiles     = errors.New("archive contains more than one .shp file")
+	ErrUnsupportedProjection = errors.New("shapefile projection is not supported, only WGS84 longitude and latitude and British National Grid are")
+)
+
+// Reader reads the shapes of a shapefile as features, with the attributes of the matching
+// .dbf record as properties. Shapes are read one at a time from a .shp file, or from a zip
+// archive holding the .shp and the files that go with it. Shapes in British National Grid
+// are reprojected to WGS84.
+type Reader struct {
+	// Projection is the well known text of the .prj file, empty when there is none
+	Projection string
+
+	sr      shp.SequentialReader
+	fields  []string
+	latin1  bool
+	bng     bool
+	closers []io.Closer
+	index   int
+}
+
+// Open opens a .shp file, or a .zip archive holding one, together with its .dbf, .prj and
+// .cpg files
+func Open(path string) (*Reader, error) {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return openZip(path)
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	return open(func(ext string) (io.ReadCloser, error) {
+		return os.Open(base + ext)
+	})
+}
+
+func openZip(path string) (*Reader, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var base string
+	for _, f := range z.File {
+		if !strings.EqualFold(filepath.Ext(f.Name), ".shp") {
+			continue
+		}
+
+		if base != "" {
+			z.Close()
+			return nil, ErrTooManyShapefiles
+		}
+		base = strings.TrimSuffix(f.Name, filepath.Ext(f.Name))
+	}
+
+	if base == "" {
+		z.Close()
+		return nil, ErrNoShapefile
+	}
+
+	r, err := open(func(ext string) (io.ReadCloser, error) {
+		for _, f := range z.File {
+			if strings.EqualFold(f.Name, base+ext) {
+				return f.Open()
+			}
+		}
+
+		return nil, os.ErrNotExist
+	})
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+
+	r.closers = append(r.closers, z)
+
+	return r, nil
+}
+
+// open builds a reader from the files of a shapefile, opened by their extension
+func open(openFile func(ext string) (io.ReadCloser, error)) (*Reader, error) {
+	r := &Reader{}
+
+	prj, err := readOptional(openFile, ".prj")
+	if err != nil {
+		return nil, err
+	}
+	r.Projection = strings.TrimSpace(prj)
+
+	if isProjected(r.Projection) {
+		name := projectionName(r.Projection)
+		if !features.IsBNG(name) {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedProjection, name)
+		}
+		r.bng = true
+	}
+
+	cpg, err := readOptional(openFile, ".cpg")
+	if err != nil {
+		return nil, err
+	}
+	r.latin1 = isLatin1(cpg)
+
+	shpFile, err := openFile(".shp")
+	if err != nil {
+		return nil, err
+	}
+
+	dbfFile, err := openFile(".dbf")
+	if err != nil {
+		shpFile.Close()
+		if os.IsNotExist(err) {
+			return nil, ErrMissingDBF
+		}
+		return nil, err
+	}
+
+	r.sr = shp.SequentialReaderFromExt(shpFile, dbfFile)
+	r.closers = append(r.closers, r.sr)
+
+	for _, field := range r.sr.Fields() {
+		r.fields = append(r.fields, field.String())
+	}
+
+	if err = r.sr.Err(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Next returns the next shape as a feature, or io.EOF once every shape has been read. A
+// *features.FeatureError is returned for a shape that cannot be converted, after which
+// Next returns the shape following it.
+func (r *Reader) Next() (*features.Feature, error) {
+	if !r.sr.Next() {
+		if err := r.sr.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	index := r.index
+	r.index++
+
+	properties := make(map[string]interface{}, len(r.fields))
+	for i, name := range r.fields {
+		value := strings.TrimRight(r.sr.Attribute(i), "\x00")
+		if r.latin1 {
+			value = fromLatin1(value)
+		}
+		properties[name] = value
+	}
+
+	_, shape := r.sr.Shape()
+
+	geometry, err := toGeometry(shape)
+	if err != nil {
+		return nil, &features.FeatureError{Index: index, Err: err}
+	}
+
+	if r.bng && geometry != nil {
+		if err = features.FromBNG(geometry); err != nil {
+			return nil, &features.FeatureError{Index: index, Err: err}
+		}
+	}
+
+	return &features.Feature{Properties: properties, Geometry: geometry}, nil
+}
+
+// Close closes the files of the shapefile
+func (r *Reader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// toGeometry converts a shape to a geojson geometry, ignoring any z or m values. A null
+// shape has no geometry.
+func toGeometry(shape shp.Shape) (*features.Geometry, error) {
+	switch s := shape.(type) {
+	case *shp.Null:
+		return nil, nil
+	case *shp.Point:
+		return &features.Geometry{Type: "Point", Coordinates: position(*s)}, nil
+	case *shp.PointZ:
+		return &features.Geometry{Type: "Point", Coordinates: []float64{s.X, s.Y}}, nil
+	case *shp.PointM:
+		return &features.Geometry{Type: "Point", Coordinates: []float64{s.X, s.Y}}, nil
+	case *shp.MultiPoint:
+		return &features.Geometry{Type: "MultiPoint", Coordinates: positions(s.Points)}, nil
+	case *shp.MultiPointZ:
+		return &features.Geometry{Type: "MultiPoint", Coordinates: positions(s.Points)}, nil
+	case *shp.MultiPointM:
+		return &features.Geometry{Type: "MultiPoint", Coordinates: positions(s.Points)}, nil
+	case *shp.PolyLine:
+		return lines(s.Parts, s.Points), nil
+	case *shp.PolyLineZ:
+		return lines(s.Parts, s.Points), nil
+	case *shp.PolyLineM:
+		return lines(s.Parts, s.Points), nil
+	case *shp.Polygon:
+		return features.FromRings(parts(s.Parts, s.Points))
+	case *shp.PolygonZ:
+		return features.FromRings(parts(s.Parts, s.Points))
+	case *shp.PolygonM:
+		return features.FromRings(parts(s.Parts, s.Points))
+	}
+
+	return nil, fmt.Errorf("%w: %T", features.ErrUnknownGeometry, shape)
+}
+
+// lines converts the parts of a polyline to a LineString, or a MultiLineString when there
+// is more than one
+func lines(partStarts []int32, points []shp.Point) *features.Geometry {
+	ls := parts(partStarts, points)
+	if len(ls) == 1 {
+		return &features.Geometry{Type: "LineString", Coordinates: ls[0]}
+	}
+
+	return &features.Geometry{Type: "MultiLineString", Coordinates: ls}
+}
+
+// parts splits the points of a shape into its parts, which start at the given indexes
+func parts(partStarts []int32, points []shp.Point) [][][]float64 {
+	ps := make([][][]float64, 0, len(partStarts))
+	for i, start := range partStarts {
+		end := int32(len(points))
+		if i+1 < len(partStarts) {
+			end = partStarts[i+1]
+		}
+
+		if start < 0 || start > end || end > int32(len(points)) {
+			continue
+		}
+
+		ps = append(ps, positions(points[start:end]))
+	}
+
+	return ps
+}
+
+func positions(points []shp.Point) [][]float64 {
+	p := make([][]float64, len(points))
+	for i := range points {
+		p[i] = position(points[i])
+	}
+
+	return p
+}
+
+func position(p shp.Point) []float64 {
+	return []float64{p.X, p.Y}
+}
+
+// readOptional returns the contents of a small text file of the shapefile, or an empty
+// string if there is none
+func readOptional(openFile func(ext string) (io.ReadCloser, error), ext string) (string, error) {
+	f, err := openFile(ext)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// isProjected reports whether the well known text of a .prj file describes a projected
+// coordinate system, such as British National Grid, rather than longitude and latitude
+func isProjected(wkt string) bool {
+	return strings.HasPrefix(strings.ToUpper(wkt), "PROJCS")
+}
+
+// projectionName returns the name given to a coordinate system in well known text
+func projectionName(wkt string) string {
+	start := strings.Index(wkt, `"`)
+	if start < 0 {
+		return wkt
+	}
+
+	end := strings.Index(wkt[start+1:], `"`)
+	if end < 0 {
+		return wkt
+	}
+
+	return wkt[start+1 : start+1+end]
+}
+
+// isLatin1 reports whether a .cpg file names a single byte western european code page.
+// Attributes are read as UTF-8 otherwise.
+func isLatin1(cpg string) bool {
+	switch strings.ToUpper(strings.TrimSpace(cpg)) {
+	case "ISO-8859-1", "ISO8859-1", "88591", "LATIN1", "1252", "CP1252", "WINDOWS-1252", "ANSI 1252":
+		return true
+	}
+
+	return false
+}
+
+func fromLatin1(s string) string {
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,250 @@
+package shapefile_test
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/bng"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/features"
+	"github.com/ONSdigital/dp-census-search-prototypes/scripts/geojson/shapefile"
+	shp "github.com/jonas-p/go-shp"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	wgs84        = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+	mercator     = `PROJCS["WGS_1984_Web_Mercator_Auxiliary_Sphere",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Mercator_Auxiliary_Sphere"],UNIT["Meter",1.0]]`
+	nationalGrid = `PROJCS["British_National_Grid",GEOGCS["GCS_OSGB_1936",DATUM["D_OSGB_1936",SPHEROID["Airy_1830",6377563.396,299.3249646]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],UNIT["Meter",1.0]]`
+)
+
+// writeShapefile writes a shapefile of two areas: one polygon with a hole, and one with
+// two parts where the second part has a hole
+func writeShapefile(t *testing.T, dir, prj, cpg string) string {
+	path := filepath.Join(dir, "areas.shp")
+
+	w, err := shp.Create(path, shp.POLYGON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.SetFields([]shp.Field{
+		shp.StringField("LSOA11CD", 9),
+		shp.StringField("LSOA11NM", 30),
+		shp.FloatField("Shape__Are", 16, 3),
+	})
+
+	withHole := shp.Polygon(*shp.NewPolyLine([][]shp.Point{
+		{{X: 0, Y: 0}, {X: 0, Y: 10}, {X: 10, Y: 10}, {X: 10, Y: 0}, {X: 0, Y: 0}},
+		{{X: 2, Y: 2}, {X: 4, Y: 2}, {X: 4, Y: 4}, {X: 2, Y: 4}, {X: 2, Y: 2}},
+	}))
+	w.Write(&withHole)
+	w.WriteAttribute(0, 0, "W01000001")
+	w.WriteAttribute(0, 1, "Isle of Anglesey 001A")
+	w.WriteAttribute(0, 2, 96.0)
+
+	twoParts := shp.Polygon(*shp.NewPolyLine([][]shp.Point{
+		{{X: 20, Y: 0}, {X: 20, Y: 5}, {X: 25, Y: 5}, {X: 25, Y: 0}, {X: 20, Y: 0}},
+		{{X: 30, Y: 0}, {X: 30, Y: 5}, {X: 35, Y: 5}, {X: 35, Y: 0}, {X: 30, Y: 0}},
+		{{X: 31, Y: 1}, {X: 32, Y: 1}, {X: 32, Y: 2}, {X: 31, Y: 2}, {X: 31, Y: 1}},
+	}))
+	w.Write(&twoParts)
+	w.WriteAttribute(1, 0, "W01000002")
+	w.WriteAttribute(1, 1, "Ynys M\xf4n 001B")
+	w.WriteAttribute(1, 2, 49.0)
+
+	w.Close()
+
+	// The writer leaves out the dot before the extension of the dbf file
+	if err = os.Rename(filepath.Join(dir, "areasdbf"), filepath.Join(dir, "areas.dbf")); err != nil {
+		t.Fatal(err)
+	}
+
+	if prj != "" {
+		if err = ioutil.WriteFile(filepath.Join(dir, "areas.prj"), []byte(prj), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if cpg != "" {
+		if err = ioutil.WriteFile(filepath.Join(dir, "areas.cpg"), []byte(cpg), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return path
+}
+
+// zipShapefile archives every file of a shapefile as a download from the geoportal would
+func zipShapefile(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "areas.zip")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	z := zip.NewWriter(f)
+	for _, ext := range []string{".shp", ".shx", ".dbf", ".prj"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "areas"+ext))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w, err := z.Create("Lower_Layer_Super_Output_Areas/areas" + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(b)
+	}
+
+	if err = z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func readAll(t *testing.T, r *shapefile.Reader) []*features.Feature {
+	var all []*features.Feature
+	for {
+		feature, err := r.Next()
+		if err == io.EOF {
+			return all
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		all = append(all, feature)
+	}
+}
+
+func TestReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shapefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeShapefile(t, dir, wgs84, "1252")
+	zipPath := zipShapefile(t, dir)
+
+	Convey("Given a shapefile of polygons with holes and several parts", t, func() {
+		r, err := shapefile.Open(path)
+		So(err, ShouldBeNil)
+		defer r.Close()
+
+		Convey("When its shapes are read", func() {
+			all := readAll(t, r)
+
+			Convey("Then each shape is returned with its attributes", func() {
+				So(all, ShouldHaveLength, 2)
+				So(r.Projection, ShouldEqual, wgs84)
+
+				code, _ := all[0].Property("LSOA11CD")
+				So(code, ShouldEqual, "W01000001")
+
+				area, _ := all[0].Property("Shape__Are")
+				So(area, ShouldEqual, "96.000")
+			})
+
+			Convey("Then attributes are decoded using the code page", func() {
+				name, _ := all[1].Property("LSOA11NM")
+				So(name, ShouldEqual, "Ynys Môn 001B")
+			})
+
+			Convey("Then a single part with a hole is a polygon", func() {
+				So(all[0].Geometry.Type, ShouldEqual, "Polygon")
+				So(all[0].Geometry.Coordinates, ShouldResemble, [][][]float64{
+					{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+					{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+				})
+			})
+
+			Convey("Then several parts are a multipolygon with the hole in its own part", func() {
+				So(all[1].Geometry.Type, ShouldEqual, "MultiPolygon")
+
+				polygons := all[1].Geometry.Coordinates.([][][][]float64)
+				So(polygons, ShouldHaveLength, 2)
+				So(polygons[0], ShouldHaveLength, 1)
+				So(polygons[1], ShouldHaveLength, 2)
+				So(polygons[1][1][0], ShouldResemble, []float64{31, 1})
+			})
+		})
+	})
+
+	Convey("Given a zipped shapefile", t, func() {
+		r, err := shapefile.Open(zipPath)
+		So(err, ShouldBeNil)
+		defer r.Close()
+
+		Convey("When its shapes are read", func() {
+			all := readAll(t, r)
+
+			Convey("Then they are read from the archive", func() {
+				So(all, ShouldHaveLength, 2)
+				So(r.Projection, ShouldEqual, wgs84)
+				So(all[1].Geometry.Type, ShouldEqual, "MultiPolygon")
+			})
+		})
+	})
+
+	Convey("Given a shapefile in british national grid", t, func() {
+		bngDir := filepath.Join(dir, "bng")
+		os.Mkdir(bngDir, 0755)
+		bngPath := writeShapefile(t, bngDir, nationalGrid, "")
+
+		Convey("When every shape is read", func() {
+			r, err := shapefile.Open(bngPath)
+			So(err, ShouldBeNil)
+			defer r.Close()
+
+			all := readAll(t, r)
+
+			Convey("Then the shapes are reprojected to WGS84 longitude and latitude", func() {
+				lat, lon, err := bng.ToWGS84(0, 0)
+				So(err, ShouldBeNil)
+
+				rings := all[0].Geometry.Coordinates.([][][]float64)
+				So(rings[0][0][0], ShouldAlmostEqual, lon, 0.0000001)
+				So(rings[0][0][1], ShouldAlmostEqual, lat, 0.0000001)
+			})
+		})
+	})
+
+	Convey("Given a shapefile in some other projection", t, func() {
+		mercatorDir := filepath.Join(dir, "mercator")
+		os.Mkdir(mercatorDir, 0755)
+		mercatorPath := writeShapefile(t, mercatorDir, mercator, "")
+
+		Convey("When it is opened", func() {
+			_, err := shapefile.Open(mercatorPath)
+
+			Convey("Then unsupported projection is returned", func() {
+				So(errors.Is(err, shapefile.ErrUnsupportedProjection), ShouldBeTrue)
+				So(err.Error(), ShouldContainSubstring, "WGS_1984_Web_Mercator_Auxiliary_Sphere")
+			})
+		})
+	})
+
+	Convey("Given a shapefile without attributes", t, func() {
+		noDBF := filepath.Join(dir, "nodbf")
+		os.Mkdir(noDBF, 0755)
+		noDBFPath := writeShapefile(t, noDBF, "", "")
+		os.Remove(filepath.Join(noDBF, "areas.dbf"))
+
+		Convey("When it is opened", func() {
+			_, err := shapefile.Open(noDBFPath)
+
+			Convey("Then missing dbf is returned", func() {
+				So(err, ShouldEqual, shapefile.ErrMissingDBF)
+			})
+		})
+	})
+}
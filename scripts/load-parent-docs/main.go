@@ -5,21 +5,19 @@ import (
 	"encoding/csv"
 	"errors"
 	"io"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
 
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
 	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
 	"github.com/ONSdigital/dp-census-search-prototypes/models"
-	dphttp "github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/log"
 )
 
 const (
-	elasticsearchAPIURL = "http://localhost:9200"
-	datasetIndex        = "test_parent"
-	mappingsFile        = "parent-mappings.json"
+	datasetIndex = "test_parent"
+	mappingsFile = "parent-mappings.json"
 )
 
 var filename = "test-data/datasets"
@@ -28,32 +26,44 @@ func main() {
 	ctx := context.Background()
 	filename = filename + ".csv"
 
-	cli := dphttp.NewClient()
-	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
-	// delete existing elasticsearch index if already exists
-	status, err := esAPI.DeleteSearchIndex(ctx, datasetIndex)
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
 	if err != nil {
-		if status != http.StatusNotFound {
-			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
-			os.Exit(1)
-		}
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
-		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to detect elasticsearch version", log.FATAL, log.Error(err))
+		os.Exit(1)
 	}
 
-	// create elasticsearch index with settings/mapping
-	status, err = esAPI.CreateSearchIndex(ctx, datasetIndex, mappingsFile)
+	// create a new version of the index with settings/mapping, the alias keeps pointing
+	// at the current version until this one is loaded
+	indexName, status, err := esAPI.CreateIndexVersion(ctx, datasetIndex, mappingsFile)
 	if err != nil {
 		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
 		os.Exit(1)
 	}
+
 	// upload geo locations from data/datasets-test.csv and manipulate data into models.GeoDoc
-	if err = uploadDocs(ctx, esAPI, datasetIndex, filename); err != nil {
+	if err = uploadDocs(ctx, esAPI, indexName, filename); err != nil {
 		log.Event(ctx, "failed to retrieve geo docs", log.ERROR, log.Error(err))
 		os.Exit(1)
 	}
 
+	if err = esAPI.PublishIndex(ctx, datasetIndex, indexName, 1, cfg.IndexVersionsToKeep); err != nil {
+		log.Event(ctx, "failed to publish index", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
 	log.Event(ctx, "successfully loaded in geo docs", log.INFO)
 }
 
@@ -5,23 +5,21 @@ import (
 	"encoding/csv"
 	"errors"
 	"io"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ONSdigital/dp-census-search-prototypes/config"
 	es "github.com/ONSdigital/dp-census-search-prototypes/elasticsearch"
-	dphttp "github.com/ONSdigital/dp-net/http"
 
 	"github.com/ONSdigital/dp-census-search-prototypes/models"
 	"github.com/ONSdigital/log.go/log"
 )
 
 const (
-	elasticsearchAPIURL = "http://localhost:9200"
-	postcodeIndex       = "test_postcode"
-	mappingsFile        = "postcode-mappings.json"
+	postcodeIndex = "test_postcode"
+	mappingsFile  = "postcode-mappings.json"
 )
 
 var (
@@ -30,39 +28,93 @@ var (
 	countCh = make(chan int)
 )
 
+// geographyColumns maps the NSPL columns kept on each postcode document to the
+// field of the document they are stored in
+var geographyColumns = map[string]func(g *models.PostcodeGeography, value string){
+	"oa11":    func(g *models.PostcodeGeography, value string) { g.OA = value },
+	"lsoa11":  func(g *models.PostcodeGeography, value string) { g.LSOA = value },
+	"msoa11":  func(g *models.PostcodeGeography, value string) { g.MSOA = value },
+	"laua":    func(g *models.PostcodeGeography, value string) { g.LAD = value },
+	"ward":    func(g *models.PostcodeGeography, value string) { g.Ward = value },
+	"pcon":    func(g *models.PostcodeGeography, value string) { g.ParliamentaryConstituency = value },
+	"rgn":     func(g *models.PostcodeGeography, value string) { g.Region = value },
+	"ctry":    func(g *models.PostcodeGeography, value string) { g.Country = value },
+	"imd":     func(g *models.PostcodeGeography, value string) { g.IMDRank = value },
+	"ru11ind": func(g *models.PostcodeGeography, value string) { g.RuralUrban = value },
+	"doterm":  func(g *models.PostcodeGeography, value string) { g.TerminationDate = value },
+}
+
 func main() {
 	ctx := context.Background()
 
-	cli := dphttp.NewClient()
-	esAPI := es.NewElasticSearchAPI(cli, elasticsearchAPIURL)
+	cfg, err := config.Get()
+	if err != nil {
+		log.Event(ctx, "failed to retrieve configuration", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
 
-	// delete existing elasticsearch index if already exists
-	status, err := esAPI.DeleteSearchIndex(ctx, postcodeIndex)
+	cli, err := es.NewClient(cfg.SignElasticsearchRequests, cfg.AWSRegion)
 	if err != nil {
-		if status != http.StatusNotFound {
-			log.Event(ctx, "failed to delete index", log.ERROR, log.Error(err), log.Data{"status": status})
+		log.Event(ctx, "failed to create elasticsearch client", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	esAPI := es.NewElasticSearchAPI(cli, cfg.ElasticSearchAPIURL)
+
+	if _, err = esAPI.DetectVersion(ctx); err != nil {
+		log.Event(ctx, "failed to detect elasticsearch version", log.FATAL, log.Error(err))
+		os.Exit(1)
+	}
+
+	if cfg.BulkDeadLetterFile != "" {
+		deadLetter, err := es.NewDeadLetterFile(cfg.BulkDeadLetterFile)
+		if err != nil {
+			log.Event(ctx, "failed to open dead letter file", log.FATAL, log.Error(err), log.Data{"file": cfg.BulkDeadLetterFile})
 			os.Exit(1)
 		}
+		defer deadLetter.Close()
 
-		log.Event(ctx, "failed to delete index as index cannot be found, continuing", log.WARN, log.Error(err), log.Data{"status": status})
+		esAPI.SetDeadLetter(deadLetter)
 	}
 
-	// create elasticsearch index with settings/mapping
-	status, err = esAPI.CreateSearchIndex(ctx, postcodeIndex, mappingsFile)
+	// create a new version of the index with settings/mapping, the postcode alias keeps
+	// pointing at the current version until this one is loaded
+	indexName, status, err := esAPI.CreateIndexVersion(ctx, postcodeIndex, mappingsFile)
 	if err != nil {
 		log.Event(ctx, "failed to create index", log.ERROR, log.Error(err), log.Data{"status": status})
 		os.Exit(1)
 	}
 
-	go trackCounts(ctx)
+	indexer := esAPI.NewBulkIndexer(ctx, es.BulkIndexerConfig{
+		Index:          indexName,
+		Workers:        cfg.BulkWorkers,
+		FlushBytes:     cfg.BulkFlushBytes,
+		FlushDocuments: cfg.BulkFlushDocuments,
+		FlushInterval:  cfg.BulkFlushInterval,
+	})
+
+	go trackCounts(ctx, indexer)
 
-	if err = getPostcodeData(ctx, esAPI, postcodeIndex, root); err != nil {
+	if err = getPostcodeData(ctx, indexer, root); err != nil {
 		log.Event(ctx, "failed to get all postcode data into index", log.ERROR, log.Error(err))
 		os.Exit(1)
 	}
+
+	stats, err := indexer.Close(ctx)
+	if err != nil {
+		log.Event(ctx, "failed to finish indexing postcodes", log.ERROR, log.Error(err), log.Data{"stats": stats})
+		os.Exit(1)
+	}
+
+	if err = esAPI.PublishIndex(ctx, postcodeIndex, indexName, stats.Indexed, cfg.IndexVersionsToKeep); err != nil {
+		log.Event(ctx, "failed to publish postcode index", log.ERROR, log.Error(err), log.Data{"index": indexName})
+		os.Exit(1)
+	}
+
+	log.Event(ctx, "successfully added postcode data to "+postcodeIndex+" index", log.INFO, log.Data{"index": indexName, "stats": stats})
 }
 
-func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename string) error {
+func getPostcodeData(ctx context.Context, indexer *es.BulkIndexer, filename string) error {
 	csvfile, err := os.Open(filename)
 	if err != nil {
 		log.Event(ctx, "failed to open the csv file", log.ERROR, log.Error(err))
@@ -79,6 +131,7 @@ func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename str
 	}
 
 	var latcol, longcol int
+	geographyCols := make(map[string]int)
 	for i, value := range headerRow {
 		if value == "lat" {
 			latcol = i
@@ -89,6 +142,10 @@ func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename str
 			longcol = i
 			continue
 		}
+
+		if _, ok := geographyColumns[value]; ok {
+			geographyCols[value] = i
+		}
 	}
 
 	if latcol == 0 || longcol == 0 {
@@ -96,9 +153,13 @@ func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename str
 		return errors.New("missing latitude or longitude header")
 	}
 
-	count := 0
+	for column := range geographyColumns {
+		if _, ok := geographyCols[column]; !ok {
+			log.Event(ctx, "missing geography header, field will be left empty", log.WARN, log.Data{"column": column})
+		}
+	}
 
-	var postcodeDocs []interface{}
+	count := 0
 
 	// Iterate through the records
 	for {
@@ -139,46 +200,60 @@ func getPostcodeData(ctx context.Context, esAPI *es.API, indexName, filename str
 					Longitude: long,
 				},
 			},
+			PostcodeGeography: getGeography(row, geographyCols),
 		}
 
-		postcodeDocs = append(postcodeDocs, postcodeDoc)
+		if err = indexer.Add(ctx, postcodeDoc); err != nil {
+			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
+			return err
+		}
 
 		if count == 500 {
-			if _, err = esAPI.BulkRequest(ctx, indexName, postcodeDocs); err != nil {
-				log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
-				return err
-			}
-
 			countCh <- count
 
 			count = 0
-			postcodeDocs = nil
 		}
 	}
 
-	// Capture last bulk
+	// Capture last count, the indexer sends any remaining documents when closed
 	if count != 0 {
-		if _, err = esAPI.BulkRequest(ctx, indexName, postcodeDocs); err != nil {
-			log.Event(ctx, "failed to upload document to index", log.ERROR, log.Error(err), log.Data{"count": count})
-			return err
-		}
-
 		countCh <- count
 
 		count = 0
-		postcodeDocs = nil
 	}
 
 	return nil
 }
 
+// getGeography reads the geography codes of a postcode from its row. The NSPL uses
+// pseudo codes ending in 99999999 where a postcode has no code for a geography, such
+// as the region of a postcode in Wales, these are left empty.
+func getGeography(row []string, geographyCols map[string]int) models.PostcodeGeography {
+	var geography models.PostcodeGeography
+	for column, i := range geographyCols {
+		if i >= len(row) {
+			continue
+		}
+
+		value := strings.TrimSpace(row[i])
+		if value == "" || strings.HasSuffix(value, "99999999") {
+			continue
+		}
+
+		geographyColumns[column](&geography, value)
+	}
+
+	return geography
+}
+
 func convertCoordinate(coordinate string) (convertedLatLong float64, err error) {
 	convertedLatLong, err = strconv.ParseFloat(coordinate, 64)
 
 	return
 }
 
-func trackCounts(ctx context.Context) {
+// trackCounts logs the number of postcodes read alongside the indexer totals
+func trackCounts(ctx context.Context, indexer *es.BulkIndexer) {
 	var (
 		totalCounter = 0
 	)
@@ -190,7 +265,8 @@ func trackCounts(ctx context.Context) {
 		case n := <-countCh:
 			totalCounter += n
 		case <-t.C:
-			log.Event(ctx, "Total uploaded: "+strconv.Itoa(totalCounter), log.INFO)
+			stats := indexer.Stats()
+			log.Event(ctx, "Total read: "+strconv.Itoa(totalCounter)+" | Indexed: "+strconv.Itoa(stats.Indexed)+" | Failed: "+strconv.Itoa(stats.Failed), log.INFO)
 		}
 	}
 }
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"net/http"
+
+	dphttp "github.com/ONSdigital/dp-net/http"
+	"github.com/ONSdigital/go-ns/common"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+const requestIDSize = 16
+
+// Middleware assigns a request id to each request, or keeps the one sent by the caller,
+// and starts a server span continuing any trace context found in the request headers.
+// routeName is used to name the span after the matched route rather than the raw path.
+func Middleware(serviceName string, routeName func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(common.RequestHeaderKey)
+			if requestID == "" {
+				requestID = common.NewRequestID(requestIDSize)
+				r.Header.Set(common.RequestHeaderKey, requestID)
+			}
+
+			// log.go and the dp-net client read the request id from different context keys
+			ctx := common.WithRequestId(r.Context(), requestID)
+			ctx = dphttp.WithRequestId(ctx, requestID)
+			ctx = global.TextMapPropagator().Extract(ctx, r.Header)
+
+			route := routeName(r)
+			ctx, span := Tracer().Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serviceName, route, r)...),
+			)
+			defer span.End()
+
+			w.Header().Set(common.RequestHeaderKey, requestID)
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(recorder.status)...)
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(recorder.status))
+		})
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,80 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ONSdigital/dp-census-search-prototypes/tracing"
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/log.go/log"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func routeName(r *http.Request) string {
+	return "/search"
+}
+
+func TestMiddleware(t *testing.T) {
+	shutdown, err := tracing.Init(context.Background(), tracing.ExporterNone, "", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	Convey("Given a request with an X-Request-Id and traceparent header", t, func() {
+		var esRequest *http.Request
+		var logData log.Data
+
+		handler := tracing.Middleware("test", routeName)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logData = tracing.AddLogData(r.Context(), log.Data{})
+			esRequest = httptest.NewRequest("GET", "http://localhost:9200/_search", nil)
+			tracing.InjectHeaders(r.Context(), esRequest)
+		}))
+
+		r := httptest.NewRequest("GET", "/search?q=cardiff", nil)
+		r.Header.Set(common.RequestHeaderKey, "abc123")
+		r.Header.Set("traceparent", traceParent)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		Convey("Then the request id is returned and forwarded to elasticsearch", func() {
+			So(w.Header().Get(common.RequestHeaderKey), ShouldEqual, "abc123")
+			So(esRequest.Header.Get(tracing.OpaqueIDHeader), ShouldEqual, "abc123")
+			So(logData["request_id"], ShouldEqual, "abc123")
+		})
+
+		Convey("Then the trace continues into the elasticsearch call", func() {
+			So(logData["trace_id"], ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(esRequest.Header.Get("traceparent"), ShouldContainSubstring, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(esRequest.Header.Get("traceparent"), ShouldNotEqual, traceParent)
+		})
+	})
+
+	Convey("Given a request without an X-Request-Id header", t, func() {
+		var requestID string
+
+		handler := tracing.Middleware("test", routeName)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID = common.GetRequestId(r.Context())
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/search?q=cardiff", nil))
+
+		Convey("Then a new request id is generated", func() {
+			So(requestID, ShouldNotBeEmpty)
+			So(w.Header().Get(common.RequestHeaderKey), ShouldEqual, requestID)
+		})
+	})
+}
+
+func TestInitUnknownExporter(t *testing.T) {
+	Convey("Given an unsupported exporter, Init returns an error", t, func() {
+		_, err := tracing.Init(context.Background(), "zipkin", "", "test")
+		So(err, ShouldEqual, tracing.ErrUnknownExporter)
+	})
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ONSdigital/go-ns/common"
+	"github.com/ONSdigital/log.go/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/propagators"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+)
+
+// List of supported span exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// OpaqueIDHeader is the header elasticsearch records against tasks and slow log entries
+const OpaqueIDHeader = "X-Opaque-Id"
+
+const tracerName = "github.com/ONSdigital/dp-census-search-prototypes"
+
+// ErrUnknownExporter is returned when the configured span exporter is not supported
+var ErrUnknownExporter = errors.New("unknown tracing exporter, expected one of none, stdout or otlp")
+
+// Init configures the global tracer provider and trace context propagator. Spans are
+// always created so trace ids reach the logs and elasticsearch, but they are only
+// exported when an exporter other than none is chosen. The returned function flushes
+// any buffered spans and should be called on shutdown.
+func Init(ctx context.Context, exporter, otlpEndpoint, serviceName string) (func(context.Context) error, error) {
+	global.SetTextMapPropagator(otel.NewCompositeTextMapPropagator(propagators.TraceContext{}, propagators.Baggage{}))
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.New(semconv.ServiceNameKey.String(serviceName))),
+	}
+
+	shutdown := func(context.Context) error { return nil }
+
+	switch exporter {
+	case "", ExporterNone:
+	case ExporterStdout:
+		exp, err := stdout.NewExporter(stdout.WithPrettyPrint(), stdout.WithoutMetricExport())
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, sdktrace.WithSyncer(exp))
+	case ExporterOTLP:
+		exp, err := otlp.NewExporter(otlp.WithInsecure(), otlp.WithAddress(otlpEndpoint))
+		if err != nil {
+			return nil, err
+		}
+
+		bsp := sdktrace.NewBatchSpanProcessor(exp)
+		options = append(options, sdktrace.WithSpanProcessor(bsp))
+
+		shutdown = func(ctx context.Context) error {
+			bsp.Shutdown()
+			return exp.Shutdown(ctx)
+		}
+	default:
+		return nil, ErrUnknownExporter
+	}
+
+	global.SetTracerProvider(sdktrace.NewTracerProvider(options...))
+
+	log.Event(ctx, "tracing initialised", log.INFO, log.Data{"exporter": exporter, "service_name": serviceName})
+
+	return shutdown, nil
+}
+
+// Tracer returns the tracer used to create spans across the service
+func Tracer() trace.Tracer {
+	return global.Tracer(tracerName)
+}
+
+// InjectHeaders adds the request id and trace context held in ctx to an outgoing request
+func InjectHeaders(ctx context.Context, req *http.Request) {
+	if requestID := common.GetRequestId(ctx); requestID != "" {
+		req.Header.Set(OpaqueIDHeader, requestID)
+	}
+
+	global.TextMapPropagator().Inject(ctx, req.Header)
+}
+
+// AddLogData adds the request id and trace identifiers held in ctx to the log data
+func AddLogData(ctx context.Context, logData log.Data) log.Data {
+	if requestID := common.GetRequestId(ctx); requestID != "" {
+		logData["request_id"] = requestID
+	}
+
+	if spanContext := trace.SpanFromContext(ctx).SpanContext(); spanContext.IsValid() {
+		logData["trace_id"] = spanContext.TraceID.String()
+		logData["span_id"] = spanContext.SpanID.String()
+	}
+
+	return logData
+}